@@ -0,0 +1,259 @@
+package accounting
+
+import (
+	"backend/src/db"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type AccountClass string
+
+const (
+	ClassAsset     AccountClass = "asset"
+	ClassLiability AccountClass = "liability"
+	ClassEquity    AccountClass = "equity"
+	ClassIncome    AccountClass = "income"
+	ClassExpense   AccountClass = "expense"
+)
+
+const (
+	AccountCash              = "cash"
+	AccountLoansReceivable   = "loans_receivable"
+	AccountLoanLossAllowance = "loan_loss_allowance"
+	AccountMemberSavings     = "member_savings"
+	AccountShareCapital      = "share_capital"
+	AccountInterestIncome    = "interest_income"
+	AccountFeeIncome         = "fee_income"
+	AccountRecoveries        = "recoveries"
+	AccountExpenses          = "expenses"
+	AccountLoanLossExpense   = "loan_loss_expense"
+)
+
+type Account struct {
+	Code  string
+	Name  string
+	Class AccountClass
+}
+
+// ChartOfAccounts lists every account the journal can post to, in the order
+// they appear on statements. The loan loss allowance is a contra-asset: its
+// credit balance reduces loans receivable.
+var ChartOfAccounts = []Account{
+	{AccountCash, "Cash at bank", ClassAsset},
+	{AccountLoansReceivable, "Loans receivable", ClassAsset},
+	{AccountLoanLossAllowance, "Allowance for loan losses", ClassAsset},
+	{AccountMemberSavings, "Member savings", ClassLiability},
+	{AccountShareCapital, "Share capital", ClassEquity},
+	{AccountInterestIncome, "Interest income", ClassIncome},
+	{AccountFeeIncome, "Fee income", ClassIncome},
+	{AccountRecoveries, "Recoveries on written-off loans", ClassIncome},
+	{AccountExpenses, "Expenses", ClassExpense},
+	{AccountLoanLossExpense, "Loan loss provision", ClassExpense},
+}
+
+func LookupAccount(code string) Account {
+	for _, account := range ChartOfAccounts {
+		if account.Code == code {
+			return account
+		}
+	}
+	return Account{Code: code, Name: code, Class: ClassExpense}
+}
+
+// Entry is one side of a double-entry journal line derived from a ledger
+// transaction.
+type Entry struct {
+	TransactionID string
+	Date          time.Time
+	Account       string
+	Debit         int
+	Credit        int
+}
+
+func debit(tx *db.Transaction, account string, amount int) Entry {
+	return Entry{TransactionID: tx.TransactionID, Date: tx.CreatedAt, Account: account, Debit: amount}
+}
+
+func credit(tx *db.Transaction, account string, amount int) Entry {
+	return Entry{TransactionID: tx.TransactionID, Date: tx.CreatedAt, Account: account, Credit: amount}
+}
+
+// LedgerData carries the rows a journal needs besides the transactions
+// themselves.
+type LedgerData struct {
+	Payments     map[string]db.LoanPayment
+	Loans        map[uint]db.Loan
+	Restructures map[string]db.LoanRestructure
+	WriteOffs    map[string]db.LoanWriteOff
+}
+
+// JournalFor maps a ledger transaction to balanced journal entries.
+// Transactions that carry no monetary movement produce no entries.
+func JournalFor(tx *db.Transaction, data *LedgerData) []Entry {
+	if tx.Status != "completed" {
+		return nil
+	}
+
+	switch tx.Type {
+	case "deposit":
+		return []Entry{
+			debit(tx, AccountCash, tx.Amount),
+			credit(tx, AccountMemberSavings, tx.Amount),
+		}
+	case "withdrawal":
+		return []Entry{
+			debit(tx, AccountMemberSavings, tx.Amount),
+			credit(tx, AccountCash, tx.Amount),
+		}
+	case "loan_payment", "loan_top_up":
+		// A top-up settles the earlier loan out of the new loan's principal,
+		// so the new loan's receivable pays rather than cash.
+		received := AccountCash
+		if tx.Type == "loan_top_up" {
+			received = AccountLoansReceivable
+		}
+		payment, ok := data.Payments[tx.TransactionID]
+		if !ok {
+			return []Entry{
+				debit(tx, received, tx.Amount),
+				credit(tx, AccountLoansReceivable, tx.Amount),
+			}
+		}
+		// Penalties and fees were recognised as income when charged to the
+		// loan, so collecting them settles the receivable. Any excess credited
+		// back to the member lands in their savings.
+		entries := []Entry{
+			debit(tx, received, tx.Amount),
+			credit(tx, AccountLoansReceivable, payment.PrincipalAmount+payment.PenaltyAmount+payment.FeeAmount),
+			credit(tx, AccountInterestIncome, payment.InterestAmount),
+		}
+		if payment.CreditAmount != 0 {
+			entries = append(entries, credit(tx, AccountMemberSavings, payment.CreditAmount))
+		}
+		allocated := payment.PrincipalAmount + payment.InterestAmount + payment.PenaltyAmount + payment.FeeAmount + payment.CreditAmount
+		if other := tx.Amount - allocated; other != 0 {
+			entries = append(entries, credit(tx, AccountFeeIncome, other))
+		}
+		return entries
+	case "loan_disbursement":
+		// Paying out into savings creates a member liability rather than
+		// moving cash.
+		payout := AccountCash
+		if strings.HasPrefix(tx.ToAccount, "USER-") {
+			payout = AccountMemberSavings
+		}
+		return []Entry{
+			debit(tx, AccountLoansReceivable, tx.Amount),
+			credit(tx, payout, tx.Amount),
+		}
+	case "loan_status_change":
+		// Loans approved before disbursements were recorded were paid out on
+		// approval. Every other loan is posted by its disbursement instead.
+		if tx.ToAccount != "Approved" {
+			return nil
+		}
+		var loanID uint
+		if _, err := fmt.Sscanf(tx.FromAccount, "LOAN-%d", &loanID); err != nil {
+			return nil
+		}
+		loan, ok := data.Loans[loanID]
+		if !ok || loan.Principal == 0 || !loan.PaidOutOnApproval {
+			return nil
+		}
+		return []Entry{
+			debit(tx, AccountLoansReceivable, loan.Principal),
+			credit(tx, AccountCash, loan.Principal),
+		}
+	case "loan_restructure":
+		// Capitalised charges were already receivable, so only capitalised
+		// interest is new income.
+		restructure, ok := data.Restructures[tx.TransactionID]
+		if !ok || restructure.CapitalisedInterest == 0 {
+			return nil
+		}
+		return []Entry{
+			debit(tx, AccountLoansReceivable, restructure.CapitalisedInterest),
+			credit(tx, AccountInterestIncome, restructure.CapitalisedInterest),
+		}
+	case "loan_write_off":
+		// The allowance absorbs what was provided for; the remainder of the
+		// receivable is a loss recognised now.
+		writeOff, ok := data.WriteOffs[tx.TransactionID]
+		if !ok || writeOff.Status != db.WriteOffApproved {
+			return nil
+		}
+		entries := []Entry{}
+		if writeOff.AllowanceUsed > 0 {
+			entries = append(entries, debit(tx, AccountLoanLossAllowance, writeOff.AllowanceUsed))
+		}
+		if expensed := writeOff.Amount - writeOff.AllowanceUsed; expensed > 0 {
+			entries = append(entries, debit(tx, AccountLoanLossExpense, expensed))
+		}
+		return append(entries, credit(tx, AccountLoansReceivable, writeOff.Amount))
+	case "loan_recovery":
+		return []Entry{
+			debit(tx, AccountCash, tx.Amount),
+			credit(tx, AccountRecoveries, tx.Amount),
+		}
+	case "loan_provision":
+		// Provisioning tops the allowance up to the required level, or
+		// releases the excess back to income.
+		if tx.ToAccount == "RELEASE" {
+			return []Entry{
+				debit(tx, AccountLoanLossAllowance, tx.Amount),
+				credit(tx, AccountLoanLossExpense, tx.Amount),
+			}
+		}
+		return []Entry{
+			debit(tx, AccountLoanLossExpense, tx.Amount),
+			credit(tx, AccountLoanLossAllowance, tx.Amount),
+		}
+	case "fee_charge":
+		return []Entry{
+			debit(tx, feeAccount(tx.FromAccount), tx.Amount),
+			credit(tx, AccountFeeIncome, tx.Amount),
+		}
+	case "fee_waiver":
+		return []Entry{
+			debit(tx, AccountFeeIncome, tx.Amount),
+			credit(tx, feeAccount(tx.ToAccount), tx.Amount),
+		}
+	case "share_capital":
+		// Members pay for shares into the bank; redeeming them pays out.
+		if strings.HasPrefix(tx.FromAccount, "SHARES-") {
+			return []Entry{
+				debit(tx, AccountShareCapital, tx.Amount),
+				credit(tx, AccountCash, tx.Amount),
+			}
+		}
+		return []Entry{
+			debit(tx, AccountCash, tx.Amount),
+			credit(tx, AccountShareCapital, tx.Amount),
+		}
+	case "expense":
+		return []Entry{
+			debit(tx, AccountExpenses, tx.Amount),
+			credit(tx, AccountCash, tx.Amount),
+		}
+	}
+	return nil
+}
+
+// feeAccount is where a fee is billed: the loan receivable for fees on a
+// loan, member savings otherwise.
+func feeAccount(ledgerAccount string) string {
+	if strings.HasPrefix(ledgerAccount, "LOAN-") {
+		return AccountLoansReceivable
+	}
+	return AccountMemberSavings
+}
+
+// BuildJournal maps every transaction and returns the combined entries.
+func BuildJournal(transactions []db.Transaction, data *LedgerData) []Entry {
+	var entries []Entry
+	for i := range transactions {
+		entries = append(entries, JournalFor(&transactions[i], data)...)
+	}
+	return entries
+}
@@ -0,0 +1,109 @@
+package accounting
+
+import (
+	"backend/src/db"
+	"testing"
+)
+
+func TestJournalFor(t *testing.T) {
+	data := &LedgerData{
+		Payments: map[string]db.LoanPayment{
+			"PAY": {PrincipalAmount: 800, InterestAmount: 100, PenaltyAmount: 50, FeeAmount: 30, CreditAmount: 20},
+			"TOP": {PrincipalAmount: 900, InterestAmount: 60, FeeAmount: 40},
+		},
+		Loans: map[uint]db.Loan{
+			7: {Principal: 5000, PaidOutOnApproval: true},
+			8: {Principal: 5000},
+		},
+		Restructures: map[string]db.LoanRestructure{
+			"RES": {CapitalisedInterest: 150},
+		},
+		WriteOffs: map[string]db.LoanWriteOff{
+			"WO":      {Amount: 1200, AllowanceUsed: 700, Status: db.WriteOffApproved},
+			"WO-PEND": {Amount: 1200, Status: db.WriteOffPending},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		tx      db.Transaction
+		entries int
+		moved   map[string]int
+	}{
+		{"deposit", db.Transaction{Type: "deposit", Amount: 500}, 2,
+			map[string]int{AccountCash: 500, AccountMemberSavings: 500}},
+		{"withdrawal", db.Transaction{Type: "withdrawal", Amount: 200}, 2,
+			map[string]int{AccountCash: -200, AccountMemberSavings: -200}},
+		{"loan payment", db.Transaction{TransactionID: "PAY", Type: "loan_payment", Amount: 1000}, 4,
+			map[string]int{AccountCash: 1000, AccountLoansReceivable: -880, AccountInterestIncome: 100, AccountMemberSavings: 20}},
+		{"loan payment with unallocated remainder", db.Transaction{TransactionID: "PAY", Type: "loan_payment", Amount: 1010}, 5,
+			map[string]int{AccountCash: 1010, AccountFeeIncome: 10}},
+		{"loan payment without a payment record", db.Transaction{Type: "loan_payment", Amount: 300}, 2,
+			map[string]int{AccountCash: 300, AccountLoansReceivable: -300}},
+		{"top-up settlement", db.Transaction{TransactionID: "TOP", Type: "loan_top_up", Amount: 1000}, 3,
+			map[string]int{AccountLoansReceivable: 1000 - 940, AccountInterestIncome: 60}},
+		{"disbursement in cash", db.Transaction{Type: "loan_disbursement", ToAccount: "BANK", Amount: 5000}, 2,
+			map[string]int{AccountLoansReceivable: 5000, AccountCash: -5000}},
+		{"disbursement to savings", db.Transaction{Type: "loan_disbursement", ToAccount: "USER-3", Amount: 5000}, 2,
+			map[string]int{AccountLoansReceivable: 5000, AccountMemberSavings: 5000}},
+		{"approval of a loan paid out on approval", db.Transaction{Type: "loan_status_change", FromAccount: "LOAN-7", ToAccount: "Approved"}, 2,
+			map[string]int{AccountLoansReceivable: 5000, AccountCash: -5000}},
+		{"approval of a loan paid out later", db.Transaction{Type: "loan_status_change", FromAccount: "LOAN-8", ToAccount: "Approved"}, 0, nil},
+		{"restructure", db.Transaction{TransactionID: "RES", Type: "loan_restructure"}, 2,
+			map[string]int{AccountLoansReceivable: 150, AccountInterestIncome: 150}},
+		{"approved write-off", db.Transaction{TransactionID: "WO", Type: "loan_write_off"}, 3,
+			map[string]int{AccountLoanLossAllowance: 700, AccountLoanLossExpense: 500, AccountLoansReceivable: -1200}},
+		{"pending write-off", db.Transaction{TransactionID: "WO-PEND", Type: "loan_write_off"}, 0, nil},
+		{"recovery", db.Transaction{Type: "loan_recovery", Amount: 400}, 2,
+			map[string]int{AccountCash: 400, AccountRecoveries: 400}},
+		{"provision", db.Transaction{Type: "loan_provision", ToAccount: "ALLOWANCE", Amount: 300}, 2,
+			map[string]int{AccountLoanLossExpense: 300, AccountLoanLossAllowance: -300}},
+		{"provision release", db.Transaction{Type: "loan_provision", ToAccount: "RELEASE", Amount: 100}, 2,
+			map[string]int{AccountLoanLossExpense: -100, AccountLoanLossAllowance: 100}},
+		{"fee on savings", db.Transaction{Type: "fee_charge", FromAccount: "USER-3", Amount: 50}, 2,
+			map[string]int{AccountMemberSavings: -50, AccountFeeIncome: 50}},
+		{"fee on a loan", db.Transaction{Type: "fee_charge", FromAccount: "LOAN-7", Amount: 50}, 2,
+			map[string]int{AccountLoansReceivable: 50, AccountFeeIncome: 50}},
+		{"fee waiver", db.Transaction{Type: "fee_waiver", ToAccount: "USER-3", Amount: 50}, 2,
+			map[string]int{AccountMemberSavings: 50, AccountFeeIncome: -50}},
+		{"share subscription", db.Transaction{Type: "share_capital", FromAccount: "BANK", ToAccount: "SHARES-3", Amount: 1000}, 2,
+			map[string]int{AccountCash: 1000, AccountShareCapital: 1000}},
+		{"share redemption", db.Transaction{Type: "share_capital", FromAccount: "SHARES-3", ToAccount: "BANK", Amount: 400}, 2,
+			map[string]int{AccountCash: -400, AccountShareCapital: -400}},
+		{"expense", db.Transaction{Type: "expense", Amount: 75}, 2,
+			map[string]int{AccountExpenses: 75, AccountCash: -75}},
+		{"guarantee record", db.Transaction{Type: "loan_guarantee"}, 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.tx.Status = "completed"
+			entries := JournalFor(&tt.tx, data)
+			if len(entries) != tt.entries {
+				t.Fatalf("JournalFor returned %d entries, want %d: %+v", len(entries), tt.entries, entries)
+			}
+
+			debits, credits := 0, 0
+			for _, entry := range entries {
+				debits += entry.Debit
+				credits += entry.Credit
+			}
+			if debits != credits {
+				t.Errorf("entries are unbalanced: debits %d, credits %d: %+v", debits, credits, entries)
+			}
+
+			balances := Balances(entries)
+			for account, want := range tt.moved {
+				if balances[account] != want {
+					t.Errorf("%s moved by %d, want %d", account, balances[account], want)
+				}
+			}
+		})
+	}
+}
+
+func TestJournalForSkipsIncompleteTransactions(t *testing.T) {
+	tx := db.Transaction{Type: "deposit", Amount: 500, Status: "pending"}
+	if entries := JournalFor(&tx, &LedgerData{}); len(entries) != 0 {
+		t.Errorf("JournalFor returned %d entries for a pending transaction, want 0", len(entries))
+	}
+}
@@ -0,0 +1,123 @@
+package accounting
+
+type TrialBalanceLine struct {
+	Account string
+	Name    string
+	Class   AccountClass
+	Debit   int
+	Credit  int
+}
+
+type TrialBalance struct {
+	Lines       []TrialBalanceLine
+	TotalDebit  int
+	TotalCredit int
+}
+
+// Balanced reports whether total debits equal total credits.
+func (tb TrialBalance) Balanced() bool {
+	return tb.TotalDebit == tb.TotalCredit
+}
+
+// Balances returns the net balance of every account on its normal side:
+// debit-normal for assets and expenses, credit-normal for everything else.
+func Balances(entries []Entry) map[string]int {
+	balances := map[string]int{}
+	for _, entry := range entries {
+		switch LookupAccount(entry.Account).Class {
+		case ClassAsset, ClassExpense:
+			balances[entry.Account] += entry.Debit - entry.Credit
+		default:
+			balances[entry.Account] += entry.Credit - entry.Debit
+		}
+	}
+	return balances
+}
+
+func BuildTrialBalance(entries []Entry) TrialBalance {
+	debits := map[string]int{}
+	credits := map[string]int{}
+	for _, entry := range entries {
+		debits[entry.Account] += entry.Debit
+		credits[entry.Account] += entry.Credit
+	}
+
+	var tb TrialBalance
+	for _, account := range ChartOfAccounts {
+		net := debits[account.Code] - credits[account.Code]
+		line := TrialBalanceLine{Account: account.Code, Name: account.Name, Class: account.Class}
+		if net >= 0 {
+			line.Debit = net
+		} else {
+			line.Credit = -net
+		}
+		tb.Lines = append(tb.Lines, line)
+		tb.TotalDebit += line.Debit
+		tb.TotalCredit += line.Credit
+	}
+	return tb
+}
+
+type IncomeStatement struct {
+	InterestIncome    int
+	FeeIncome         int
+	Recoveries        int
+	TotalIncome       int
+	Expenses          int
+	LoanLossProvision int
+	NetIncome         int
+}
+
+func BuildIncomeStatement(entries []Entry) IncomeStatement {
+	balances := Balances(entries)
+	is := IncomeStatement{
+		InterestIncome:    balances[AccountInterestIncome],
+		FeeIncome:         balances[AccountFeeIncome],
+		Recoveries:        balances[AccountRecoveries],
+		Expenses:          balances[AccountExpenses],
+		LoanLossProvision: balances[AccountLoanLossExpense],
+	}
+	is.TotalIncome = is.InterestIncome + is.FeeIncome + is.Recoveries
+	is.NetIncome = is.TotalIncome - is.Expenses - is.LoanLossProvision
+	return is
+}
+
+type BalanceSheet struct {
+	Cash              int
+	LoansReceivable   int
+	LoanLossAllowance int
+	NetLoans          int
+	TotalAssets       int
+	MemberSavings     int
+	TotalLiabilities  int
+	ShareCapital      int
+	CurrentEarnings   int
+	TotalEquity       int
+}
+
+// Balanced reports whether assets equal liabilities plus equity.
+func (bs BalanceSheet) Balanced() bool {
+	return bs.TotalAssets == bs.TotalLiabilities+bs.TotalEquity
+}
+
+// BuildBalanceSheet expects every entry up to the reporting date. Income not
+// yet appropriated is shown as current earnings within equity. The loan loss
+// allowance is shown as the positive amount held against loans receivable.
+func BuildBalanceSheet(entries []Entry) BalanceSheet {
+	balances := Balances(entries)
+	income := BuildIncomeStatement(entries)
+
+	bs := BalanceSheet{
+		Cash:              balances[AccountCash],
+		LoansReceivable:   balances[AccountLoansReceivable],
+		LoanLossAllowance: -balances[AccountLoanLossAllowance],
+		MemberSavings:     balances[AccountMemberSavings],
+		ShareCapital:      balances[AccountShareCapital],
+		CurrentEarnings:   income.NetIncome,
+	}
+	bs.NetLoans = bs.LoansReceivable - bs.LoanLossAllowance
+	bs.TotalAssets = bs.Cash + bs.NetLoans
+	bs.TotalLiabilities = bs.MemberSavings
+	bs.TotalEquity = bs.ShareCapital + bs.CurrentEarnings
+	return bs
+}
@@ -0,0 +1,31 @@
+package accounting
+
+import (
+	"backend/src/db"
+	"testing"
+)
+
+func TestBuildBalanceSheet(t *testing.T) {
+	transactions := []db.Transaction{
+		{Type: "share_capital", FromAccount: "BANK", ToAccount: "SHARES-1", Amount: 2000, Status: "completed"},
+		{Type: "deposit", Amount: 5000, Status: "completed"},
+		{Type: "loan_disbursement", ToAccount: "BANK", Amount: 4000, Status: "completed"},
+		{Type: "fee_charge", FromAccount: "LOAN-1", Amount: 100, Status: "completed"},
+		{Type: "expense", Amount: 30, Status: "completed"},
+	}
+	bs := BuildBalanceSheet(BuildJournal(transactions, &LedgerData{}))
+
+	if bs.ShareCapital != 2000 {
+		t.Errorf("ShareCapital = %d, want 2000", bs.ShareCapital)
+	}
+	if bs.Cash != 2970 || bs.LoansReceivable != 4100 || bs.MemberSavings != 5000 {
+		t.Errorf("Cash, LoansReceivable, MemberSavings = %d, %d, %d, want 2970, 4100, 5000",
+			bs.Cash, bs.LoansReceivable, bs.MemberSavings)
+	}
+	if bs.CurrentEarnings != 70 {
+		t.Errorf("CurrentEarnings = %d, want 70", bs.CurrentEarnings)
+	}
+	if !bs.Balanced() {
+		t.Errorf("balance sheet is unbalanced: %+v", bs)
+	}
+}
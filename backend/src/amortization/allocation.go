@@ -0,0 +1,132 @@
+package amortization
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	BucketPenalties = "penalties"
+	BucketFees      = "fees"
+	BucketInterest  = "interest"
+	BucketPrincipal = "principal"
+)
+
+// Waterfall is the order in which a repayment settles what is owed.
+type Waterfall []string
+
+var DefaultWaterfall = Waterfall{BucketPenalties, BucketFees, BucketInterest, BucketPrincipal}
+
+// ParseWaterfall reads a comma-separated bucket order. Every bucket must
+// appear exactly once. An empty string yields the default order.
+func ParseWaterfall(value string) (Waterfall, error) {
+	if strings.TrimSpace(value) == "" {
+		return DefaultWaterfall, nil
+	}
+
+	seen := map[string]bool{}
+	var waterfall Waterfall
+	for _, part := range strings.Split(value, ",") {
+		bucket := strings.TrimSpace(part)
+		switch bucket {
+		case BucketPenalties, BucketFees, BucketInterest, BucketPrincipal:
+		default:
+			return nil, fmt.Errorf("unknown waterfall bucket: %s", bucket)
+		}
+		if seen[bucket] {
+			return nil, fmt.Errorf("duplicate waterfall bucket: %s", bucket)
+		}
+		seen[bucket] = true
+		waterfall = append(waterfall, bucket)
+	}
+	if len(waterfall) != len(DefaultWaterfall) {
+		return nil, fmt.Errorf("waterfall must list %s", strings.Join(DefaultWaterfall, ", "))
+	}
+	return waterfall, nil
+}
+
+// Dues is what a loan currently owes in each bucket. Principal is the
+// scheduled principal due so far; Outstanding is the full principal balance.
+type Dues struct {
+	Penalties   int
+	Fees        int
+	Interest    int
+	Principal   int
+	Outstanding int
+}
+
+// Allocation is how a repayment was split. Prepayment is principal paid
+// ahead of schedule and Credit is the excess returned to the member.
+type Allocation struct {
+	Penalties  int
+	Fees       int
+	Interest   int
+	Principal  int
+	Prepayment int
+	Credit     int
+}
+
+// TotalPrincipal is the reduction in the loan's principal balance.
+func (a Allocation) TotalPrincipal() int {
+	return a.Principal + a.Prepayment
+}
+
+// SettlesEarly reports whether the allocation prepays the whole remaining
+// principal, closing the loan ahead of its schedule. Only a payoff quote,
+// whose dues already make all of the principal due, may do that.
+func (a Allocation) SettlesEarly(dues Dues) bool {
+	return a.Prepayment > 0 && a.TotalPrincipal() >= dues.Outstanding
+}
+
+// Settles reports whether the allocation clears everything the dues hold:
+// the principal balance and every penalty, fee and interest due.
+func (a Allocation) Settles(dues Dues) bool {
+	return a.TotalPrincipal() >= dues.Outstanding &&
+		a.Penalties >= dues.Penalties &&
+		a.Fees >= dues.Fees &&
+		a.Interest >= dues.Interest
+}
+
+// Allocate splits amount across the dues in waterfall order. Whatever is left
+// goes to prepaying principal when prepay is set, and otherwise, or once the
+// principal is cleared, becomes a credit.
+func Allocate(amount int, dues Dues, waterfall Waterfall, prepay bool) Allocation {
+	var allocation Allocation
+	remaining := amount
+
+	take := func(due int) int {
+		if due <= 0 || remaining <= 0 {
+			return 0
+		}
+		if due > remaining {
+			due = remaining
+		}
+		remaining -= due
+		return due
+	}
+
+	principalDue := dues.Principal
+	if principalDue > dues.Outstanding {
+		principalDue = dues.Outstanding
+	}
+
+	for _, bucket := range waterfall {
+		switch bucket {
+		case BucketPenalties:
+			allocation.Penalties = take(dues.Penalties)
+		case BucketFees:
+			allocation.Fees = take(dues.Fees)
+		case BucketInterest:
+			allocation.Interest = take(dues.Interest)
+		case BucketPrincipal:
+			allocation.Principal = take(principalDue)
+		}
+	}
+
+	if prepay {
+		allocation.Prepayment = take(dues.Outstanding - allocation.Principal)
+	}
+	allocation.Credit = remaining
+
+	return allocation
+}
@@ -0,0 +1,137 @@
+package amortization
+
+import "testing"
+
+func TestAllocate(t *testing.T) {
+	principalFirst := Waterfall{BucketPrincipal, BucketInterest, BucketFees, BucketPenalties}
+	tests := []struct {
+		name      string
+		amount    int
+		dues      Dues
+		waterfall Waterfall
+		prepay    bool
+		want      Allocation
+		settles   bool
+		early     bool
+	}{
+		{
+			name:      "installment in default order",
+			amount:    1200,
+			dues:      Dues{Penalties: 50, Fees: 100, Interest: 100, Principal: 1000, Outstanding: 5000},
+			waterfall: DefaultWaterfall,
+			prepay:    true,
+			want:      Allocation{Penalties: 50, Fees: 100, Interest: 100, Principal: 950},
+		},
+		{
+			name:      "overpayment prepays principal",
+			amount:    2000,
+			dues:      Dues{Interest: 100, Principal: 1000, Outstanding: 5000},
+			waterfall: DefaultWaterfall,
+			prepay:    true,
+			want:      Allocation{Interest: 100, Principal: 1000, Prepayment: 900},
+		},
+		{
+			name:      "overpayment credited",
+			amount:    2000,
+			dues:      Dues{Interest: 100, Principal: 1000, Outstanding: 5000},
+			waterfall: DefaultWaterfall,
+			want:      Allocation{Interest: 100, Principal: 1000, Credit: 900},
+		},
+		{
+			name:      "prepaying the whole balance settles early",
+			amount:    6000,
+			dues:      Dues{Interest: 100, Principal: 1000, Outstanding: 5000},
+			waterfall: DefaultWaterfall,
+			prepay:    true,
+			want:      Allocation{Interest: 100, Principal: 1000, Prepayment: 4000, Credit: 900},
+			settles:   true,
+			early:     true,
+		},
+		{
+			name:      "final installment closes the loan",
+			amount:    1150,
+			dues:      Dues{Fees: 50, Interest: 100, Principal: 1000, Outstanding: 1000},
+			waterfall: DefaultWaterfall,
+			prepay:    true,
+			want:      Allocation{Fees: 50, Interest: 100, Principal: 1000},
+			settles:   true,
+		},
+		{
+			name:      "principal cleared with charges left",
+			amount:    1050,
+			dues:      Dues{Penalties: 40, Fees: 50, Interest: 100, Principal: 1000, Outstanding: 1000},
+			waterfall: principalFirst,
+			prepay:    true,
+			want:      Allocation{Principal: 1000, Interest: 50},
+		},
+		{
+			name:      "charges paid after principal closes the loan",
+			amount:    190,
+			dues:      Dues{Penalties: 40, Fees: 50, Interest: 50},
+			waterfall: principalFirst,
+			prepay:    true,
+			want:      Allocation{Interest: 50, Fees: 50, Penalties: 40, Credit: 50},
+			settles:   true,
+		},
+		{
+			name:      "payoff quote settles in full",
+			amount:    5350,
+			dues:      Dues{Fees: 100, Interest: 250, Principal: 5000, Outstanding: 5000},
+			waterfall: DefaultWaterfall,
+			prepay:    true,
+			want:      Allocation{Fees: 100, Interest: 250, Principal: 5000},
+			settles:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Allocate(tt.amount, tt.dues, tt.waterfall, tt.prepay)
+			if got != tt.want {
+				t.Errorf("Allocate = %+v, want %+v", got, tt.want)
+			}
+			if settles := got.Settles(tt.dues); settles != tt.settles {
+				t.Errorf("Settles = %v, want %v", settles, tt.settles)
+			}
+			if early := got.SettlesEarly(tt.dues); early != tt.early {
+				t.Errorf("SettlesEarly = %v, want %v", early, tt.early)
+			}
+		})
+	}
+}
+
+func TestParseWaterfall(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Waterfall
+		wantErr bool
+	}{
+		{value: "", want: DefaultWaterfall},
+		{value: "principal, interest, fees, penalties", want: Waterfall{BucketPrincipal, BucketInterest, BucketFees, BucketPenalties}},
+		{value: "fees,interest,principal", wantErr: true},
+		{value: "fees,fees,interest,principal", wantErr: true},
+		{value: "fees,interest,principal,tips", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseWaterfall(tt.value)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseWaterfall(%q) succeeded, want error", tt.value)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseWaterfall(%q) returned error: %v", tt.value, err)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("ParseWaterfall(%q) = %v, want %v", tt.value, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("ParseWaterfall(%q) = %v, want %v", tt.value, got, tt.want)
+				break
+			}
+		}
+	}
+}
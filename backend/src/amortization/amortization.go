@@ -0,0 +1,135 @@
+package amortization
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	MethodFlat            = "flat"
+	MethodReducingBalance = "reducing_balance"
+)
+
+var Methods = []string{MethodFlat, MethodReducingBalance}
+
+func IsValidMethod(method string) bool {
+	for _, m := range Methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// Installment is one monthly repayment. Balance is the principal still owed
+// once the installment has been paid.
+type Installment struct {
+	Number    int
+	DueDate   time.Time
+	Payment   int
+	Principal int
+	Interest  int
+	Balance   int
+}
+
+// Generate builds a monthly repayment schedule. The rate is the percentage
+// charged over the whole term, as loan interest rates have always been quoted
+// here. Flat-rate loans charge that interest on the original principal;
+// reducing-balance loans spread it evenly across the months as a monthly rate
+// on the outstanding balance with an equal monthly installment (EMI).
+// Rounding differences are settled in the final installment so the principal
+// always repays exactly.
+func Generate(method string, principal int, rate float64, months int, start time.Time) ([]Installment, error) {
+	if months <= 0 {
+		return nil, fmt.Errorf("duration must be positive")
+	}
+	if principal <= 0 {
+		return nil, fmt.Errorf("principal must be positive")
+	}
+
+	switch method {
+	case MethodFlat:
+		return flat(principal, rate, months, start), nil
+	case MethodReducingBalance:
+		return reducingBalance(principal, rate, months, start), nil
+	}
+	return nil, fmt.Errorf("unknown repayment method: %s", method)
+}
+
+// AddMonths returns the date n months after start on the same day of the
+// month, or on the last day of the month when that month is shorter, so a
+// loan started on 31 January falls due on 28 or 29 February.
+func AddMonths(start time.Time, n int) time.Time {
+	first := time.Date(start.Year(), start.Month(), 1, start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), start.Location())
+	month := first.AddDate(0, n, 0)
+	lastDay := month.AddDate(0, 1, -1).Day()
+	return month.AddDate(0, 0, min(start.Day(), lastDay)-1)
+}
+
+func flat(principal int, rate float64, months int, start time.Time) []Installment {
+	totalInterest := int(math.Round(float64(principal) * rate / 100))
+	principalPart := principal / months
+	interestPart := totalInterest / months
+
+	schedule := make([]Installment, months)
+	balance := principal
+	interestLeft := totalInterest
+	for i := 0; i < months; i++ {
+		p, in := principalPart, interestPart
+		if i == months-1 {
+			p, in = balance, interestLeft
+		}
+		balance -= p
+		interestLeft -= in
+		schedule[i] = Installment{
+			Number:    i + 1,
+			DueDate:   AddMonths(start, i+1),
+			Payment:   p + in,
+			Principal: p,
+			Interest:  in,
+			Balance:   balance,
+		}
+	}
+	return schedule
+}
+
+func reducingBalance(principal int, rate float64, months int, start time.Time) []Installment {
+	r := rate / 100 / float64(months)
+	var emi float64
+	if r == 0 {
+		emi = float64(principal) / float64(months)
+	} else {
+		emi = float64(principal) * r / (1 - math.Pow(1+r, -float64(months)))
+	}
+	payment := int(math.Round(emi))
+
+	schedule := make([]Installment, months)
+	balance := principal
+	for i := 0; i < months; i++ {
+		in := int(math.Round(float64(balance) * r))
+		p := payment - in
+		if i == months-1 || p > balance {
+			p = balance
+		}
+		balance -= p
+		schedule[i] = Installment{
+			Number:    i + 1,
+			DueDate:   AddMonths(start, i+1),
+			Payment:   p + in,
+			Principal: p,
+			Interest:  in,
+			Balance:   balance,
+		}
+	}
+	return schedule
+}
+
+// TotalInterest sums the interest across the schedule.
+func TotalInterest(schedule []Installment) int {
+	total := 0
+	for _, installment := range schedule {
+		total += installment.Interest
+	}
+	return total
+}
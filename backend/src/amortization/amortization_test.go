@@ -0,0 +1,106 @@
+package amortization
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAddMonths(t *testing.T) {
+	tests := []struct {
+		start string
+		n     int
+		want  string
+	}{
+		{"2025-01-15", 1, "2025-02-15"},
+		{"2025-01-31", 1, "2025-02-28"},
+		{"2024-01-31", 1, "2024-02-29"},
+		{"2025-01-31", 2, "2025-03-31"},
+		{"2025-01-31", 3, "2025-04-30"},
+		{"2025-03-31", 1, "2025-04-30"},
+		{"2025-08-31", 6, "2026-02-28"},
+		{"2025-11-30", 3, "2026-02-28"},
+		{"2025-12-31", 1, "2026-01-31"},
+		{"2025-05-10", 0, "2025-05-10"},
+	}
+	for _, tt := range tests {
+		start, _ := time.Parse("2006-01-02", tt.start)
+		if got := AddMonths(start, tt.n).Format("2006-01-02"); got != tt.want {
+			t.Errorf("AddMonths(%s, %d) = %s, want %s", tt.start, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	start := time.Date(2025, time.January, 31, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name          string
+		method        string
+		principal     int
+		rate          float64
+		months        int
+		totalInterest int
+		firstPayment  int
+	}{
+		{"flat term rate", MethodFlat, 12000, 10, 12, 1200, 1100},
+		{"flat zero rate", MethodFlat, 1000, 0, 3, 0, 333},
+		{"flat uneven", MethodFlat, 1000, 12.5, 3, 125, 374},
+		{"reducing balance", MethodReducingBalance, 12000, 12, 12, 796, 1066},
+		{"reducing balance zero rate", MethodReducingBalance, 1000, 0, 4, 0, 250},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := Generate(tt.method, tt.principal, tt.rate, tt.months, start)
+			if err != nil {
+				t.Fatalf("Generate returned error: %v", err)
+			}
+			if len(schedule) != tt.months {
+				t.Fatalf("got %d installments, want %d", len(schedule), tt.months)
+			}
+			if got := TotalInterest(schedule); got != tt.totalInterest {
+				t.Errorf("total interest = %d, want %d", got, tt.totalInterest)
+			}
+			if schedule[0].Payment != tt.firstPayment {
+				t.Errorf("first payment = %d, want %d", schedule[0].Payment, tt.firstPayment)
+			}
+
+			principal := 0
+			for i, installment := range schedule {
+				principal += installment.Principal
+				if installment.Payment != installment.Principal+installment.Interest {
+					t.Errorf("installment %d pays %d, want principal+interest %d", i+1, installment.Payment, installment.Principal+installment.Interest)
+				}
+				if want := AddMonths(start, i+1); !installment.DueDate.Equal(want) {
+					t.Errorf("installment %d due %s, want %s", i+1, installment.DueDate, want)
+				}
+			}
+			if principal != tt.principal {
+				t.Errorf("principal repaid = %d, want %d", principal, tt.principal)
+			}
+			if last := schedule[len(schedule)-1]; last.Balance != 0 {
+				t.Errorf("final balance = %d, want 0", last.Balance)
+			}
+			if due := schedule[0].DueDate; due.Month() != time.February || due.Day() != 28 {
+				t.Errorf("first installment due %s, want 28 February", due.Format("2006-01-02"))
+			}
+		})
+	}
+}
+
+func TestGenerateRejectsInvalidInput(t *testing.T) {
+	start := time.Now()
+	tests := []struct {
+		name      string
+		method    string
+		principal int
+		months    int
+	}{
+		{"zero months", MethodFlat, 1000, 0},
+		{"zero principal", MethodFlat, 0, 12},
+		{"unknown method", "balloon", 1000, 12},
+	}
+	for _, tt := range tests {
+		if _, err := Generate(tt.method, tt.principal, 10, tt.months, start); err == nil {
+			t.Errorf("%s: Generate succeeded, want error", tt.name)
+		}
+	}
+}
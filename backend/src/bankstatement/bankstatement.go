@@ -0,0 +1,398 @@
+package bankstatement
+
+import (
+	"backend/src/tabular"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	FormatCSV  = tabular.FormatCSV
+	FormatOFX  = "ofx"
+	FormatCAMT = "camt053"
+)
+
+// Line is a single booked entry on a bank statement. Amount is in the same
+// minor units as db.Deposit.Amount; credits are positive, debits negative.
+type Line struct {
+	Date        time.Time
+	Amount      int
+	Reference   string
+	Description string
+}
+
+// DetectFormat guesses the statement format from the file name, falling back
+// to sniffing the content.
+func DetectFormat(filename string, data []byte) string {
+	lower := strings.ToLower(filename)
+	switch {
+	case strings.HasSuffix(lower, ".ofx") || strings.HasSuffix(lower, ".qfx"):
+		return FormatOFX
+	case strings.HasSuffix(lower, ".xml"):
+		return FormatCAMT
+	case strings.HasSuffix(lower, ".csv"):
+		return FormatCSV
+	}
+
+	head := strings.ToUpper(string(data[:min(len(data), 512)]))
+	switch {
+	case strings.Contains(head, "OFXHEADER") || strings.Contains(head, "<OFX>"):
+		return FormatOFX
+	case strings.Contains(head, "CAMT.053") || strings.Contains(head, "BKTOCSTMRSTMT"):
+		return FormatCAMT
+	}
+	return FormatCSV
+}
+
+// Parse reads a statement in the given format. decimal is the decimal mark
+// of CSV amounts; when empty it is worked out from the file. OFX and
+// camt.053 define their own.
+func Parse(format string, data []byte, decimal string) ([]Line, error) {
+	switch format {
+	case FormatCSV:
+		return ParseCSV(data, decimal)
+	case FormatOFX:
+		return ParseOFX(data)
+	case FormatCAMT:
+		return ParseCAMT053(data)
+	}
+	return nil, fmt.Errorf("unsupported statement format: %s", format)
+}
+
+var csvColumns = tabular.Columns{
+	"date":        {"date", "value date", "transaction date", "posting date", "booking date"},
+	"amount":      {"amount", "credit", "deposit", "value"},
+	"debit":       {"debit", "withdrawal"},
+	"reference":   {"reference", "ref", "transaction reference", "bank reference", "utr", "cheque no"},
+	"description": {"description", "narration", "details", "particulars", "memo"},
+}
+
+// ParseCSV reads a header-row CSV. Column names are matched case-insensitively
+// against common bank export headings. A separate debit column is supported.
+// Amounts are read with the given decimal mark, or the one the file's
+// amounts show when it is empty.
+func ParseCSV(data []byte, decimal string) ([]Line, error) {
+	records, err := tabular.ReadCSV(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("CSV is missing a header row")
+	}
+
+	header := csvColumns.Match(records[0])
+	if !header.Has("date") {
+		return nil, fmt.Errorf("CSV is missing a date column")
+	}
+	if !header.Has("amount") {
+		return nil, fmt.Errorf("CSV is missing an amount column")
+	}
+
+	if decimal == "" {
+		var amounts []string
+		for _, record := range records[1:] {
+			amounts = append(amounts, header.Field(record, "amount"), header.Field(record, "debit"))
+		}
+		if decimal, err = tabular.DetectDecimal(amounts); err != nil {
+			return nil, err
+		}
+	}
+
+	var lines []Line
+	for i, record := range records[1:] {
+		row := i + 2
+		if tabular.Blank(record) {
+			continue
+		}
+
+		date, err := parseDate(header.Field(record, "date"))
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", row, err)
+		}
+
+		amount := 0
+		if raw := header.Field(record, "amount"); raw != "" {
+			if amount, err = tabular.ParseAmount(raw, decimal); err != nil {
+				return nil, fmt.Errorf("row %d: %w", row, err)
+			}
+		}
+		if raw := header.Field(record, "debit"); raw != "" {
+			debit, err := tabular.ParseAmount(raw, decimal)
+			if err != nil {
+				return nil, fmt.Errorf("row %d: %w", row, err)
+			}
+			amount -= int(math.Abs(float64(debit)))
+		}
+
+		lines = append(lines, Line{
+			Date:        date,
+			Amount:      amount,
+			Reference:   header.Field(record, "reference"),
+			Description: header.Field(record, "description"),
+		})
+	}
+	return lines, nil
+}
+
+// asciiUpper upper-cases ASCII letters only. Unlike strings.ToUpper it keeps
+// every byte where it was, so offsets found in the result index the input,
+// whatever the file's character set.
+func asciiUpper(value string) string {
+	b := []byte(value)
+	for i, c := range b {
+		if 'a' <= c && c <= 'z' {
+			b[i] = c - 'a' + 'A'
+		}
+	}
+	return string(b)
+}
+
+var ofxTag = regexp.MustCompile(`(?is)<(\w+)>([^<\r\n]*)`)
+
+// ParseOFX extracts STMTTRN records from OFX 1.x (SGML) or 2.x (XML) files.
+func ParseOFX(data []byte) ([]Line, error) {
+	content := string(data)
+	upper := asciiUpper(content)
+
+	var lines []Line
+	for {
+		start := strings.Index(upper, "<STMTTRN>")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(upper[start:], "</STMTTRN>")
+		if end < 0 {
+			end = len(upper) - start
+		}
+		block := content[start : start+end]
+		content = content[start+end:]
+		upper = upper[start+end:]
+
+		fields := map[string]string{}
+		for _, match := range ofxTag.FindAllStringSubmatch(block, -1) {
+			fields[strings.ToUpper(match[1])] = strings.TrimSpace(match[2])
+		}
+
+		date, err := parseOFXDate(fields["DTPOSTED"])
+		if err != nil {
+			return nil, err
+		}
+		amount, err := parseOFXAmount(fields["TRNAMT"])
+		if err != nil {
+			return nil, err
+		}
+
+		reference := fields["REFNUM"]
+		if reference == "" {
+			reference = fields["CHECKNUM"]
+		}
+		if reference == "" {
+			reference = fields["FITID"]
+		}
+
+		description := fields["NAME"]
+		if memo := fields["MEMO"]; memo != "" {
+			description = strings.TrimSpace(description + " " + memo)
+		}
+
+		lines = append(lines, Line{Date: date, Amount: amount, Reference: reference, Description: description})
+	}
+
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("no transactions found in OFX file")
+	}
+	return lines, nil
+}
+
+type camtDocument struct {
+	Statements []struct {
+		Entries []struct {
+			Amount struct {
+				Value string `xml:",chardata"`
+			} `xml:"Amt"`
+			CreditDebit string `xml:"CdtDbtInd"`
+			BookingDate struct {
+				Date     string `xml:"Dt"`
+				DateTime string `xml:"DtTm"`
+			} `xml:"BookgDt"`
+			ValueDate struct {
+				Date string `xml:"Dt"`
+			} `xml:"ValDt"`
+			ServicerRef string `xml:"AcctSvcrRef"`
+			Info        string `xml:"AddtlNtryInf"`
+			Details     []struct {
+				Refs struct {
+					EndToEndID  string `xml:"EndToEndId"`
+					ServicerRef string `xml:"AcctSvcrRef"`
+				} `xml:"Refs"`
+				Unstructured []string `xml:"RmtInf>Ustrd"`
+			} `xml:"NtryDtls>TxDtls"`
+		} `xml:"Ntry"`
+	} `xml:"BkToCstmrStmt>Stmt"`
+}
+
+// ParseCAMT053 reads ISO 20022 camt.053 bank-to-customer statements.
+func ParseCAMT053(data []byte) ([]Line, error) {
+	var doc camtDocument
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid camt.053 document: %w", err)
+	}
+
+	var lines []Line
+	for _, stmt := range doc.Statements {
+		for _, entry := range stmt.Entries {
+			dateStr := entry.BookingDate.Date
+			if dateStr == "" && entry.BookingDate.DateTime != "" {
+				dateStr = entry.BookingDate.DateTime[:min(len(entry.BookingDate.DateTime), 10)]
+			}
+			if dateStr == "" {
+				dateStr = entry.ValueDate.Date
+			}
+			date, err := parseDate(dateStr)
+			if err != nil {
+				return nil, err
+			}
+
+			amount, err := tabular.ParseAmount(entry.Amount.Value, tabular.DecimalPoint)
+			if err != nil {
+				return nil, err
+			}
+			if strings.EqualFold(entry.CreditDebit, "DBIT") {
+				amount = -amount
+			}
+
+			reference := entry.ServicerRef
+			description := entry.Info
+			if len(entry.Details) > 0 {
+				details := entry.Details[0]
+				if details.Refs.EndToEndID != "" && details.Refs.EndToEndID != "NOTPROVIDED" {
+					reference = details.Refs.EndToEndID
+				} else if details.Refs.ServicerRef != "" {
+					reference = details.Refs.ServicerRef
+				}
+				if len(details.Unstructured) > 0 {
+					description = strings.Join(details.Unstructured, " ")
+				}
+			}
+
+			lines = append(lines, Line{Date: date, Amount: amount, Reference: reference, Description: description})
+		}
+	}
+
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("no entries found in camt.053 document")
+	}
+	return lines, nil
+}
+
+var dateLayouts = []string{"2006-01-02", "02/01/2006", "02-01-2006", "2006/01/02", "02.01.2006", "2 Jan 2006", "02 Jan 2006", time.RFC3339}
+
+func parseDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognised date: %q", value)
+}
+
+// parseOFXAmount reads an OFX amount. OFX amounts are never grouped, so a
+// comma or point in one is always its decimal mark.
+func parseOFXAmount(value string) (int, error) {
+	decimal := tabular.DecimalPoint
+	if strings.Contains(value, ",") {
+		decimal = tabular.DecimalComma
+	}
+	return tabular.ParseAmount(value, decimal)
+}
+
+func parseOFXDate(value string) (time.Time, error) {
+	if len(value) < 8 {
+		return time.Time{}, fmt.Errorf("unrecognised OFX date: %q", value)
+	}
+	return time.ParseInLocation("20060102", value[:8], time.Local)
+}
+
+// Candidate is a recorded deposit that a statement line may correspond to.
+type Candidate struct {
+	ID        uint
+	Amount    int
+	Date      time.Time
+	Reference string
+}
+
+// Match pairs a statement line with a candidate. Lines matched on reference,
+// amount and date are Confirmed; lines matched on amount and date alone are
+// only suggestions for a manager to confirm.
+type Match struct {
+	LineIndex   int
+	CandidateID uint
+	Confirmed   bool
+}
+
+// MatchLines pairs credit lines with candidates whose amount is equal and
+// whose date lies within window of the line date. Each candidate is used at
+// most once.
+func MatchLines(lines []Line, candidates []Candidate, window time.Duration) []Match {
+	used := map[uint]bool{}
+	matchedLine := map[int]bool{}
+	var matches []Match
+
+	withinWindow := func(line Line, candidate Candidate) bool {
+		diff := line.Date.Sub(candidate.Date)
+		if diff < 0 {
+			diff = -diff
+		}
+		return line.Amount == candidate.Amount && diff <= window
+	}
+
+	for i, line := range lines {
+		if line.Amount <= 0 || line.Reference == "" {
+			continue
+		}
+		for _, candidate := range candidates {
+			if used[candidate.ID] || !withinWindow(line, candidate) {
+				continue
+			}
+			if strings.EqualFold(strings.TrimSpace(candidate.Reference), strings.TrimSpace(line.Reference)) {
+				used[candidate.ID] = true
+				matchedLine[i] = true
+				matches = append(matches, Match{LineIndex: i, CandidateID: candidate.ID, Confirmed: true})
+				break
+			}
+		}
+	}
+
+	for i, line := range lines {
+		if line.Amount <= 0 || matchedLine[i] {
+			continue
+		}
+		var best *Candidate
+		var bestDiff time.Duration
+		for j := range candidates {
+			candidate := candidates[j]
+			if used[candidate.ID] || !withinWindow(line, candidate) {
+				continue
+			}
+			diff := line.Date.Sub(candidate.Date)
+			if diff < 0 {
+				diff = -diff
+			}
+			if best == nil || diff < bestDiff {
+				best = &candidates[j]
+				bestDiff = diff
+			}
+		}
+		if best != nil {
+			used[best.ID] = true
+			matches = append(matches, Match{LineIndex: i, CandidateID: best.ID, Confirmed: false})
+		}
+	}
+
+	return matches
+}
@@ -0,0 +1,123 @@
+package bankstatement
+
+import (
+	"testing"
+)
+
+func TestParseCSVAmounts(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		decimal string
+		want    []int
+		wantErr bool
+	}{
+		{
+			name: "decimal point",
+			data: "Date,Narration,Credit,Debit,Ref\n" +
+				"2025-11-14,NEFT JOHN,\"1,000.50\",,R1\n" +
+				"2025-11-15,CHARGES,,\"2,500\",R2\n",
+			want: []int{1001, -2500},
+		},
+		{
+			name: "decimal comma",
+			data: "Date,Narration,Credit,Debit,Ref\n" +
+				"2025-11-14,NEFT JOHN,\"1.000\",,R1\n" +
+				"2025-11-15,CHARGES,,\"25,00\",R2\n",
+			want: []int{1000, -25},
+		},
+		{
+			name: "ambiguous amounts",
+			data: "Date,Narration,Credit,Ref\n" +
+				"2025-11-14,NEFT JOHN,1.500,R1\n",
+			wantErr: true,
+		},
+		{
+			name: "ambiguous amounts with a decimal mark",
+			data: "Date,Narration,Credit,Ref\n" +
+				"2025-11-14,NEFT JOHN,1.500,R1\n",
+			decimal: ",",
+			want:    []int{1500},
+		},
+		{
+			name: "mixed decimal marks",
+			data: "Date,Narration,Credit,Debit,Ref\n" +
+				"2025-11-14,NEFT JOHN,\"1,000.50\",,R1\n" +
+				"2025-11-15,CHARGES,,\"25,00\",R2\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines, err := ParseCSV([]byte(tt.data), tt.decimal)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseCSV succeeded with %+v, want error", lines)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseCSV returned error: %v", err)
+			}
+			if len(lines) != len(tt.want) {
+				t.Fatalf("ParseCSV returned %d lines, want %d", len(lines), len(tt.want))
+			}
+			for i, line := range lines {
+				if line.Amount != tt.want[i] {
+					t.Errorf("line %d amount = %d, want %d", i+1, line.Amount, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseOFX(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []Line
+	}{
+		{
+			name: "SGML",
+			data: "OFXHEADER:100\nCHARSET:1252\n<OFX><BANKTRANLIST>\n" +
+				"<STMTTRN>\n<TRNTYPE>CREDIT\n<DTPOSTED>20251114\n<TRNAMT>1500.00\n<FITID>F1\n<NAME>JOHN\n</STMTTRN>\n" +
+				"<stmttrn>\n<trntype>DEBIT\n<dtposted>20251115\n<trnamt>-20.00\n<refnum>R2\n<memo>fee\n</stmttrn>\n" +
+				"</BANKTRANLIST></OFX>",
+			want: []Line{
+				{Amount: 1500, Reference: "F1", Description: "JOHN"},
+				{Amount: -20, Reference: "R2", Description: "fee"},
+			},
+		},
+		{
+			// Windows-1252 é is not valid UTF-8; upper-casing it must not
+			// shift the offsets used to slice the file.
+			name: "non-UTF-8 names",
+			data: "<OFX>\n<STMTTRN>\n<DTPOSTED>20251114\n<TRNAMT>10.00\n<FITID>A\n<NAME>Caf\xe9 Cr\xe8me Br\xfbl\xe9e\n</STMTTRN>\n" +
+				"<STMTTRN>\n<DTPOSTED>20251114\n<TRNAMT>20.00\n<FITID>B\n<NAME>ıııııııııııııııııııı\n</STMTTRN>\n" +
+				"<STMTTRN>\n<DTPOSTED>20251115\n<TRNAMT>30.00\n<FITID>C\n<NAME>X\n</STMTTRN>\n</OFX>",
+			want: []Line{
+				{Amount: 10, Reference: "A", Description: "Caf\xe9 Cr\xe8me Br\xfbl\xe9e"},
+				{Amount: 20, Reference: "B", Description: "ıııııııııııııııııııı"},
+				{Amount: 30, Reference: "C", Description: "X"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines, err := ParseOFX([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("ParseOFX returned error: %v", err)
+			}
+			if len(lines) != len(tt.want) {
+				t.Fatalf("ParseOFX returned %d lines, want %d", len(lines), len(tt.want))
+			}
+			for i, line := range lines {
+				want := tt.want[i]
+				if line.Amount != want.Amount || line.Reference != want.Reference || line.Description != want.Description {
+					t.Errorf("line %d = %+v, want amount %d, reference %q, description %q",
+						i+1, line, want.Amount, want.Reference, want.Description)
+				}
+			}
+		})
+	}
+}
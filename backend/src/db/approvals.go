@@ -0,0 +1,14 @@
+package db
+
+const (
+	VoteApprove = "approve"
+	VoteReject  = "reject"
+)
+
+// SeedApprovalPolicies creates the base band, which covers every amount
+// below the other bands and keeps the single-manager approval loans had
+// before committees.
+func SeedApprovalPolicies() error {
+	policy := ApprovalPolicy{MinAmount: 0, RequiredApprovals: 1, IsActive: true}
+	return DB.Where("min_amount = ?", 0).FirstOrCreate(&policy).Error
+}
@@ -0,0 +1,39 @@
+package db
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+const (
+	CollateralLandTitle    = "land_title"
+	CollateralVehicle      = "vehicle"
+	CollateralFixedDeposit = "fixed_deposit"
+	CollateralOther        = "other"
+)
+
+const (
+	LienStatusActive   = "active"
+	LienStatusReleased = "released"
+)
+
+var CollateralTypes = []string{CollateralLandTitle, CollateralVehicle, CollateralFixedDeposit, CollateralOther}
+
+// Hash digests the fields that make up the collateral record. The digest is
+// anchored whenever the record changes, so a valuation edited behind the
+// API's back no longer matches the chain.
+func (c *Collateral) Hash() string {
+	data, _ := json.Marshal(map[string]interface{}{
+		"id":               c.ID,
+		"loan_id":          c.LoanID,
+		"type":             c.Type,
+		"description":      c.Description,
+		"valuation":        c.Valuation,
+		"valuation_date":   c.ValuationDate,
+		"lien_status":      c.LienStatus,
+		"lien_released_at": c.LienReleasedAt,
+	})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -103,35 +103,264 @@ type Transaction struct {
 
 type Loan struct {
 	gorm.Model
-	BorrowerID         uint    `gorm:"not null;index"`
-	Borrower           User    `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT"`
-	ApprovedByID       *uint   `gorm:"index"`
-	ApprovedBy         *User   `gorm:"foreignKey:ApprovedByID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
-	Amount             int     `gorm:"not null"`
-	Principal          int     `gorm:"not null"`
-	Duration           int     `gorm:"not null"`
-	InterestRate       float64 `gorm:"type:decimal(5,2);not null"`
-	Status             string  `gorm:"type:varchar(50);default:'Requested';not null;index"`
-	Reason             string  `gorm:"type:text"`
-	DisbursedAt        *int64
-	PaidOffAt          *int64
-	MonthlyPayment     int           `gorm:"default:0"`
-	OutstandingBalance int           `gorm:"default:0"`
-	Transactions       []Transaction `gorm:"many2many:transaction_loans;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
-	Payments           []LoanPayment `gorm:"foreignKey:LoanID"`
+	BorrowerID           uint         `gorm:"not null;index"`
+	Borrower             User         `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT"`
+	ApprovedByID         *uint        `gorm:"index"`
+	ApprovedBy           *User        `gorm:"foreignKey:ApprovedByID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
+	ProductID            *uint        `gorm:"index"`
+	Product              *LoanProduct `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
+	ProductTerms         string       `gorm:"type:text"`
+	Amount               int          `gorm:"not null"`
+	Principal            int          `gorm:"not null"`
+	Duration             int          `gorm:"not null"`
+	InterestRate         float64      `gorm:"type:decimal(5,2);not null"`
+	InterestRateID       *uint        `gorm:"index"`
+	Status               string       `gorm:"type:varchar(50);default:'Requested';not null;index"`
+	Reason               string       `gorm:"type:text"`
+	RequestVersion       int          `gorm:"default:1;not null"`
+	RepaymentMethod      string       `gorm:"type:varchar(20);default:'flat';not null"`
+	ApprovedAt           *int64
+	DisbursedAt          *int64
+	DisbursedByID        *uint  `gorm:"index"`
+	DisbursedBy          *User  `gorm:"foreignKey:DisbursedByID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
+	DisbursementMethod   string `gorm:"type:varchar(20)"`
+	DisbursementRef      string
+	PaidOffAt            *int64
+	MonthlyPayment       int `gorm:"default:0"`
+	OutstandingBalance   int `gorm:"default:0"`
+	DaysPastDue          int `gorm:"default:0"`
+	ArrearsAmount        int `gorm:"default:0"`
+	DelinquencyCheckedAt *int64
+	RestructuredAt       *int64
+	RestructureCount     int `gorm:"default:0"`
+	WrittenOffAt         *int64
+	WrittenOffAmount     int           `gorm:"default:0"`
+	RecoveredAmount      int           `gorm:"default:0"`
+	TopUpOfID            *uint         `gorm:"index"`
+	TopUpAmount          int           `gorm:"default:0"`
+	PaidOutOnApproval    bool          `gorm:"default:false;not null"`
+	Transactions         []Transaction `gorm:"many2many:transaction_loans;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Payments             []LoanPayment `gorm:"foreignKey:LoanID"`
+	Installments         []LoanInstallment
+}
+
+type LoanStatusHistory struct {
+	gorm.Model
+	LoanID        uint   `gorm:"not null;index"`
+	Loan          Loan   `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	FromStatus    string `gorm:"type:varchar(50)"`
+	ToStatus      string `gorm:"type:varchar(50);not null"`
+	ChangedByID   *uint  `gorm:"index"`
+	ChangedBy     *User  `gorm:"foreignKey:ChangedByID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
+	Reason        string `gorm:"type:text"`
+	TransactionID string `gorm:"index"`
+	// CommentsDigest covers the first CommentCount comments on the loan
+	// when a request is decided on, so the discussion behind the decision
+	// is anchored with it.
+	CommentsDigest string `gorm:"type:varchar(64)"`
+	// RequestVersion is the version of the loan request a change made
+	// while the loan was still a request was decided on.
+	RequestVersion *int
+	CommentCount   *int
+}
+
+func (LoanStatusHistory) TableName() string {
+	return "loan_status_history"
+}
+
+// LoanRequestVersion is the amount, duration and reason of a loan request
+// as it stood after the member's original request or one of their
+// amendments.
+type LoanRequestVersion struct {
+	gorm.Model
+	LoanID        uint   `gorm:"not null;uniqueIndex:idx_loan_request_version"`
+	Loan          Loan   `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Version       int    `gorm:"not null;uniqueIndex:idx_loan_request_version"`
+	Amount        int    `gorm:"not null"`
+	Duration      int    `gorm:"not null"`
+	Reason        string `gorm:"type:text"`
+	Note          string `gorm:"type:text"`
+	ChangedByID   *uint  `gorm:"index"`
+	ChangedBy     *User  `gorm:"foreignKey:ChangedByID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
+	TransactionID string `gorm:"index"`
+}
+
+// LoanComment is a message in the discussion thread of a loan. Internal
+// notes are only visible to staff. ContentHash is computed when the comment
+// is posted and never changes.
+type LoanComment struct {
+	gorm.Model
+	LoanID      uint   `gorm:"not null;index"`
+	Loan        Loan   `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	AuthorID    uint   `gorm:"not null;index"`
+	Author      User   `gorm:"foreignKey:AuthorID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT"`
+	Body        string `gorm:"type:text;not null"`
+	Internal    bool   `gorm:"not null;default:false"`
+	ContentHash string `gorm:"type:varchar(64);not null"`
+}
+
+// LoanApprovalVote is a manager's vote on a version of a loan request.
+// Amending the request starts a new round of votes.
+type LoanApprovalVote struct {
+	gorm.Model
+	LoanID         uint   `gorm:"not null;uniqueIndex:idx_loan_approval_vote"`
+	Loan           Loan   `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	RequestVersion int    `gorm:"not null;uniqueIndex:idx_loan_approval_vote"`
+	VoterID        uint   `gorm:"not null;uniqueIndex:idx_loan_approval_vote"`
+	Voter          User   `gorm:"foreignKey:VoterID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT"`
+	Decision       string `gorm:"type:varchar(10);not null"`
+	Comment        string `gorm:"type:text"`
+	TransactionID  string `gorm:"index"`
+}
+
+type LoanRestructure struct {
+	gorm.Model
+	LoanID                 uint    `gorm:"not null;index"`
+	Loan                   Loan    `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	RestructuredByID       *uint   `gorm:"index"`
+	RestructuredBy         *User   `gorm:"foreignKey:RestructuredByID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
+	Reason                 string  `gorm:"type:text;not null"`
+	PreviousDuration       int     `gorm:"not null"`
+	PreviousInterestRate   float64 `gorm:"type:decimal(5,2);not null"`
+	PreviousMonthlyPayment int     `gorm:"not null"`
+	PreviousOutstanding    int     `gorm:"not null"`
+	PreviousSchedule       string  `gorm:"type:text"`
+	NewDuration            int     `gorm:"not null"`
+	NewInterestRate        float64 `gorm:"type:decimal(5,2);not null"`
+	NewMonthlyPayment      int     `gorm:"not null"`
+	NewOutstanding         int     `gorm:"not null"`
+	CapitalisedInterest    int     `gorm:"default:0;not null"`
+	CapitalisedCharges     int     `gorm:"default:0;not null"`
+	HolidayMonths          int     `gorm:"default:0;not null"`
+	TransactionID          string  `gorm:"uniqueIndex"`
+}
+
+// LoanWriteOff is a manager's request to write a loan off, decided by an
+// auditor. Amount is the receivable written off: the outstanding balance
+// plus unpaid charges. AllowanceUsed is the part absorbed by the loan loss
+// allowance; the rest is expensed.
+type LoanWriteOff struct {
+	gorm.Model
+	LoanID        uint   `gorm:"not null;index"`
+	Loan          Loan   `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	RequestedByID uint   `gorm:"not null;index"`
+	RequestedBy   User   `gorm:"foreignKey:RequestedByID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT"`
+	Reason        string `gorm:"type:text;not null"`
+	Amount        int    `gorm:"not null"`
+	AllowanceUsed int    `gorm:"default:0;not null"`
+	Status        string `gorm:"type:varchar(20);default:'pending';not null;index"`
+	DecidedByID   *uint  `gorm:"index"`
+	DecidedBy     *User  `gorm:"foreignKey:DecidedByID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
+	DecidedAt     *int64
+	Comment       string `gorm:"type:text"`
+	TransactionID string `gorm:"index"`
+}
+
+// LoanRecovery is money collected on a loan after it was written off.
+type LoanRecovery struct {
+	gorm.Model
+	LoanID        uint   `gorm:"not null;index"`
+	Loan          Loan   `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Amount        int    `gorm:"not null"`
+	Reference     string `gorm:"type:varchar(100)"`
+	RecordedByID  uint   `gorm:"not null;index"`
+	RecordedBy    User   `gorm:"foreignKey:RecordedByID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT"`
+	TransactionID string `gorm:"uniqueIndex"`
+}
+
+type LoanGuarantee struct {
+	gorm.Model
+	LoanID        uint   `gorm:"not null;uniqueIndex:idx_loan_guarantor"`
+	Loan          Loan   `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	GuarantorID   uint   `gorm:"not null;uniqueIndex:idx_loan_guarantor;index"`
+	Guarantor     User   `gorm:"foreignKey:GuarantorID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT"`
+	Amount        int    `gorm:"not null"`
+	Status        string `gorm:"type:varchar(20);default:'pending';not null;index"`
+	RespondedAt   *int64
+	ReleasedAt    *int64
+	TransactionID string `gorm:"index"`
+}
+
+type PayoffQuote struct {
+	gorm.Model
+	LoanID            uint   `gorm:"not null;index"`
+	Loan              Loan   `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	RequestedByID     uint   `gorm:"not null;index"`
+	RequestedBy       User   `gorm:"foreignKey:RequestedByID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT"`
+	SettlementDate    int64  `gorm:"not null"`
+	ExpiresAt         int64  `gorm:"not null;index"`
+	Principal         int    `gorm:"not null"`
+	Interest          int    `gorm:"not null"`
+	Penalties         int    `gorm:"default:0;not null"`
+	Fees              int    `gorm:"default:0;not null"`
+	PrepaymentPenalty int    `gorm:"default:0;not null"`
+	InterestRebate    int    `gorm:"default:0;not null"`
+	Total             int    `gorm:"not null"`
+	Status            string `gorm:"type:varchar(20);default:'issued';not null;index"`
+	TransactionID     string `gorm:"index"`
+}
+
+type Collateral struct {
+	gorm.Model
+	LoanID         uint   `gorm:"not null;index"`
+	Loan           Loan   `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Type           string `gorm:"type:varchar(30);not null"`
+	Description    string `gorm:"type:text;not null"`
+	Valuation      int    `gorm:"not null"`
+	ValuationDate  int64  `gorm:"not null"`
+	LienStatus     string `gorm:"type:varchar(20);default:'active';not null;index"`
+	LienReleasedAt *int64
+	RegisteredByID *uint  `gorm:"index"`
+	RegisteredBy   *User  `gorm:"foreignKey:RegisteredByID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
+	RecordHash     string `gorm:"type:varchar(64)"`
+	TransactionID  string `gorm:"index"`
+}
+
+type Document struct {
+	gorm.Model
+	LoanID        *uint  `gorm:"index"`
+	Loan          *Loan  `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	MemberID      *uint  `gorm:"index"`
+	Member        *User  `gorm:"foreignKey:MemberID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Category      string `gorm:"type:varchar(30);not null;index"`
+	FileName      string `gorm:"not null"`
+	ContentType   string `gorm:"type:varchar(100);not null"`
+	Size          int64  `gorm:"not null"`
+	SHA256        string `gorm:"column:sha256;type:varchar(64);not null;index"`
+	StorageKey    string `gorm:"uniqueIndex;not null"`
+	Description   string `gorm:"type:text"`
+	UploadedByID  uint   `gorm:"not null;index"`
+	UploadedBy    User   `gorm:"foreignKey:UploadedByID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT"`
+	TransactionID string `gorm:"index"`
+}
+
+type LoanInstallment struct {
+	gorm.Model
+	LoanID    uint  `gorm:"not null;uniqueIndex:idx_loan_installment"`
+	Loan      Loan  `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Number    int   `gorm:"not null;uniqueIndex:idx_loan_installment"`
+	DueDate   int64 `gorm:"not null;index"`
+	Payment   int   `gorm:"not null"`
+	Principal int   `gorm:"not null"`
+	Interest  int   `gorm:"not null"`
+	Balance   int   `gorm:"not null"`
 }
 
 type LoanPayment struct {
 	gorm.Model
-	LoanID          uint   `gorm:"not null;index"`
-	Loan            Loan   `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT"`
-	TransactionID   string `gorm:"not null;index"`
-	Amount          int    `gorm:"not null"`
-	PrincipalAmount int    `gorm:"not null"`
-	InterestAmount  int    `gorm:"not null"`
-	BalanceAfter    int    `gorm:"not null"`
-	Status          string `gorm:"type:varchar(50);default:'completed';not null"`
-	PaymentDate     int64  `gorm:"not null;index"`
+	LoanID           uint   `gorm:"not null;index"`
+	Loan             Loan   `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT"`
+	TransactionID    string `gorm:"not null;index"`
+	Amount           int    `gorm:"not null"`
+	PrincipalAmount  int    `gorm:"not null"`
+	InterestAmount   int    `gorm:"not null"`
+	PenaltyAmount    int    `gorm:"default:0;not null"`
+	FeeAmount        int    `gorm:"default:0;not null"`
+	PrepaymentAmount int    `gorm:"default:0;not null"`
+	CreditAmount     int    `gorm:"default:0;not null"`
+	BalanceAfter     int    `gorm:"not null"`
+	Status           string `gorm:"type:varchar(50);default:'completed';not null"`
+	PaymentDate      int64  `gorm:"not null;index"`
 }
 
 type Deposit struct {
@@ -144,11 +373,44 @@ type Deposit struct {
 	Reference     string `gorm:"index"`
 }
 
+type LoanProduct struct {
+	gorm.Model
+	Name               string    `gorm:"uniqueIndex;not null"`
+	Description        string    `gorm:"type:text"`
+	InterestRate       float64   `gorm:"type:decimal(5,2);not null"`
+	RepaymentMethod    string    `gorm:"type:varchar(20);default:'flat';not null"`
+	Durations          string    `gorm:"not null"`
+	MinAmount          int       `gorm:"default:0;not null"`
+	MaxAmount          int       `gorm:"default:0;not null"`
+	RequiredGuarantors int       `gorm:"default:0;not null"`
+	IsActive           bool      `gorm:"default:false;not null"`
+	Fees               []FeeType `gorm:"many2many:loan_product_fees"`
+}
+
+// ApprovalPolicy sets how many managers must approve loans from MinAmount up
+// to the next active band. With a committee only its members may vote;
+// without one any manager may.
+type ApprovalPolicy struct {
+	gorm.Model
+	MinAmount         int    `gorm:"uniqueIndex;not null"`
+	RequiredApprovals int    `gorm:"default:1;not null"`
+	IsActive          bool   `gorm:"default:false;not null"`
+	Committee         []User `gorm:"many2many:approval_policy_members"`
+	UpdatedByID       *uint  `gorm:"index"`
+	UpdatedBy         *User  `gorm:"foreignKey:UpdatedByID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
+}
+
+// InterestRate is one version of the rate for a loan duration. Versions are
+// appended, never changed: the latest one whose EffectiveFrom has passed is
+// the rate in force, and later ones are scheduled changes.
 type InterestRate struct {
 	gorm.Model
-	DurationMonths int     `gorm:"uniqueIndex;not null"`
+	DurationMonths int     `gorm:"not null;index:idx_interest_rate_version,priority:1"`
 	Rate           float64 `gorm:"type:decimal(5,2);not null"`
-	EffectiveFrom  int64   `gorm:"not null"`
+	EffectiveFrom  int64   `gorm:"not null;index:idx_interest_rate_version,priority:2"`
+	SetByID        *uint
+	SetBy          *User  `gorm:"foreignKey:SetByID"`
+	TransactionID  string `gorm:"type:varchar(100)"`
 }
 
 type Block struct {
@@ -173,26 +435,281 @@ type Session struct {
 	UserAgent string
 }
 
+// FiscalPeriod is a calendar month (Month 1-12) or a full year (Month 0)
+// that can be closed to further postings.
+type FiscalPeriod struct {
+	gorm.Model
+	Year                 int    `gorm:"not null;uniqueIndex:idx_fiscal_period"`
+	Month                int    `gorm:"not null;uniqueIndex:idx_fiscal_period"`
+	StartsAt             int64  `gorm:"not null;index"`
+	EndsAt               int64  `gorm:"not null;index"`
+	Status               string `gorm:"type:varchar(20);default:'open';not null;index"`
+	ClosedByID           *uint  `gorm:"index"`
+	ClosedBy             *User  `gorm:"foreignKey:ClosedByID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
+	ClosedAt             *int64
+	ClosingTransactionID string `gorm:"index"`
+	PeriodHash           string `gorm:"type:varchar(64)"`
+	TransactionCount     int    `gorm:"default:0"`
+	ClosingBalances      []PeriodClosingBalance
+	ReopenRequests       []PeriodReopenRequest
+}
+
+type PeriodClosingBalance struct {
+	gorm.Model
+	FiscalPeriodID uint         `gorm:"not null;index"`
+	FiscalPeriod   FiscalPeriod `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Account        string       `gorm:"not null;index"`
+	Balance        int          `gorm:"not null"`
+}
+
+type PeriodReopenRequest struct {
+	gorm.Model
+	FiscalPeriodID uint         `gorm:"not null;index"`
+	FiscalPeriod   FiscalPeriod `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	RequestedByID  uint         `gorm:"not null;index"`
+	RequestedBy    User         `gorm:"foreignKey:RequestedByID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT"`
+	Reason         string       `gorm:"type:text;not null"`
+	Status         string       `gorm:"type:varchar(20);default:'pending';not null;index"`
+	DecidedByID    *uint        `gorm:"index"`
+	DecidedBy      *User        `gorm:"foreignKey:DecidedByID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
+	DecidedAt      *int64
+	Comment        string `gorm:"type:text"`
+	TransactionID  string `gorm:"index"`
+}
+
+type BankStatement struct {
+	gorm.Model
+	Filename      string `gorm:"not null"`
+	Format        string `gorm:"type:varchar(20);not null"`
+	FileHash      string `gorm:"type:varchar(64);uniqueIndex;not null"`
+	UploadedByID  uint   `gorm:"not null;index"`
+	UploadedBy    User   `gorm:"foreignKey:UploadedByID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT"`
+	LineCount     int    `gorm:"default:0"`
+	MatchedCount  int    `gorm:"default:0"`
+	TransactionID string `gorm:"index"`
+	Lines         []BankStatementLine
+}
+
+type BankStatementLine struct {
+	gorm.Model
+	BankStatementID uint          `gorm:"not null;index"`
+	BankStatement   BankStatement `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	LineNumber      int           `gorm:"not null"`
+	Date            int64         `gorm:"not null;index"`
+	Amount          int           `gorm:"not null"`
+	Reference       string        `gorm:"index"`
+	Description     string        `gorm:"type:text"`
+	Status          string        `gorm:"type:varchar(20);default:'unmatched';not null;index"`
+	DepositID       *uint         `gorm:"index"`
+	Deposit         *Deposit      `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
+	MatchedByID     *uint         `gorm:"index"`
+	MatchedBy       *User         `gorm:"foreignKey:MatchedByID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
+	MatchedAt       *int64
+	Note            string `gorm:"type:text"`
+	TransactionID   string `gorm:"index"`
+}
+
+type PayrollBatch struct {
+	gorm.Model
+	Filename       string `gorm:"not null"`
+	Format         string `gorm:"type:varchar(20);not null"`
+	FileHash       string `gorm:"type:varchar(64);uniqueIndex;not null"`
+	Employer       string `gorm:"index"`
+	Status         string `gorm:"type:varchar(20);default:'previewed';not null;index"`
+	UploadedByID   uint   `gorm:"not null;index"`
+	UploadedBy     User   `gorm:"foreignKey:UploadedByID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT"`
+	PostedByID     *uint  `gorm:"index"`
+	PostedBy       *User  `gorm:"foreignKey:PostedByID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
+	PostedAt       *int64
+	RowCount       int    `gorm:"default:0"`
+	PostedCount    int    `gorm:"default:0"`
+	FailedCount    int    `gorm:"default:0"`
+	RepaymentTotal int    `gorm:"default:0"`
+	SavingsTotal   int    `gorm:"default:0"`
+	TransactionID  string `gorm:"index"`
+	Rows           []PayrollBatchRow
+}
+
+type PayrollBatchRow struct {
+	gorm.Model
+	PayrollBatchID         uint         `gorm:"not null;index"`
+	PayrollBatch           PayrollBatch `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	LineNumber             int          `gorm:"not null"`
+	MemberID               *uint        `gorm:"index"`
+	Member                 *User        `gorm:"foreignKey:MemberID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
+	Phone                  string
+	LoanID                 *uint `gorm:"index"`
+	Loan                   *Loan `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
+	RepaymentAmount        int   `gorm:"default:0;not null"`
+	SavingsAmount          int   `gorm:"default:0;not null"`
+	Reference              string
+	Status                 string `gorm:"type:varchar(20);not null;index"`
+	Error                  string `gorm:"type:text"`
+	RepaymentTransactionID string `gorm:"index"`
+	DepositTransactionID   string `gorm:"index"`
+}
+
+type FeeType struct {
+	gorm.Model
+	Code        string  `gorm:"type:varchar(50);uniqueIndex;not null"`
+	Name        string  `gorm:"not null"`
+	TriggerRule string  `gorm:"type:varchar(20);not null;index"`
+	Rate        float64 `gorm:"type:decimal(5,2);default:0;not null"`
+	Amount      int     `gorm:"default:0;not null"`
+	Cap         int     `gorm:"default:0;not null"`
+	IsActive    bool    `gorm:"default:false;not null"`
+}
+
+type ReminderTemplate struct {
+	gorm.Model
+	Event       string `gorm:"type:varchar(30);uniqueIndex;not null"`
+	Body        string `gorm:"type:text;not null"`
+	Days        int    `gorm:"default:0;not null"`
+	IsActive    bool   `gorm:"default:false;not null"`
+	UpdatedByID *uint  `gorm:"index"`
+	UpdatedBy   *User  `gorm:"foreignKey:UpdatedByID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
+}
+
+type SMSMessage struct {
+	gorm.Model
+	MessageKey    string `gorm:"uniqueIndex;not null"`
+	Event         string `gorm:"type:varchar(30);not null;index"`
+	UserID        uint   `gorm:"not null;index"`
+	User          User   `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	LoanID        *uint  `gorm:"index"`
+	PhoneNumber   string `gorm:"not null"`
+	Body          string `gorm:"type:text;not null"`
+	Status        string `gorm:"type:varchar(20);default:'pending';not null;index"`
+	Attempts      int    `gorm:"default:0;not null"`
+	NextAttemptAt int64  `gorm:"not null;index"`
+	LastError     string `gorm:"type:text"`
+	SentAt        *int64
+}
+
+type EligibilityRule struct {
+	gorm.Model
+	Code     string  `gorm:"type:varchar(50);uniqueIndex;not null"`
+	Name     string  `gorm:"not null"`
+	Value    float64 `gorm:"type:decimal(10,2);default:0;not null"`
+	IsActive bool    `gorm:"default:false;not null"`
+}
+
+// ProvisionRate is the share of the outstanding balance held against losses
+// for loans in an aging bucket.
+type ProvisionRate struct {
+	gorm.Model
+	Bucket string  `gorm:"type:varchar(20);uniqueIndex;not null"`
+	Rate   float64 `gorm:"type:decimal(5,2);default:0;not null"`
+}
+
+type FeeCharge struct {
+	gorm.Model
+	FeeTypeID           uint    `gorm:"not null;index"`
+	FeeType             FeeType `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT"`
+	UserID              uint    `gorm:"not null;index"`
+	User                User    `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT"`
+	LoanID              *uint   `gorm:"index"`
+	Loan                *Loan   `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
+	ChargeKey           string  `gorm:"uniqueIndex;not null"`
+	BaseAmount          int     `gorm:"default:0;not null"`
+	Amount              int     `gorm:"not null"`
+	PaidAmount          int     `gorm:"default:0;not null"`
+	WaivedAmount        int     `gorm:"default:0;not null"`
+	Status              string  `gorm:"type:varchar(20);default:'charged';not null;index"`
+	TransactionID       string  `gorm:"index"`
+	WaivedByID          *uint   `gorm:"index"`
+	WaivedBy            *User   `gorm:"foreignKey:WaivedByID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
+	WaivedAt            *int64
+	WaiverReason        string `gorm:"type:text"`
+	WaiverTransactionID string `gorm:"index"`
+}
+
 func Migrate() error {
 	log.Println("Running database migrations...")
 
+	// Rates were once unique per duration; versions of a rate now share it.
+	if DB.Migrator().HasIndex(&InterestRate{}, "idx_interest_rates_duration_months") {
+		if err := DB.Migrator().DropIndex(&InterestRate{}, "idx_interest_rates_duration_months"); err != nil {
+			return fmt.Errorf("migration failed: %w", err)
+		}
+	}
+
+	// Loans were paid out on approval until disbursements were recorded. The
+	// loans approved but never disbursed when the flag is introduced are
+	// those, and keep being posted as paid out on approval.
+	flagLegacyPayouts := !DB.Migrator().HasColumn(&Loan{}, "PaidOutOnApproval")
+
 	err := DB.AutoMigrate(
 		&User{},
 		&UserOtp{},
 		&Transaction{},
 		&Loan{},
 		&LoanPayment{},
+		&LoanInstallment{},
+		&LoanStatusHistory{},
+		&LoanGuarantee{},
+		&LoanRequestVersion{},
+		&LoanComment{},
+		&LoanApprovalVote{},
+		&LoanRestructure{},
+		&LoanWriteOff{},
+		&LoanRecovery{},
+		&Collateral{},
+		&Document{},
+		&PayoffQuote{},
 		&Deposit{},
+		&LoanProduct{},
+		&ApprovalPolicy{},
 		&InterestRate{},
 		&Block{},
 		&Session{},
+		&FiscalPeriod{},
+		&PeriodClosingBalance{},
+		&PeriodReopenRequest{},
+		&BankStatement{},
+		&BankStatementLine{},
+		&PayrollBatch{},
+		&PayrollBatchRow{},
+		&FeeType{},
+		&FeeCharge{},
+		&EligibilityRule{},
+		&ReminderTemplate{},
+		&SMSMessage{},
+		&ProvisionRate{},
 	)
 	if err != nil {
 		return fmt.Errorf("migration failed: %w", err)
 	}
 
+	if flagLegacyPayouts {
+		if err := DB.Model(&Loan{}).Where("approved_by_id IS NOT NULL AND disbursed_by_id IS NULL").
+			Update("paid_out_on_approval", true).Error; err != nil {
+			return fmt.Errorf("migration failed: %w", err)
+		}
+	}
+
 	log.Println("Database migrations completed successfully")
 
+	if err := SeedFeeTypes(); err != nil {
+		return fmt.Errorf("fee catalog seeding failed: %w", err)
+	}
+
+	if err := SeedEligibilityRules(); err != nil {
+		return fmt.Errorf("eligibility rule seeding failed: %w", err)
+	}
+
+	if err := SeedReminderTemplates(); err != nil {
+		return fmt.Errorf("reminder template seeding failed: %w", err)
+	}
+
+	if err := SeedProvisionRates(); err != nil {
+		return fmt.Errorf("provision rate seeding failed: %w", err)
+	}
+
+	if err := SeedApprovalPolicies(); err != nil {
+		return fmt.Errorf("approval policy seeding failed: %w", err)
+	}
+
 	if err := InitializeBlockchain(); err != nil {
 		return fmt.Errorf("blockchain initialization failed: %w", err)
 	}
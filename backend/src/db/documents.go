@@ -0,0 +1,10 @@
+package db
+
+const (
+	DocumentAgreement  = "agreement"
+	DocumentIDCopy     = "id_copy"
+	DocumentCollateral = "collateral"
+	DocumentOther      = "other"
+)
+
+var DocumentCategories = []string{DocumentAgreement, DocumentIDCopy, DocumentCollateral, DocumentOther}
@@ -0,0 +1,31 @@
+package db
+
+const (
+	RuleMaxSavingsMultiple = "max_savings_multiple"
+	RuleMaxConcurrentLoans = "max_concurrent_loans"
+	RuleMinMembershipDays  = "min_membership_days"
+	RuleNoArrears          = "no_arrears"
+	RuleAllowedDurations   = "allowed_durations"
+)
+
+// defaultEligibilityRules is the rule set created on first start. Every rule
+// starts inactive so loan requests are not refused until a manager turns the
+// rules on. The value of no_arrears and allowed_durations is unused: allowed
+// durations are the ones with a configured interest rate.
+var defaultEligibilityRules = []EligibilityRule{
+	{Code: RuleMaxSavingsMultiple, Name: "Maximum loan as a multiple of savings plus shares", Value: 3},
+	{Code: RuleMaxConcurrentLoans, Name: "Maximum number of concurrent loans", Value: 1},
+	{Code: RuleMinMembershipDays, Name: "Minimum membership age in days", Value: 90},
+	{Code: RuleNoArrears, Name: "No outstanding arrears"},
+	{Code: RuleAllowedDurations, Name: "Only durations with a configured interest rate"},
+}
+
+func SeedEligibilityRules() error {
+	for _, rule := range defaultEligibilityRules {
+		r := rule
+		if err := DB.Where("code = ?", r.Code).FirstOrCreate(&r).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
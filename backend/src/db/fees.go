@@ -0,0 +1,65 @@
+package db
+
+import "math"
+
+const (
+	FeeTriggerOnApproval  = "on_approval"
+	FeeTriggerOnOverdue   = "on_overdue"
+	FeeTriggerMonthly     = "monthly"
+	FeeTriggerOnStatement = "on_statement"
+	FeeTriggerOnPayoff    = "on_payoff"
+)
+
+const (
+	FeeCodeLoanProcessing     = "loan_processing"
+	FeeCodeLatePayment        = "late_payment"
+	FeeCodeAccountMaintenance = "account_maintenance"
+	FeeCodeStatement          = "statement"
+	FeeCodeEarlySettlement    = "early_settlement"
+)
+
+// Member fees are charged straight to savings; what available savings cannot
+// cover stays outstanding until the member has funds. Fees on a loan are owed
+// on the loan until a repayment settles them.
+const (
+	FeeStatusCharged     = "charged"
+	FeeStatusOutstanding = "outstanding"
+	FeeStatusPaid        = "paid"
+	FeeStatusWaived      = "waived"
+	FeeStatusWrittenOff  = "written_off"
+)
+
+var FeeTriggers = []string{FeeTriggerOnApproval, FeeTriggerOnOverdue, FeeTriggerMonthly, FeeTriggerOnStatement, FeeTriggerOnPayoff}
+
+// defaultFeeTypes is the catalog created on first start. Every fee starts
+// inactive with no amount so nothing is charged until a manager sets it up.
+var defaultFeeTypes = []FeeType{
+	{Code: FeeCodeLoanProcessing, Name: "Loan processing fee", TriggerRule: FeeTriggerOnApproval},
+	{Code: FeeCodeLatePayment, Name: "Late payment penalty", TriggerRule: FeeTriggerOnOverdue},
+	{Code: FeeCodeAccountMaintenance, Name: "Account maintenance fee", TriggerRule: FeeTriggerMonthly},
+	{Code: FeeCodeStatement, Name: "Statement fee", TriggerRule: FeeTriggerOnStatement},
+	{Code: FeeCodeEarlySettlement, Name: "Early settlement penalty", TriggerRule: FeeTriggerOnPayoff},
+}
+
+func SeedFeeTypes() error {
+	for _, feeType := range defaultFeeTypes {
+		ft := feeType
+		if err := DB.Where("code = ?", ft.Code).FirstOrCreate(&ft).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Compute returns the fee owed on the given base amount: the fixed amount plus
+// the percentage of the base, limited to the cap when one is set.
+func (f *FeeType) Compute(base int) int {
+	fee := f.Amount + int(math.Round(float64(base)*f.Rate/100))
+	if f.Cap > 0 && fee > f.Cap {
+		fee = f.Cap
+	}
+	if fee < 0 {
+		return 0
+	}
+	return fee
+}
@@ -0,0 +1,11 @@
+package db
+
+// A guarantee is pending until the guarantor responds. Accepted guarantees
+// lock the guarantor's savings until the loan is repaid or falls through.
+const (
+	GuaranteeStatusPending   = "pending"
+	GuaranteeStatusAccepted  = "accepted"
+	GuaranteeStatusDeclined  = "declined"
+	GuaranteeStatusReleased  = "released"
+	GuaranteeStatusCancelled = "cancelled"
+)
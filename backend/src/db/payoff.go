@@ -0,0 +1,8 @@
+package db
+
+// A quote is issued until a payment settles the loan with it. Quotes past
+// their expiry can no longer be paid.
+const (
+	PayoffQuoteIssued  = "issued"
+	PayoffQuoteSettled = "settled"
+)
@@ -0,0 +1,98 @@
+package db
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	PeriodStatusOpen   = "open"
+	PeriodStatusClosed = "closed"
+)
+
+var ErrPeriodClosed = errors.New("posting date falls inside a closed fiscal period")
+
+// PeriodBounds returns the [start, end) unix range of a fiscal period.
+// Month 0 denotes the whole year.
+func PeriodBounds(year, month int) (int64, int64, error) {
+	if month < 0 || month > 12 {
+		return 0, 0, fmt.Errorf("invalid month: %d", month)
+	}
+	if year < 1900 {
+		return 0, 0, fmt.Errorf("invalid year: %d", year)
+	}
+
+	if month == 0 {
+		start := time.Date(year, time.January, 1, 0, 0, 0, 0, time.Local)
+		return start.Unix(), start.AddDate(1, 0, 0).Unix(), nil
+	}
+
+	start := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.Local)
+	return start.Unix(), start.AddDate(0, 1, 0).Unix(), nil
+}
+
+// IsPostingDateClosed reports whether the given date falls inside any closed
+// monthly or yearly period.
+func IsPostingDateClosed(conn *gorm.DB, postedAt time.Time) (bool, error) {
+	var count int64
+	err := conn.Model(&FiscalPeriod{}).
+		Where("status = ? AND starts_at <= ? AND ends_at > ?", PeriodStatusClosed, postedAt.Unix(), postedAt.Unix()).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// checkPostingDate rejects a posting dated inside a closed fiscal period. A
+// zero date means the posting is dated now.
+func checkPostingDate(tx *gorm.DB, postedAt time.Time) error {
+	if postedAt.IsZero() {
+		postedAt = time.Now()
+	}
+
+	closed, err := IsPostingDateClosed(tx.Session(&gorm.Session{NewDB: true}), postedAt)
+	if err != nil {
+		return fmt.Errorf("failed to check fiscal period: %w", err)
+	}
+	if closed {
+		return ErrPeriodClosed
+	}
+	return nil
+}
+
+// BeforeCreate rejects transactions dated inside a closed fiscal period.
+func (t *Transaction) BeforeCreate(tx *gorm.DB) error {
+	return checkPostingDate(tx, t.CreatedAt)
+}
+
+// BeforeCreate rejects deposits dated inside a closed fiscal period.
+func (d *Deposit) BeforeCreate(tx *gorm.DB) error {
+	return checkPostingDate(tx, d.CreatedAt)
+}
+
+// BeforeCreate rejects loan payments whose payment date falls inside a
+// closed fiscal period, whenever they are recorded.
+func (p *LoanPayment) BeforeCreate(tx *gorm.DB) error {
+	postedAt := p.CreatedAt
+	if p.PaymentDate != 0 {
+		postedAt = time.Unix(p.PaymentDate, 0)
+	}
+	return checkPostingDate(tx, postedAt)
+}
+
+// HashTransactionSet hashes the ordered list of transaction hashes so a
+// single digest covers every transaction in the set.
+func HashTransactionSet(transactions []Transaction) (string, error) {
+	h := sha256.New()
+	for i := range transactions {
+		txHash, err := hashTransaction(&transactions[i])
+		if err != nil {
+			return "", fmt.Errorf("failed to hash transaction %s: %w", transactions[i].TransactionID, err)
+		}
+		h.Write([]byte(txHash))
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,31 @@
+package db
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// DurationList returns the loan durations in months the product offers. They
+// are stored as a comma-separated list.
+func (p *LoanProduct) DurationList() []int {
+	durations := []int{}
+	for _, part := range strings.Split(p.Durations, ",") {
+		if months, err := strconv.Atoi(strings.TrimSpace(part)); err == nil && months > 0 {
+			durations = append(durations, months)
+		}
+	}
+	return durations
+}
+
+// SetDurations stores the durations sorted and without repeats.
+func (p *LoanProduct) SetDurations(durations []int) {
+	sorted := slices.Clone(durations)
+	slices.Sort(sorted)
+	sorted = slices.Compact(sorted)
+	parts := make([]string, len(sorted))
+	for i, months := range sorted {
+		parts[i] = strconv.Itoa(months)
+	}
+	p.Durations = strings.Join(parts, ",")
+}
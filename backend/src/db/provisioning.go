@@ -0,0 +1,27 @@
+package db
+
+const (
+	WriteOffPending  = "pending"
+	WriteOffApproved = "approved"
+	WriteOffRejected = "rejected"
+)
+
+// defaultProvisionRates is the provisioning matrix created on first start,
+// keyed by the portfolio aging buckets.
+var defaultProvisionRates = []ProvisionRate{
+	{Bucket: "current", Rate: 1},
+	{Bucket: "1-30", Rate: 5},
+	{Bucket: "31-60", Rate: 25},
+	{Bucket: "61-90", Rate: 50},
+	{Bucket: "90+", Rate: 100},
+}
+
+func SeedProvisionRates() error {
+	for _, rate := range defaultProvisionRates {
+		r := rate
+		if err := DB.Where("bucket = ?", r.Bucket).FirstOrCreate(&r).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
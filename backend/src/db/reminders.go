@@ -0,0 +1,51 @@
+package db
+
+const (
+	ReminderInstallmentDue     = "installment_due"
+	ReminderInstallmentOverdue = "installment_overdue"
+	ReminderPaymentConfirmed   = "payment_confirmation"
+	ReminderLoanComment        = "loan_comment"
+)
+
+const (
+	SMSStatusPending = "pending"
+	SMSStatusSent    = "sent"
+	SMSStatusFailed  = "failed"
+)
+
+var ReminderEvents = []string{ReminderInstallmentDue, ReminderInstallmentOverdue, ReminderPaymentConfirmed, ReminderLoanComment}
+
+// defaultReminderTemplates are created on first start. Every template
+// starts inactive so no SMS goes out until a manager turns it on. Days is
+// how long before the due date a due reminder goes out, and how long after
+// it an overdue notice does; confirmations and comment notices ignore it.
+var defaultReminderTemplates = []ReminderTemplate{
+	{
+		Event: ReminderInstallmentDue,
+		Body:  "Dear {name}, installment {installment} of {amount} on loan #{loan_id} is due on {due_date}.",
+		Days:  3,
+	},
+	{
+		Event: ReminderInstallmentOverdue,
+		Body:  "Dear {name}, installment {installment} on loan #{loan_id} was due on {due_date} and is {days} days overdue. Please pay {amount} to avoid penalties.",
+		Days:  1,
+	},
+	{
+		Event: ReminderPaymentConfirmed,
+		Body:  "Dear {name}, we received your payment of {amount} on loan #{loan_id}. Outstanding balance: {balance}.",
+	},
+	{
+		Event: ReminderLoanComment,
+		Body:  "Dear {name}, {author} commented on loan #{loan_id}: {comment}",
+	},
+}
+
+func SeedReminderTemplates() error {
+	for _, template := range defaultReminderTemplates {
+		t := template
+		if err := DB.Where("event = ?", t.Event).FirstOrCreate(&t).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package eligibility
+
+import (
+	"backend/src/db"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+)
+
+// Applicant is what the rules need to know about a member asking for a loan.
+type Applicant struct {
+	Savings     int
+	Shares      int
+	MemberSince time.Time
+	OpenLoans   int
+	Arrears     int
+	Durations   []int
+}
+
+// Reason explains why a rule refused a request.
+type Reason struct {
+	Rule    string
+	Message string
+}
+
+// MaxAmount returns the largest loan the applicant may ask for, and false
+// when no active rule limits the amount.
+func MaxAmount(rules []db.EligibilityRule, applicant Applicant) (int, bool) {
+	for _, rule := range rules {
+		if rule.IsActive && rule.Code == db.RuleMaxSavingsMultiple {
+			return int(math.Floor(float64(applicant.Savings+applicant.Shares) * rule.Value)), true
+		}
+	}
+	return 0, false
+}
+
+// Evaluate checks a request for amount over duration months against every
+// active rule and returns the reasons it is refused. An empty result means
+// the member is eligible.
+func Evaluate(rules []db.EligibilityRule, applicant Applicant, amount, duration int, now time.Time) []Reason {
+	reasons := []Reason{}
+	refuse := func(rule db.EligibilityRule, format string, args ...interface{}) {
+		reasons = append(reasons, Reason{Rule: rule.Code, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for _, rule := range rules {
+		if !rule.IsActive {
+			continue
+		}
+		switch rule.Code {
+		case db.RuleMaxSavingsMultiple:
+			if limit, _ := MaxAmount(rules, applicant); amount > limit {
+				refuse(rule, "Requested amount %d exceeds the limit of %d (%gx savings plus shares of %d)",
+					amount, limit, rule.Value, applicant.Savings+applicant.Shares)
+			}
+		case db.RuleMaxConcurrentLoans:
+			if limit := int(rule.Value); applicant.OpenLoans >= limit {
+				refuse(rule, "You already have %d open loan(s); the limit is %d", applicant.OpenLoans, limit)
+			}
+		case db.RuleMinMembershipDays:
+			days := int(now.Sub(applicant.MemberSince).Hours() / 24)
+			if required := int(rule.Value); days < required {
+				refuse(rule, "Membership of %d day(s) is below the required %d days", days, required)
+			}
+		case db.RuleNoArrears:
+			if applicant.Arrears > 0 {
+				refuse(rule, "You have %d in arrears on existing loans", applicant.Arrears)
+			}
+		case db.RuleAllowedDurations:
+			if !slices.Contains(applicant.Durations, duration) {
+				refuse(rule, "A duration of %d months is not offered; allowed durations are %v", duration, applicant.Durations)
+			}
+		}
+	}
+	return reasons
+}
@@ -0,0 +1,126 @@
+package eligibility
+
+import (
+	"backend/src/db"
+	"testing"
+	"time"
+)
+
+func rule(code string, value float64) db.EligibilityRule {
+	return db.EligibilityRule{Code: code, Value: value, IsActive: true}
+}
+
+func TestEvaluate(t *testing.T) {
+	now := time.Date(2025, time.June, 30, 12, 0, 0, 0, time.UTC)
+	eligible := Applicant{
+		Savings:     20000,
+		Shares:      5000,
+		MemberSince: now.AddDate(0, 0, -200),
+		Durations:   []int{6, 12},
+	}
+	allRules := []db.EligibilityRule{
+		rule(db.RuleMaxSavingsMultiple, 3),
+		rule(db.RuleMaxConcurrentLoans, 1),
+		rule(db.RuleMinMembershipDays, 90),
+		rule(db.RuleNoArrears, 0),
+		rule(db.RuleAllowedDurations, 0),
+	}
+
+	tests := []struct {
+		name      string
+		rules     []db.EligibilityRule
+		applicant func(Applicant) Applicant
+		amount    int
+		duration  int
+		want      []string
+	}{
+		{name: "eligible", rules: allRules, amount: 75000, duration: 12},
+		{name: "amount above savings multiple", rules: allRules, amount: 75001, duration: 12, want: []string{db.RuleMaxSavingsMultiple}},
+		{
+			name:      "too many open loans",
+			rules:     allRules,
+			applicant: func(a Applicant) Applicant { a.OpenLoans = 1; return a },
+			amount:    1000,
+			duration:  12,
+			want:      []string{db.RuleMaxConcurrentLoans},
+		},
+		{
+			name:      "new member",
+			rules:     allRules,
+			applicant: func(a Applicant) Applicant { a.MemberSince = now.AddDate(0, 0, -89); return a },
+			amount:    1000,
+			duration:  12,
+			want:      []string{db.RuleMinMembershipDays},
+		},
+		{
+			name:      "in arrears",
+			rules:     allRules,
+			applicant: func(a Applicant) Applicant { a.Arrears = 1; return a },
+			amount:    1000,
+			duration:  12,
+			want:      []string{db.RuleNoArrears},
+		},
+		{name: "duration not offered", rules: allRules, amount: 1000, duration: 9, want: []string{db.RuleAllowedDurations}},
+		{
+			name:      "every rule fails",
+			rules:     allRules,
+			applicant: func(a Applicant) Applicant { a.OpenLoans, a.Arrears, a.MemberSince = 2, 10, now; return a },
+			amount:    100000,
+			duration:  24,
+			want: []string{
+				db.RuleMaxSavingsMultiple, db.RuleMaxConcurrentLoans, db.RuleMinMembershipDays,
+				db.RuleNoArrears, db.RuleAllowedDurations,
+			},
+		},
+		{
+			name: "inactive rules are ignored",
+			rules: []db.EligibilityRule{
+				{Code: db.RuleMaxSavingsMultiple, Value: 3},
+				{Code: db.RuleAllowedDurations},
+			},
+			amount:   1000000,
+			duration: 24,
+		},
+		{name: "no rules", amount: 1000000, duration: 24},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			applicant := eligible
+			if tt.applicant != nil {
+				applicant = tt.applicant(applicant)
+			}
+			reasons := Evaluate(tt.rules, applicant, tt.amount, tt.duration, now)
+			if len(reasons) != len(tt.want) {
+				t.Fatalf("Evaluate returned %+v, want rules %v", reasons, tt.want)
+			}
+			for i, reason := range reasons {
+				if reason.Rule != tt.want[i] {
+					t.Errorf("reason %d is %s, want %s", i, reason.Rule, tt.want[i])
+				}
+				if reason.Message == "" {
+					t.Errorf("reason %d for %s has no message", i, reason.Rule)
+				}
+			}
+		})
+	}
+}
+
+func TestMaxAmount(t *testing.T) {
+	applicant := Applicant{Savings: 1000, Shares: 500}
+	tests := []struct {
+		name    string
+		rules   []db.EligibilityRule
+		want    int
+		limited bool
+	}{
+		{name: "multiple of savings plus shares", rules: []db.EligibilityRule{rule(db.RuleMaxSavingsMultiple, 2.5)}, want: 3750, limited: true},
+		{name: "inactive rule", rules: []db.EligibilityRule{{Code: db.RuleMaxSavingsMultiple, Value: 3}}},
+		{name: "no rule"},
+	}
+	for _, tt := range tests {
+		got, limited := MaxAmount(tt.rules, applicant)
+		if got != tt.want || limited != tt.limited {
+			t.Errorf("%s: MaxAmount = %d, %v, want %d, %v", tt.name, got, limited, tt.want, tt.limited)
+		}
+	}
+}
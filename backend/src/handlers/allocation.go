@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"backend/src/amortization"
+	"backend/src/db"
+	"log"
+	"os"
+	"time"
+)
+
+type PaymentAllocation struct {
+	Penalties  int `json:"penalties" example:"0"`
+	Fees       int `json:"fees" example:"500"`
+	Interest   int `json:"interest" example:"1000"`
+	Principal  int `json:"principal" example:"8333"`
+	Prepayment int `json:"prepayment" example:"0"`
+	Credit     int `json:"credit" example:"0"`
+}
+
+// paymentWaterfall reads the allocation order from PAYMENT_WATERFALL,
+// falling back to the default when it is unset or invalid.
+func paymentWaterfall() amortization.Waterfall {
+	waterfall, err := amortization.ParseWaterfall(os.Getenv("PAYMENT_WATERFALL"))
+	if err != nil {
+		log.Printf("WARNING: Invalid PAYMENT_WATERFALL, using default: %v", err)
+		return amortization.DefaultWaterfall
+	}
+	return waterfall
+}
+
+// prepayOverpayments reports whether money beyond what is due reduces
+// principal early (the default) or is credited back to the member's savings.
+func prepayOverpayments() bool {
+	return os.Getenv("OVERPAYMENT_HANDLING") != "credit"
+}
+
+// loanDues works out what a loan owes right now: unpaid penalties and fees,
+// plus the interest and principal of every installment up to and including
+// the current one, less what has already been repaid.
+func loanDues(loan *db.Loan, now time.Time) (amortization.Dues, []db.FeeCharge, error) {
+	dues := amortization.Dues{Outstanding: loan.OutstandingBalance}
+
+	charges, err := feeRepo.GetOutstandingLoanCharges(loan.ID)
+	if err != nil {
+		return dues, nil, err
+	}
+	for _, charge := range charges {
+		owed := charge.Amount - charge.PaidAmount
+		if charge.FeeType.TriggerRule == db.FeeTriggerOnOverdue {
+			dues.Penalties += owed
+		} else {
+			dues.Fees += owed
+		}
+	}
+
+	installments, _, _, err := loanSchedule(loan)
+	if err != nil {
+		return dues, nil, err
+	}
+	paidPrincipal, paidInterest, err := scheduleRepo.GetPaidSplit(loan.ID, scheduleStart(loan))
+	if err != nil {
+		return dues, nil, err
+	}
+
+	scheduledPrincipal, scheduledInterest := 0, 0
+	for _, installment := range installments {
+		scheduledPrincipal += installment.Principal
+		scheduledInterest += installment.Interest
+		if installment.DueDate > now.Unix() {
+			break
+		}
+	}
+	dues.Principal = max(0, scheduledPrincipal-paidPrincipal)
+	dues.Interest = max(0, scheduledInterest-paidInterest)
+
+	return dues, charges, nil
+}
+
+// settleLoanCharges applies the penalty and fee portions of a repayment to
+// the loan's outstanding charges, oldest first.
+func settleLoanCharges(charges []db.FeeCharge, penalties, fees int) error {
+	for i := range charges {
+		charge := &charges[i]
+
+		pool := &fees
+		if charge.FeeType.TriggerRule == db.FeeTriggerOnOverdue {
+			pool = &penalties
+		}
+		if *pool <= 0 {
+			continue
+		}
+
+		paid := min(*pool, charge.Amount-charge.PaidAmount)
+		*pool -= paid
+		charge.PaidAmount += paid
+		if charge.PaidAmount == charge.Amount {
+			charge.Status = db.FeeStatusPaid
+		}
+		if err := feeRepo.SaveChargePayment(charge); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func toPaymentAllocation(allocation amortization.Allocation) PaymentAllocation {
+	return PaymentAllocation{
+		Penalties:  allocation.Penalties,
+		Fees:       allocation.Fees,
+		Interest:   allocation.Interest,
+		Principal:  allocation.Principal,
+		Prepayment: allocation.Prepayment,
+		Credit:     allocation.Credit,
+	}
+}
@@ -0,0 +1,430 @@
+package handlers
+
+import (
+	"backend/src/db"
+	"backend/src/repos"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+var approvalRepo = repos.ApprovalRepo{}
+
+type ApprovalPolicyRequest struct {
+	MinAmount         *int   `json:"min_amount" example:"50000"`
+	RequiredApprovals *int   `json:"required_approvals" example:"2"`
+	CommitteeIDs      []uint `json:"committee_ids" example:"2,5,7"`
+	IsActive          *bool  `json:"is_active" example:"true"`
+}
+
+type ApprovalPolicyItem struct {
+	ID                uint          `json:"id" example:"2"`
+	MinAmount         int           `json:"min_amount" example:"50000"`
+	MaxAmount         *int          `json:"max_amount,omitempty" example:"199999"`
+	RequiredApprovals int           `json:"required_approvals" example:"2"`
+	Committee         []ManagerInfo `json:"committee"`
+	IsActive          bool          `json:"is_active" example:"true"`
+	UpdatedBy         *ManagerInfo  `json:"updated_by,omitempty"`
+	UpdatedAt         string        `json:"updated_at" example:"2025-01-15T10:30:00Z"`
+}
+
+type ApprovalPolicyListResponse struct {
+	Policies []ApprovalPolicyItem `json:"policies"`
+}
+
+type LoanApprovalVoteItem struct {
+	ID             uint         `json:"id" example:"1"`
+	Voter          *ManagerInfo `json:"voter,omitempty"`
+	Decision       string       `json:"decision" example:"approve"`
+	Comment        string       `json:"comment,omitempty" example:"Income verified against payslips"`
+	RequestVersion int          `json:"request_version" example:"1"`
+	TransactionID  string       `json:"transaction_id" example:"TXN-1234567890"`
+	CreatedAt      string       `json:"created_at" example:"2025-01-15T10:30:00Z"`
+}
+
+type LoanApprovalsResponse struct {
+	LoanID            uint                   `json:"loan_id" example:"1"`
+	Status            string                 `json:"status" example:"UnderReview"`
+	RequestVersion    int                    `json:"request_version" example:"1"`
+	Policy            *ApprovalPolicyItem    `json:"policy,omitempty"`
+	RequiredApprovals int                    `json:"required_approvals" example:"2"`
+	Approvals         int                    `json:"approvals" example:"1"`
+	Rejections        int                    `json:"rejections" example:"0"`
+	Votes             []LoanApprovalVoteItem `json:"votes"`
+}
+
+// approvalRound is the voting on the current version of a loan request
+// under the policy for its amount.
+type approvalRound struct {
+	Policy     *db.ApprovalPolicy
+	Votes      []db.LoanApprovalVote
+	Approvals  int
+	Rejections int
+}
+
+func loadApprovalRound(loan *db.Loan) (*approvalRound, error) {
+	policy, err := approvalRepo.GetPolicyForAmount(loan.Amount)
+	if err != nil {
+		return nil, err
+	}
+	version := loan.RequestVersion
+	votes, err := approvalRepo.GetVotes(loan.ID, &version)
+	if err != nil {
+		return nil, err
+	}
+
+	round := &approvalRound{Policy: policy}
+	for _, vote := range votes {
+		round.add(vote)
+	}
+	return round, nil
+}
+
+func (r *approvalRound) add(vote db.LoanApprovalVote) {
+	r.Votes = append(r.Votes, vote)
+	if vote.Decision == db.VoteApprove {
+		r.Approvals++
+	} else {
+		r.Rejections++
+	}
+}
+
+func (r *approvalRound) canVote(userID uint) bool {
+	if len(r.Policy.Committee) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(r.Policy.Committee, func(member db.User) bool { return member.ID == userID })
+}
+
+func (r *approvalRound) hasVoted(userID uint) bool {
+	return slices.ContainsFunc(r.Votes, func(vote db.LoanApprovalVote) bool { return vote.VoterID == userID })
+}
+
+// approved reports whether quorum has been reached. rejected reports
+// whether it no longer can be: without a committee any manager's rejection
+// settles it, with one it takes enough rejections that the remaining members
+// cannot make up the required approvals.
+func (r *approvalRound) approved() bool {
+	return r.Approvals >= r.Policy.RequiredApprovals
+}
+
+func (r *approvalRound) rejected() bool {
+	if len(r.Policy.Committee) == 0 {
+		return r.Rejections > 0
+	}
+	return len(r.Policy.Committee)-r.Rejections < r.Policy.RequiredApprovals
+}
+
+// voters lists every vote in the round for the decision's anchored
+// description.
+func (r *approvalRound) voters() string {
+	votes := make([]string, len(r.Votes))
+	for i, vote := range r.Votes {
+		votes[i] = fmt.Sprintf("%s by %s (user %d)", vote.Decision, vote.Voter.Name, vote.VoterID)
+	}
+	return strings.Join(votes, ", ")
+}
+
+// castApprovalVote adds a vote to the round without saving it, so the
+// round can be tallied before anything is written.
+func castApprovalVote(loan *db.Loan, round *approvalRound, voter *repos.UserWithSession, decision, comment string) *db.LoanApprovalVote {
+	round.add(db.LoanApprovalVote{
+		LoanID:         loan.ID,
+		RequestVersion: loan.RequestVersion,
+		VoterID:        voter.ID,
+		Voter:          db.User{Name: voter.Name},
+		Decision:       decision,
+		Comment:        comment,
+	})
+	return &round.Votes[len(round.Votes)-1]
+}
+
+// saveApprovalVote anchors a cast vote and stores it.
+func saveApprovalVote(vote *db.LoanApprovalVote) error {
+	description := fmt.Sprintf("User %d voted to %s request version %d of loan %d", vote.VoterID, vote.Decision, vote.RequestVersion, vote.LoanID)
+	if vote.Comment != "" {
+		description += ": " + vote.Comment
+	}
+	transaction := &db.Transaction{
+		TransactionID: transactionGenerator(),
+		Type:          "loan_approval_vote",
+		FromAccount:   fmt.Sprintf("LOAN-%d", vote.LoanID),
+		ToAccount:     strings.ToUpper(vote.Decision),
+		Amount:        0,
+		Status:        "completed",
+		Description:   description,
+	}
+	if err := anchorTransaction(transaction); err != nil {
+		return err
+	}
+
+	vote.TransactionID = transaction.TransactionID
+	record := *vote
+	record.Voter = db.User{}
+	return approvalRepo.CreateVote(&record)
+}
+
+// toApprovalPolicyItems lists the bands in order, each ending below the
+// next active one.
+func toApprovalPolicyItems(policies []db.ApprovalPolicy) []ApprovalPolicyItem {
+	items := []ApprovalPolicyItem{}
+	for i := range policies {
+		item := toApprovalPolicyItem(&policies[i])
+		if item.IsActive {
+			for _, next := range policies[i+1:] {
+				if next.IsActive {
+					maxAmount := next.MinAmount - 1
+					item.MaxAmount = &maxAmount
+					break
+				}
+			}
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+func toApprovalPolicyItem(policy *db.ApprovalPolicy) ApprovalPolicyItem {
+	item := ApprovalPolicyItem{
+		ID:                policy.ID,
+		MinAmount:         policy.MinAmount,
+		RequiredApprovals: policy.RequiredApprovals,
+		Committee:         []ManagerInfo{},
+		IsActive:          policy.IsActive,
+		UpdatedAt:         policy.UpdatedAt.Format(time.RFC3339),
+	}
+	for _, member := range policy.Committee {
+		item.Committee = append(item.Committee, ManagerInfo{ID: member.ID, Name: member.Name})
+	}
+	if policy.UpdatedBy != nil {
+		item.UpdatedBy = &ManagerInfo{ID: policy.UpdatedBy.ID, Name: policy.UpdatedBy.Name}
+	}
+	return item
+}
+
+func applyApprovalPolicyRequest(policy *db.ApprovalPolicy, req *ApprovalPolicyRequest) string {
+	base := policy.ID != 0 && policy.MinAmount == 0
+	if req.MinAmount != nil {
+		if base && *req.MinAmount != 0 {
+			return "The base band must start at 0"
+		}
+		policy.MinAmount = *req.MinAmount
+	}
+	if req.RequiredApprovals != nil {
+		policy.RequiredApprovals = *req.RequiredApprovals
+	}
+	if req.IsActive != nil {
+		if base && !*req.IsActive {
+			return "The base band cannot be deactivated"
+		}
+		policy.IsActive = *req.IsActive
+	}
+
+	if policy.MinAmount < 0 {
+		return "Minimum amount cannot be negative"
+	}
+	if policy.RequiredApprovals < 1 {
+		return "At least one approval is required"
+	}
+
+	if req.CommitteeIDs != nil {
+		policy.Committee = []db.User{}
+		for _, userID := range req.CommitteeIDs {
+			if slices.ContainsFunc(policy.Committee, func(member db.User) bool { return member.ID == userID }) {
+				continue
+			}
+			member, err := userRepoHandler.GetByID(userID)
+			if err != nil || member.Role != "manager" || !member.IsActive {
+				return fmt.Sprintf("User %d is not an active manager", userID)
+			}
+			policy.Committee = append(policy.Committee, *member)
+		}
+	}
+	if len(policy.Committee) > 0 && policy.RequiredApprovals > len(policy.Committee) {
+		return fmt.Sprintf("A committee of %d cannot give %d approvals", len(policy.Committee), policy.RequiredApprovals)
+	}
+
+	taken, err := approvalRepo.MinAmountTaken(policy.MinAmount, policy.ID)
+	if err != nil {
+		return "Failed to check existing bands"
+	}
+	if taken {
+		return fmt.Sprintf("A band starting at %d already exists", policy.MinAmount)
+	}
+	return ""
+}
+
+// ListApprovalPolicies godoc
+// @Summary List loan approval policies
+// @Description Returns the approval bands by amount. A loan falls in the active band with the highest minimum at or below its amount and needs that band's number of approvals; with a committee only its members may vote.
+// @Tags loans
+// @Produce json
+// @Security SessionAuth
+// @Success 200 {object} ApprovalPolicyListResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/loans/approval_policies [get]
+func ListApprovalPolicies(c echo.Context) error {
+	policies, err := approvalRepo.GetPolicies()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch approval policies"})
+	}
+
+	return c.JSON(http.StatusOK, ApprovalPolicyListResponse{Policies: toApprovalPolicyItems(policies)})
+}
+
+// CreateApprovalPolicy godoc
+// @Summary Add a loan approval band (manager)
+// @Description Adds an approval band starting at min_amount. Committee members must be active managers, and a committee must be at least as large as the number of approvals required.
+// @Tags loans
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param request body ApprovalPolicyRequest true "Approval band"
+// @Success 200 {object} ApprovalPolicyItem
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/loans/approval_policies [post]
+func CreateApprovalPolicy(c echo.Context) error {
+	user := c.Get("user").(*repos.UserWithSession)
+
+	var req ApprovalPolicyRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request"})
+	}
+	if req.MinAmount == nil || req.RequiredApprovals == nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Minimum amount and required approvals are required"})
+	}
+
+	policy := &db.ApprovalPolicy{IsActive: true}
+	if message := applyApprovalPolicyRequest(policy, &req); message != "" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: message})
+	}
+
+	updatedByID := user.ID
+	policy.UpdatedByID = &updatedByID
+	if err := approvalRepo.Save(policy); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to create approval policy"})
+	}
+	item := toApprovalPolicyItem(policy)
+	item.UpdatedBy = &ManagerInfo{ID: user.ID, Name: user.Name}
+
+	return c.JSON(http.StatusOK, item)
+}
+
+// UpdateApprovalPolicy godoc
+// @Summary Update a loan approval band (manager)
+// @Description Changes an approval band. Omitted fields are left unchanged; committee_ids replaces the committee when given and an empty list lets any manager vote. The base band starting at 0 cannot be moved or deactivated. Votes already cast are counted against the band in force when the next vote is cast.
+// @Tags loans
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param id path int true "Policy ID"
+// @Param request body ApprovalPolicyRequest true "Approval band"
+// @Success 200 {object} ApprovalPolicyItem
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/loans/approval_policies/{id} [post]
+func UpdateApprovalPolicy(c echo.Context) error {
+	user := c.Get("user").(*repos.UserWithSession)
+
+	policyID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid policy ID"})
+	}
+
+	var req ApprovalPolicyRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request"})
+	}
+
+	policy, err := approvalRepo.GetPolicy(uint(policyID))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Approval policy not found"})
+	}
+
+	if message := applyApprovalPolicyRequest(policy, &req); message != "" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: message})
+	}
+
+	updatedByID := user.ID
+	policy.UpdatedByID = &updatedByID
+	if err := approvalRepo.Save(policy); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update approval policy"})
+	}
+	item := toApprovalPolicyItem(policy)
+	item.UpdatedBy = &ManagerInfo{ID: user.ID, Name: user.Name}
+
+	return c.JSON(http.StatusOK, item)
+}
+
+// GetLoanApprovals godoc
+// @Summary Get the approval votes on a loan
+// @Description Returns the approval policy for the loan's amount, the tally of votes on the current version of the request and every vote cast on the loan, oldest first, with the comment given and its anchored transaction.
+// @Tags loans
+// @Produce json
+// @Security SessionAuth
+// @Param id path int true "Loan ID"
+// @Success 200 {object} LoanApprovalsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/loans/{id}/approvals [get]
+func GetLoanApprovals(c echo.Context) error {
+	loanID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid loan ID"})
+	}
+
+	loan, err := loanRepoHandler.GetByID(uint(loanID))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Loan not found"})
+	}
+
+	round, err := loadApprovalRound(loan)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch approval policy"})
+	}
+	votes, err := approvalRepo.GetVotes(loan.ID, nil)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch votes"})
+	}
+
+	policy := toApprovalPolicyItem(round.Policy)
+	response := LoanApprovalsResponse{
+		LoanID:            loan.ID,
+		Status:            loan.Status,
+		RequestVersion:    loan.RequestVersion,
+		Policy:            &policy,
+		RequiredApprovals: round.Policy.RequiredApprovals,
+		Approvals:         round.Approvals,
+		Rejections:        round.Rejections,
+		Votes:             []LoanApprovalVoteItem{},
+	}
+	for _, vote := range votes {
+		response.Votes = append(response.Votes, LoanApprovalVoteItem{
+			ID:             vote.ID,
+			Voter:          &ManagerInfo{ID: vote.Voter.ID, Name: vote.Voter.Name},
+			Decision:       vote.Decision,
+			Comment:        vote.Comment,
+			RequestVersion: vote.RequestVersion,
+			TransactionID:  vote.TransactionID,
+			CreatedAt:      vote.CreatedAt.Format(time.RFC3339),
+		})
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
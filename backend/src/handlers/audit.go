@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"backend/src/accounting"
 	"backend/src/blockchain"
 	"backend/src/db"
+	"backend/src/lifecycle"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -19,6 +21,8 @@ type FinancialSummaryResponse struct {
 	TotalLoansDisbursed        int    `json:"total_loans_disbursed" example:"500000"`
 	TotalLoansOutstanding      int    `json:"total_loans_outstanding" example:"300000"`
 	TotalLoansRepaid           int    `json:"total_loans_repaid" example:"200000"`
+	TotalWrittenOff            int    `json:"total_written_off" example:"40000"`
+	TotalRecovered             int    `json:"total_recovered" example:"5000"`
 	TotalProfit                int    `json:"total_profit" example:"50000"`
 	TotalInterestEarned        int    `json:"total_interest_earned" example:"75000"`
 	TotalMembers               int64  `json:"total_members" example:"150"`
@@ -44,6 +48,13 @@ type OutstandingLoanItem struct {
 	Reason                string  `json:"reason" example:"Home renovation"`
 	BlockchainVerified    bool    `json:"blockchain_verified" example:"true"`
 	BlockchainHash        string  `json:"blockchain_hash" example:"0xabc123..."`
+	CollateralValue       int     `json:"collateral_value" example:"60000"`
+	CoverageRatio         float64 `json:"coverage_ratio" example:"1.43"`
+	Restructured          bool    `json:"restructured" example:"false"`
+	RestructureCount      int     `json:"restructure_count" example:"0"`
+	TopUpOfID             *uint   `json:"top_up_of_id,omitempty" example:"98"`
+	TopUpAmount           int     `json:"top_up_amount,omitempty" example:"30000"`
+	RefinancedIntoID      *uint   `json:"refinanced_into_id,omitempty" example:"130"`
 }
 
 // OutstandingLoansResponse represents the outstanding loans list
@@ -64,6 +75,7 @@ type AuditTransactionItem struct {
 	Reference             string `json:"reference" example:"BANK-TX-12345"`
 	Timestamp             string `json:"timestamp" example:"2025-12-01T14:30:00Z"`
 	LoanID                *uint  `json:"loan_id,omitempty" example:"123"`
+	LinkedLoanID          *uint  `json:"linked_loan_id,omitempty" example:"98"`
 	BlockchainVerified    bool   `json:"blockchain_verified" example:"true"`
 	BlockchainHash        string `json:"blockchain_hash" example:"0xdef456..."`
 	BlockchainBlockNumber *uint  `json:"blockchain_block_number,omitempty" example:"12345"`
@@ -92,6 +104,7 @@ type UserAuditReportResponse struct {
 	TotalLoansTaken         int64   `json:"total_loans_taken" example:"3"`
 	TotalLoansAmount        int     `json:"total_loans_amount" example:"150000"`
 	TotalLoansRepaid        int     `json:"total_loans_repaid" example:"100000"`
+	TotalRefinanced         int     `json:"total_refinanced" example:"30000"`
 	CurrentOutstanding      int     `json:"current_outstanding" example:"50000"`
 	TransactionCount        int64   `json:"transaction_count" example:"25"`
 	BlockchainVerifiedTrans int64   `json:"blockchain_verified_transactions" example:"25"`
@@ -126,20 +139,34 @@ func GetFinancialSummary(c echo.Context) error {
 	db.DB.Model(&db.Deposit{}).Select("COALESCE(SUM(amount), 0)").Scan(&totalDeposits)
 
 	var totalLoansDisbursed int
-	db.DB.Model(&db.Loan{}).Where("status IN ?", []string{"Approved", "Closed"}).
+	db.DB.Model(&db.Loan{}).Where("status IN ?", append([]string{lifecycle.StatusPaidOff, lifecycle.StatusWrittenOff, "Closed"}, lifecycle.ActiveStatuses...)).
 		Select("COALESCE(SUM(amount), 0)").Scan(&totalLoansDisbursed)
 
 	var totalLoansOutstanding int
-	db.DB.Model(&db.Loan{}).Where("status = ?", "Approved").
+	db.DB.Model(&db.Loan{}).Where("status IN ?", lifecycle.ActiveStatuses).
 		Select("COALESCE(SUM(outstanding_balance), 0)").Scan(&totalLoansOutstanding)
 
-	totalLoansRepaid := totalLoansDisbursed - totalLoansOutstanding
+	var totalLoansRepaid int
+	db.DB.Model(&db.LoanPayment{}).Where("status = ?", "completed").
+		Select("COALESCE(SUM(principal_amount), 0)").Scan(&totalLoansRepaid)
+
+	var writtenOff struct {
+		WrittenOff int
+		Recovered  int
+	}
+	db.DB.Model(&db.Loan{}).Where("status = ?", lifecycle.StatusWrittenOff).
+		Select("COALESCE(SUM(written_off_amount), 0) AS written_off, COALESCE(SUM(recovered_amount), 0) AS recovered").
+		Scan(&writtenOff)
 
 	var totalInterestEarned int
 	db.DB.Model(&db.LoanPayment{}).Select("COALESCE(SUM(interest_amount), 0)").Scan(&totalInterestEarned)
 
-	totalAssets := totalDeposits
-	totalProfit := totalInterestEarned
+	entries, err := ledgerRepo.GetJournal(nil, nil)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to build journal"})
+	}
+	totalAssets := accounting.BuildBalanceSheet(entries).TotalAssets
+	totalProfit := accounting.BuildIncomeStatement(entries).NetIncome
 
 	var totalMembers int64
 	db.DB.Model(&db.User{}).Where("role = ?", "member").Count(&totalMembers)
@@ -156,6 +183,8 @@ func GetFinancialSummary(c echo.Context) error {
 		TotalLoansDisbursed:        totalLoansDisbursed,
 		TotalLoansOutstanding:      totalLoansOutstanding,
 		TotalLoansRepaid:           totalLoansRepaid,
+		TotalWrittenOff:            writtenOff.WrittenOff,
+		TotalRecovered:             writtenOff.Recovered,
 		TotalProfit:                totalProfit,
 		TotalInterestEarned:        totalInterestEarned,
 		TotalMembers:               totalMembers,
@@ -188,7 +217,7 @@ func GetOutstandingLoans(c echo.Context) error {
 	if status != "" {
 		query = query.Where("status = ?", status)
 	} else {
-		query = query.Where("status = ?", "Approved")
+		query = query.Where("status IN ?", lifecycle.ActiveStatuses)
 	}
 
 	// Apply sorting
@@ -248,9 +277,25 @@ func GetOutstandingLoans(c echo.Context) error {
 			expectedDate := time.Unix(*loan.DisbursedAt, 0).AddDate(0, loan.Duration, 0)
 			expectedRepaymentDate = expectedDate.Format(time.RFC3339)
 		}
+		// A restructured loan is repaid on its new schedule.
+		if loan.RestructuredAt != nil {
+			if installments, err := scheduleRepo.GetSchedule(loan.ID); err == nil && len(installments) > 0 {
+				expectedRepaymentDate = time.Unix(installments[len(installments)-1].DueDate, 0).Format(time.RFC3339)
+			}
+		}
 
 		totalRepayment := loan.Principal + int(float64(loan.Principal)*loan.InterestRate/100.0)
-		amountRepaid := loan.Principal - loan.OutstandingBalance
+		amountRepaid := max(0, loan.Principal-loan.OutstandingBalance)
+
+		collateralValue, err := collateralRepo.GetPledgedValue(loan.ID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch collateral"})
+		}
+
+		refinancedIntoID, err := refinancedInto(loan.ID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch top-ups"})
+		}
 
 		item := OutstandingLoanItem{
 			LoanID:                loan.ID,
@@ -269,6 +314,13 @@ func GetOutstandingLoans(c echo.Context) error {
 			Reason:                loan.Reason,
 			BlockchainVerified:    blockchainVerified,
 			BlockchainHash:        blockchainHash,
+			CollateralValue:       collateralValue,
+			CoverageRatio:         coverageRatio(collateralValue, loan.OutstandingBalance),
+			Restructured:          loan.RestructuredAt != nil,
+			RestructureCount:      loan.RestructureCount,
+			TopUpOfID:             loan.TopUpOfID,
+			TopUpAmount:           loan.TopUpAmount,
+			RefinancedIntoID:      refinancedIntoID,
 		}
 
 		outstandingLoans = append(outstandingLoans, item)
@@ -387,6 +439,18 @@ func GetAllTransactions(c echo.Context) error {
 			}
 		}
 
+		// Loan transactions are posted from the loan's account; a top-up
+		// settlement is posted to the loan it refinanced.
+		var loanID, linkedLoanID *uint
+		var id uint
+		if _, err := fmt.Sscanf(tx.FromAccount, "LOAN-%d", &id); err == nil {
+			loanID = &id
+		}
+		var linkedID uint
+		if _, err := fmt.Sscanf(tx.ToAccount, "LOAN-%d", &linkedID); err == nil {
+			linkedLoanID = &linkedID
+		}
+
 		item := AuditTransactionItem{
 			TransactionID:         tx.TransactionID,
 			TransactionType:       tx.Type,
@@ -396,6 +460,8 @@ func GetAllTransactions(c echo.Context) error {
 			Amount:                tx.Amount,
 			Reference:             tx.TransactionID,
 			Timestamp:             tx.CreatedAt.Format(time.RFC3339),
+			LoanID:                loanID,
+			LinkedLoanID:          linkedLoanID,
 			BlockchainVerified:    blockchainVerified,
 			BlockchainHash:        block.EthereumTxHash,
 			BlockchainBlockNumber: blockNumber,
@@ -639,11 +705,17 @@ func GetUserAuditReport(c echo.Context) error {
 		Select("COALESCE(SUM(amount), 0)").Scan(&totalLoansAmount)
 
 	var currentOutstanding int
-	db.DB.Model(&db.Loan{}).Where("borrower_id = ? AND status = ?", userID, "Approved").
+	db.DB.Model(&db.Loan{}).Where("borrower_id = ? AND status IN ?", userID, lifecycle.ActiveStatuses).
 		Select("COALESCE(SUM(outstanding_balance), 0)").Scan(&currentOutstanding)
 
 	totalRepaid := totalLoansAmount - currentOutstanding
 
+	// Part of what was repaid may have been settled by top-up loans rather
+	// than paid in.
+	var totalRefinanced int
+	db.DB.Model(&db.Loan{}).Where("borrower_id = ?", userID).
+		Select("COALESCE(SUM(top_up_amount), 0)").Scan(&totalRefinanced)
+
 	var txCount int64
 	db.DB.Model(&db.Transaction{}).
 		Where("from_account LIKE ? OR to_account LIKE ?",
@@ -682,6 +754,7 @@ func GetUserAuditReport(c echo.Context) error {
 		TotalLoansTaken:         loanCount,
 		TotalLoansAmount:        totalLoansAmount,
 		TotalLoansRepaid:        totalRepaid,
+		TotalRefinanced:         totalRefinanced,
 		CurrentOutstanding:      currentOutstanding,
 		TransactionCount:        txCount,
 		BlockchainVerifiedTrans: verifiedCount,
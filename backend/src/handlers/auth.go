@@ -44,6 +44,13 @@ type ErrorResponse struct {
 	Error string `json:"error" example:"Invalid request"`
 }
 
+// OKResponse acknowledges an action that has nothing to return but a
+// confirmation.
+type OKResponse struct {
+	OK      bool   `json:"ok" example:"true"`
+	Message string `json:"message" example:"Done"`
+}
+
 // Login godoc
 // @Summary Login with phone and password
 // @Description Authenticate with phone number and password
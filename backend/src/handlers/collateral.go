@@ -0,0 +1,345 @@
+package handlers
+
+import (
+	"backend/src/db"
+	"backend/src/lifecycle"
+	"backend/src/repos"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+var collateralRepo = repos.CollateralRepo{}
+
+type RegisterCollateralRequest struct {
+	Type          string `json:"type" binding:"required" example:"land_title"`
+	Description   string `json:"description" binding:"required" example:"Plot 42, Block 7, Kampala"`
+	Valuation     int    `json:"valuation" binding:"required" example:"250000"`
+	ValuationDate string `json:"valuation_date" example:"2025-01-10"`
+}
+
+type RevalueCollateralRequest struct {
+	Valuation     int    `json:"valuation" binding:"required" example:"230000"`
+	ValuationDate string `json:"valuation_date" example:"2025-07-10"`
+}
+
+type CollateralItem struct {
+	ID             uint   `json:"id" example:"1"`
+	LoanID         uint   `json:"loan_id" example:"1"`
+	Type           string `json:"type" example:"land_title"`
+	Description    string `json:"description" example:"Plot 42, Block 7, Kampala"`
+	Valuation      int    `json:"valuation" example:"250000"`
+	ValuationDate  string `json:"valuation_date" example:"2025-01-10T00:00:00Z"`
+	LienStatus     string `json:"lien_status" example:"active"`
+	LienReleasedAt string `json:"lien_released_at,omitempty" example:"2026-01-10T00:00:00Z"`
+	RecordHash     string `json:"record_hash" example:"9f86d081884c7d65..."`
+	TransactionID  string `json:"transaction_id" example:"TXN-1234567890"`
+	Verified       bool   `json:"verified" example:"true"`
+}
+
+type LoanCollateralResponse struct {
+	LoanID          uint             `json:"loan_id" example:"1"`
+	Outstanding     int              `json:"outstanding" example:"95000"`
+	CollateralValue int              `json:"collateral_value" example:"250000"`
+	CoverageRatio   float64          `json:"coverage_ratio" example:"2.63"`
+	Collateral      []CollateralItem `json:"collateral"`
+}
+
+// coverageRatio is the pledged value per unit of outstanding balance.
+func coverageRatio(value, outstanding int) float64 {
+	if outstanding <= 0 {
+		return 0
+	}
+	return math.Round(float64(value)*100/float64(outstanding)) / 100
+}
+
+// collateralVerified reports whether the record still matches its stored
+// hash and that hash is the one anchored by its latest transaction.
+func collateralVerified(collateral *db.Collateral) bool {
+	if collateral.RecordHash == "" || collateral.Hash() != collateral.RecordHash {
+		return false
+	}
+	transaction, err := collateralRepo.GetTransaction(collateral.TransactionID)
+	if err != nil {
+		return false
+	}
+	return strings.Contains(transaction.Description, collateral.RecordHash)
+}
+
+// anchorCollateral hashes the current state of the record, anchors the hash
+// and saves it with the transaction that proves it. Pledging collateral moves
+// no money, so the valuation is kept in the description and the hash rather
+// than the transaction amount.
+func anchorCollateral(collateral *db.Collateral, event string) error {
+	collateral.RecordHash = collateral.Hash()
+	transaction := &db.Transaction{
+		TransactionID: transactionGenerator(),
+		Type:          "collateral_record",
+		FromAccount:   fmt.Sprintf("LOAN-%d", collateral.LoanID),
+		ToAccount:     fmt.Sprintf("COLLATERAL-%d", collateral.ID),
+		Amount:        0,
+		Status:        "completed",
+		Description: fmt.Sprintf("Collateral #%d (%s) on loan #%d %s at %d; record hash %s",
+			collateral.ID, collateral.Type, collateral.LoanID, event, collateral.Valuation, collateral.RecordHash),
+	}
+	if err := anchorTransaction(transaction); err != nil {
+		return err
+	}
+	collateral.TransactionID = transaction.TransactionID
+	return collateralRepo.Save(collateral)
+}
+
+// releaseCollateral lifts the liens on a loan that has been repaid or fell
+// through. Records that no longer match their anchored hash keep their lien
+// so the discrepancy is investigated rather than re-anchored.
+func releaseCollateral(loan *db.Loan) {
+	collateral, err := collateralRepo.GetByLoan(loan.ID)
+	if err != nil {
+		log.Printf("WARNING: Failed to fetch collateral for loan %d: %v", loan.ID, err)
+		return
+	}
+
+	now := time.Now().Unix()
+	for i := range collateral {
+		if collateral[i].LienStatus != db.LienStatusActive {
+			continue
+		}
+		if !collateralVerified(&collateral[i]) {
+			log.Printf("WARNING: Collateral %d does not match its anchored hash; lien not released", collateral[i].ID)
+			continue
+		}
+		collateral[i].LienStatus = db.LienStatusReleased
+		collateral[i].LienReleasedAt = &now
+		if err := anchorCollateral(&collateral[i], fmt.Sprintf("lien released (loan %s)", loan.Status)); err != nil {
+			log.Printf("WARNING: Failed to release collateral %d: %v", collateral[i].ID, err)
+		}
+	}
+}
+
+func toCollateralItem(collateral *db.Collateral) CollateralItem {
+	item := CollateralItem{
+		ID:            collateral.ID,
+		LoanID:        collateral.LoanID,
+		Type:          collateral.Type,
+		Description:   collateral.Description,
+		Valuation:     collateral.Valuation,
+		ValuationDate: time.Unix(collateral.ValuationDate, 0).Format(time.RFC3339),
+		LienStatus:    collateral.LienStatus,
+		RecordHash:    collateral.RecordHash,
+		TransactionID: collateral.TransactionID,
+		Verified:      collateralVerified(collateral),
+	}
+	if collateral.LienReleasedAt != nil {
+		item.LienReleasedAt = time.Unix(*collateral.LienReleasedAt, 0).Format(time.RFC3339)
+	}
+	return item
+}
+
+// loanCollateral lists a loan's collateral with the value still pledged.
+func loanCollateral(loanID uint) ([]CollateralItem, int, error) {
+	collateral, err := collateralRepo.GetByLoan(loanID)
+	if err != nil {
+		return nil, 0, err
+	}
+	items := []CollateralItem{}
+	value := 0
+	for i := range collateral {
+		items = append(items, toCollateralItem(&collateral[i]))
+		if collateral[i].LienStatus == db.LienStatusActive {
+			value += collateral[i].Valuation
+		}
+	}
+	return items, value, nil
+}
+
+func parseValuationDate(value string) (int64, error) {
+	if value == "" {
+		return time.Now().Unix(), nil
+	}
+	t, err := parseReportDate(value, false)
+	if err != nil {
+		return 0, err
+	}
+	if t.After(time.Now()) {
+		return 0, fmt.Errorf("valuation date cannot be in the future")
+	}
+	return t.Unix(), nil
+}
+
+// RegisterCollateral godoc
+// @Summary Register collateral against a loan (manager)
+// @Description Records an asset securing an open loan with its valuation and places a lien on it. A hash of the record is anchored on the blockchain.
+// @Tags collateral
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param id path int true "Loan ID"
+// @Param request body RegisterCollateralRequest true "Collateral (type: land_title, vehicle, fixed_deposit, other)"
+// @Success 200 {object} CollateralItem
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/loans/{id}/collateral [post]
+func RegisterCollateral(c echo.Context) error {
+	user := c.Get("user").(*repos.UserWithSession)
+
+	loanID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid loan ID"})
+	}
+
+	var req RegisterCollateralRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request"})
+	}
+	if !slices.Contains(db.CollateralTypes, req.Type) {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Type must be 'land_title', 'vehicle', 'fixed_deposit' or 'other'"})
+	}
+	if strings.TrimSpace(req.Description) == "" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Description is required"})
+	}
+	if req.Valuation <= 0 {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Valuation must be positive"})
+	}
+	valuationDate, err := parseValuationDate(req.ValuationDate)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	loan, err := loanRepoHandler.GetByID(uint(loanID))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Loan not found"})
+	}
+	if lifecycle.IsTerminal(loan.Status) {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Collateral can only be registered on open loans"})
+	}
+
+	registeredByID := user.ID
+	collateral := &db.Collateral{
+		LoanID:         loan.ID,
+		Type:           req.Type,
+		Description:    strings.TrimSpace(req.Description),
+		Valuation:      req.Valuation,
+		ValuationDate:  valuationDate,
+		LienStatus:     db.LienStatusActive,
+		RegisteredByID: &registeredByID,
+	}
+	if err := collateralRepo.Create(collateral); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to register collateral"})
+	}
+	if err := anchorCollateral(collateral, "registered"); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to anchor collateral record"})
+	}
+
+	return c.JSON(http.StatusOK, toCollateralItem(collateral))
+}
+
+// RevalueCollateral godoc
+// @Summary Revalue collateral (manager)
+// @Description Records a new valuation for collateral still under lien. Records that no longer match their anchored hash are refused. The updated record hash is anchored on the blockchain.
+// @Tags collateral
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param id path int true "Collateral ID"
+// @Param request body RevalueCollateralRequest true "New valuation"
+// @Success 200 {object} CollateralItem
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/collateral/{id}/revalue [post]
+func RevalueCollateral(c echo.Context) error {
+	collateralID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid collateral ID"})
+	}
+
+	var req RevalueCollateralRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request"})
+	}
+	if req.Valuation <= 0 {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Valuation must be positive"})
+	}
+	valuationDate, err := parseValuationDate(req.ValuationDate)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	collateral, err := collateralRepo.GetByID(uint(collateralID))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Collateral not found"})
+	}
+	if collateral.LienStatus != db.LienStatusActive {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Only collateral under lien can be revalued"})
+	}
+	if !collateralVerified(collateral) {
+		return c.JSON(http.StatusConflict, ErrorResponse{Error: "Collateral record does not match its anchored hash"})
+	}
+
+	previous := collateral.Valuation
+	collateral.Valuation = req.Valuation
+	collateral.ValuationDate = valuationDate
+	if err := anchorCollateral(collateral, fmt.Sprintf("revalued from %d", previous)); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to anchor collateral record"})
+	}
+
+	return c.JSON(http.StatusOK, toCollateralItem(collateral))
+}
+
+// GetLoanCollateral godoc
+// @Summary Get loan collateral
+// @Description Returns the collateral registered against a loan, whether each record still matches its anchored hash, and the coverage ratio of pledged value to outstanding balance. Members may only view their own loans.
+// @Tags collateral
+// @Produce json
+// @Security SessionAuth
+// @Param id path int true "Loan ID"
+// @Success 200 {object} LoanCollateralResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/loans/{id}/collateral [get]
+func GetLoanCollateral(c echo.Context) error {
+	user := c.Get("user").(*repos.UserWithSession)
+
+	loanID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid loan ID"})
+	}
+
+	loan, err := loanRepoHandler.GetByID(uint(loanID))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Loan not found"})
+	}
+
+	if user.Role == "member" && loan.BorrowerID != user.ID {
+		return c.JSON(http.StatusForbidden, ErrorResponse{Error: "Not authorized to view this loan"})
+	}
+
+	items, value, err := loanCollateral(loan.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch collateral"})
+	}
+
+	return c.JSON(http.StatusOK, LoanCollateralResponse{
+		LoanID:          loan.ID,
+		Outstanding:     loan.OutstandingBalance,
+		CollateralValue: value,
+		CoverageRatio:   coverageRatio(value, loan.OutstandingBalance),
+		Collateral:      items,
+	})
+}
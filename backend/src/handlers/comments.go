@@ -0,0 +1,296 @@
+package handlers
+
+import (
+	"backend/src/db"
+	"backend/src/repos"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	MaxCommentChars     = 2000
+	commentPreviewChars = 120
+)
+
+var commentRepo = repos.CommentRepo{}
+
+type LoanCommentItem struct {
+	ID          uint         `json:"id" example:"1"`
+	Author      *ManagerInfo `json:"author,omitempty"`
+	AuthorRole  string       `json:"author_role" example:"manager"`
+	Body        string       `json:"body" example:"Can you bring the amount down to 80000?"`
+	Internal    bool         `json:"internal" example:"false"`
+	ContentHash string       `json:"content_hash" example:"9f86d081884c7d65..."`
+	HashValid   bool         `json:"hash_valid" example:"true"`
+	CreatedAt   string       `json:"created_at" example:"2025-01-15T10:30:00Z"`
+}
+
+// CommentDecisionItem is a decision on the loan request that anchored a
+// digest of the comments posted before it.
+type CommentDecisionItem struct {
+	ToStatus      string `json:"to_status" example:"Approved"`
+	TransactionID string `json:"transaction_id" example:"TXN-1234567890"`
+	CommentCount  int    `json:"comment_count" example:"3"`
+	Digest        string `json:"digest" example:"2c26b46b68ffc68f..."`
+	Matches       bool   `json:"matches" example:"true"`
+	CreatedAt     string `json:"created_at" example:"2025-01-16T09:00:00Z"`
+}
+
+type LoanCommentListResponse struct {
+	LoanID    uint                  `json:"loan_id" example:"1"`
+	Comments  []LoanCommentItem     `json:"comments"`
+	Decisions []CommentDecisionItem `json:"decisions,omitempty"`
+}
+
+type CreateLoanCommentRequest struct {
+	Body     string `json:"body" example:"Can you bring the amount down to 80000?"`
+	Internal bool   `json:"internal" example:"false"`
+	Notify   bool   `json:"notify" example:"true"`
+}
+
+type CreateLoanCommentResponse struct {
+	Comment  LoanCommentItem `json:"comment"`
+	Notified int             `json:"notified" example:"1"`
+}
+
+// commentHash fingerprints everything that makes up a comment. CreatedAt is
+// hashed at second precision so the hash survives a round trip through the
+// database.
+func commentHash(comment *db.LoanComment) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d|%d|%t|%d|%s",
+		comment.LoanID, comment.AuthorID, comment.Internal, comment.CreatedAt.Unix(), comment.Body)))
+	return hex.EncodeToString(sum[:])
+}
+
+// commentsDigest combines the hashes of comments, oldest first, into the
+// single value anchored with a decision. Hashes are recomputed rather than
+// read back so an edited comment no longer matches its decision.
+func commentsDigest(comments []db.LoanComment) string {
+	hashes := make([]string, len(comments))
+	for i := range comments {
+		hashes[i] = commentHash(&comments[i])
+	}
+	sum := sha256.Sum256([]byte(strings.Join(hashes, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+func toLoanCommentItem(comment *db.LoanComment) LoanCommentItem {
+	return LoanCommentItem{
+		ID:          comment.ID,
+		Author:      &ManagerInfo{ID: comment.Author.ID, Name: comment.Author.Name},
+		AuthorRole:  comment.Author.Role,
+		Body:        comment.Body,
+		Internal:    comment.Internal,
+		ContentHash: comment.ContentHash,
+		HashValid:   commentHash(comment) == comment.ContentHash,
+		CreatedAt:   comment.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// notifyLoanComment texts everyone on the thread who can see the comment,
+// apart from its author: the borrower for comments that are not internal
+// notes, and staff who have taken part in the discussion. It returns how
+// many notices were queued.
+func notifyLoanComment(loan *db.Loan, comment *db.LoanComment, author *repos.UserWithSession, now time.Time) int {
+	recipients, err := commentRepo.GetAuthors(loan.ID)
+	if err != nil {
+		log.Printf("WARNING: Failed to load participants of loan %d: %v", loan.ID, err)
+		return 0
+	}
+	if !comment.Internal {
+		recipients = append(recipients, loan.Borrower)
+	}
+
+	preview := []rune(comment.Body)
+	if len(preview) > commentPreviewChars {
+		preview = append(preview[:commentPreviewChars-3], []rune("...")...)
+	}
+
+	queued := 0
+	notified := map[uint]bool{author.ID: true}
+	for i := range recipients {
+		recipient := &recipients[i]
+		if notified[recipient.ID] || (comment.Internal && recipient.Role == "member") {
+			continue
+		}
+		notified[recipient.ID] = true
+
+		loanID := loan.ID
+		message, err := queueReminder(db.ReminderLoanComment, fmt.Sprintf("%d:%d", comment.ID, recipient.ID), recipient, &loanID, map[string]string{
+			"loan_id": strconv.FormatUint(uint64(loan.ID), 10),
+			"author":  author.Name,
+			"comment": string(preview),
+		}, now)
+		if err != nil {
+			log.Printf("WARNING: Failed to queue comment notice for loan %d: %v", loan.ID, err)
+			continue
+		}
+		if message == nil {
+			continue
+		}
+		queued++
+		if inQuietHours(now) {
+			continue
+		}
+		if err := deliverSMS(message, now); err != nil {
+			log.Printf("WARNING: Failed to send comment notice for loan %d: %v", loan.ID, err)
+		}
+	}
+	return queued
+}
+
+// GetLoanComments godoc
+// @Summary Get the comment thread of a loan
+// @Description Returns the comments on a loan oldest first, each with its content hash and whether it still matches. Staff also see internal notes and, for every decision on the loan request, the anchored digest of the comments it covered and whether it still matches them. Members may only view the thread of their own loans and never see internal notes.
+// @Tags loans
+// @Produce json
+// @Security SessionAuth
+// @Param id path int true "Loan ID"
+// @Success 200 {object} LoanCommentListResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/loans/{id}/comments [get]
+func GetLoanComments(c echo.Context) error {
+	user := c.Get("user").(*repos.UserWithSession)
+
+	loanID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid loan ID"})
+	}
+
+	loan, err := loanRepoHandler.GetByID(uint(loanID))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Loan not found"})
+	}
+
+	staff := user.Role != "member"
+	if !staff && loan.BorrowerID != user.ID {
+		return c.JSON(http.StatusForbidden, ErrorResponse{Error: "Not authorized to view this loan"})
+	}
+
+	comments, err := commentRepo.GetByLoan(loan.ID, staff)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch comments"})
+	}
+
+	response := LoanCommentListResponse{
+		LoanID:   loan.ID,
+		Comments: []LoanCommentItem{},
+	}
+	for i := range comments {
+		response.Comments = append(response.Comments, toLoanCommentItem(&comments[i]))
+	}
+
+	if staff {
+		history, err := loanRepoHandler.GetStatusHistory(loan.ID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch loan history"})
+		}
+		for _, entry := range history {
+			if entry.CommentCount == nil {
+				continue
+			}
+			count := min(*entry.CommentCount, len(comments))
+			response.Decisions = append(response.Decisions, CommentDecisionItem{
+				ToStatus:      entry.ToStatus,
+				TransactionID: entry.TransactionID,
+				CommentCount:  *entry.CommentCount,
+				Digest:        entry.CommentsDigest,
+				Matches:       count == *entry.CommentCount && commentsDigest(comments[:count]) == entry.CommentsDigest,
+				CreatedAt:     entry.CreatedAt.Format(time.RFC3339),
+			})
+		}
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// CreateLoanComment godoc
+// @Summary Comment on a loan
+// @Description Adds a comment to the thread of a loan. Members may only comment on their own loans; managers may mark a comment as an internal note, which members never see. With notify set, everyone on the thread who can see the comment is sent an SMS using the loan_comment template.
+// @Tags loans
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param id path int true "Loan ID"
+// @Param request body CreateLoanCommentRequest true "Comment"
+// @Success 201 {object} CreateLoanCommentResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/loans/{id}/comments [post]
+func CreateLoanComment(c echo.Context) error {
+	user := c.Get("user").(*repos.UserWithSession)
+
+	loanID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid loan ID"})
+	}
+
+	var req CreateLoanCommentRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request"})
+	}
+
+	body := strings.TrimSpace(req.Body)
+	if body == "" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Comment cannot be empty"})
+	}
+	if len([]rune(body)) > MaxCommentChars {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("Comment cannot be longer than %d characters", MaxCommentChars)})
+	}
+
+	loan, err := loanRepoHandler.GetByID(uint(loanID))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Loan not found"})
+	}
+
+	if user.Role == "member" {
+		if loan.BorrowerID != user.ID {
+			return c.JSON(http.StatusForbidden, ErrorResponse{Error: "Not authorized to comment on this loan"})
+		}
+		if req.Internal {
+			return c.JSON(http.StatusForbidden, ErrorResponse{Error: "Only managers can add internal notes"})
+		}
+	}
+
+	now := time.Now()
+	comment := &db.LoanComment{
+		LoanID:   loan.ID,
+		AuthorID: user.ID,
+		Body:     body,
+		Internal: req.Internal,
+	}
+	comment.CreatedAt = now.Truncate(time.Second)
+	comment.ContentHash = commentHash(comment)
+	if err := commentRepo.Create(comment); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to add comment"})
+	}
+
+	notified := 0
+	if req.Notify {
+		notified = notifyLoanComment(loan, comment, user, now)
+	}
+
+	item := toLoanCommentItem(comment)
+	item.Author = &ManagerInfo{ID: user.ID, Name: user.Name}
+	item.AuthorRole = user.Role
+
+	return c.JSON(http.StatusCreated, CreateLoanCommentResponse{
+		Comment:  item,
+		Notified: notified,
+	})
+}
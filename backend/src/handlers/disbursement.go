@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"backend/src/db"
+	"backend/src/lifecycle"
+	"backend/src/repos"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	PayoutSavings = "savings"
+	PayoutCash    = "cash"
+	PayoutBank    = "bank"
+)
+
+type DisburseLoanRequest struct {
+	Method    string `json:"method" binding:"required" example:"bank"`
+	Reference string `json:"reference" example:"BANK-TX-98765"`
+}
+
+type DisburseLoanResponse struct {
+	OK                      bool   `json:"ok" example:"true"`
+	TransactionID           string `json:"transaction_id" example:"TXN-1234567890"`
+	DisbursedAt             string `json:"disbursed_at" example:"2025-01-20T10:00:00Z"`
+	FirstDueDate            string `json:"first_due_date" example:"2025-02-20T10:00:00Z"`
+	MonthlyPayment          int    `json:"monthly_payment" example:"9333"`
+	PaidOut                 int    `json:"paid_out" example:"58000"`
+	SettledLoanID           *uint  `json:"settled_loan_id,omitempty" example:"3"`
+	SettlementAmount        int    `json:"settlement_amount,omitempty" example:"42000"`
+	SettlementTransactionID string `json:"settlement_transaction_id,omitempty" example:"TXN-1234567889"`
+}
+
+// DisburseLoan godoc
+// @Summary Disburse an approved loan (manager)
+// @Description Pays out an approved loan either into the member's savings or by cash/bank transfer with a reference. Records the disbursement date, restarts the repayment schedule from it and anchors a loan_disbursement transaction. A top-up first settles the loan it refinances at today's payoff amount, anchored as a loan_top_up transaction that marks that loan PaidOff, and pays out only the difference.
+// @Tags loans
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param id path int true "Loan ID"
+// @Param request body DisburseLoanRequest true "Payout details"
+// @Success 200 {object} DisburseLoanResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/loans/{id}/disburse [post]
+func DisburseLoan(c echo.Context) error {
+	user := c.Get("user").(*repos.UserWithSession)
+
+	loanID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid loan ID"})
+	}
+
+	var req DisburseLoanRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request"})
+	}
+
+	switch req.Method {
+	case PayoutSavings:
+	case PayoutCash, PayoutBank:
+		if req.Reference == "" {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "A reference is required for cash or bank payouts"})
+		}
+	default:
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Method must be 'savings', 'cash' or 'bank'"})
+	}
+
+	loan, err := loanRepoHandler.GetByID(uint(loanID))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Loan not found"})
+	}
+
+	if err := lifecycle.CheckPayout(loan); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	now := time.Now()
+	schedule, err := generateLoanSchedule(loan, loan.InterestRate, now)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	// A top-up pays off the loan it refinances first and pays out the rest.
+	payout := loan.Principal
+	var previous *db.Loan
+	var quote *db.PayoffQuote
+	if loan.TopUpOfID != nil {
+		previous, err = loanRepoHandler.GetByID(*loan.TopUpOfID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch the loan being topped up"})
+		}
+		if !lifecycle.IsActive(previous.Status) || previous.OutstandingBalance <= 0 {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error: fmt.Sprintf("Loan #%d is no longer outstanding; cancel this top-up and request a new loan", previous.ID),
+			})
+		}
+		quote, err = payoffQuote(previous, now, now)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to calculate payoff amount"})
+		}
+		if quote.Total >= loan.Principal {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error: fmt.Sprintf("The payoff of loan #%d is now %d, which this top-up no longer covers", previous.ID, quote.Total),
+			})
+		}
+		payout = loan.Principal - quote.Total
+	}
+
+	toAccount := "BANK"
+	description := fmt.Sprintf("Loan #%d disbursed by %s: %s", loan.ID, req.Method, req.Reference)
+	if req.Method == PayoutSavings {
+		toAccount = fmt.Sprintf("USER-%d", loan.BorrowerID)
+		description = fmt.Sprintf("Loan #%d disbursed to savings", loan.ID)
+	}
+
+	actorID := user.ID
+	updates := map[string]interface{}{
+		"disbursed_at":        now.Unix(),
+		"disbursed_by_id":     user.ID,
+		"disbursement_method": req.Method,
+		"disbursement_ref":    req.Reference,
+		"monthly_payment":     schedule[0].Payment,
+	}
+	if previous != nil {
+		description += fmt.Sprintf(", net of %d settling loan #%d", quote.Total, previous.ID)
+		updates["top_up_amount"] = quote.Total
+	}
+
+	transaction := &db.Transaction{
+		TransactionID: transactionGenerator(),
+		Type:          "loan_disbursement",
+		FromAccount:   fmt.Sprintf("LOAN-%d", loan.ID),
+		ToAccount:     toAccount,
+		Amount:        payout,
+		Status:        "completed",
+		Description:   description,
+	}
+
+	transactionID, err := changeLoanStatus(loan, lifecycle.StatusDisbursed, &actorID, description, transaction, updates)
+	if err != nil {
+		return statusChangeError(c, err)
+	}
+
+	if err := saveLoanSchedule(loan.ID, schedule); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to save repayment schedule"})
+	}
+
+	if req.Method == PayoutSavings {
+		if err := depositRepoHandler.UpdateUserBalance(loan.BorrowerID, payout); err != nil {
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update user balance"})
+		}
+	}
+
+	// The earlier loan is only closed once the top-up is disbursed, so a
+	// refused disbursement leaves it untouched.
+	settlementID := ""
+	if previous != nil {
+		settlementID, err = settleTopUp(previous, loan, quote, actorID, now)
+		if err != nil {
+			log.Printf("ERROR: Loan %d was disbursed but settling loan %d failed: %v", loan.ID, previous.ID, err)
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{
+				Error: fmt.Sprintf("Loan #%d was disbursed but settling loan #%d failed", loan.ID, previous.ID),
+			})
+		}
+	}
+
+	response := DisburseLoanResponse{
+		OK:             true,
+		TransactionID:  transactionID,
+		DisbursedAt:    now.Format(time.RFC3339),
+		FirstDueDate:   schedule[0].DueDate.Format(time.RFC3339),
+		MonthlyPayment: schedule[0].Payment,
+		PaidOut:        payout,
+	}
+	if previous != nil {
+		response.SettledLoanID = &previous.ID
+		response.SettlementAmount = quote.Total
+		response.SettlementTransactionID = settlementID
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,431 @@
+package handlers
+
+import (
+	"backend/src/db"
+	"backend/src/repos"
+	"backend/src/storage"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	MaxDocumentFileBytes = 10 << 20
+	DefaultDocumentDir   = "uploads"
+)
+
+var (
+	documentRepo    = repos.DocumentRepo{}
+	documentStorage storage.Storage
+)
+
+// documentContentTypes are the file types accepted for upload, detected
+// from the file contents rather than trusted from the client.
+var documentContentTypes = []string{"application/pdf", "image/jpeg", "image/png"}
+
+// InitDocumentStorage opens the local document store at DOCUMENT_STORAGE_DIR.
+func InitDocumentStorage() error {
+	dir := os.Getenv("DOCUMENT_STORAGE_DIR")
+	if dir == "" {
+		dir = DefaultDocumentDir
+	}
+	local, err := storage.NewLocal(dir)
+	if err != nil {
+		return err
+	}
+	documentStorage = local
+	return nil
+}
+
+type DocumentItem struct {
+	ID            uint   `json:"id" example:"1"`
+	LoanID        *uint  `json:"loan_id,omitempty" example:"1"`
+	MemberID      *uint  `json:"member_id,omitempty" example:"2"`
+	Category      string `json:"category" example:"agreement"`
+	FileName      string `json:"file_name" example:"loan-agreement.pdf"`
+	ContentType   string `json:"content_type" example:"application/pdf"`
+	Size          int64  `json:"size" example:"184233"`
+	SHA256        string `json:"sha256" example:"9f86d081884c7d65..."`
+	Description   string `json:"description,omitempty" example:"Signed agreement"`
+	UploadedByID  uint   `json:"uploaded_by_id" example:"3"`
+	UploadedBy    string `json:"uploaded_by" example:"Jane Manager"`
+	UploadedAt    string `json:"uploaded_at" example:"2025-01-15T10:30:00Z"`
+	TransactionID string `json:"transaction_id" example:"TXN-1234567890"`
+}
+
+type DocumentListResponse struct {
+	Documents []DocumentItem `json:"documents"`
+}
+
+type DocumentVerificationResponse struct {
+	DocumentID    uint   `json:"document_id" example:"1"`
+	SHA256        string `json:"sha256" example:"9f86d081884c7d65..."`
+	FileHash      string `json:"file_hash" example:"9f86d081884c7d65..."`
+	FileMatches   bool   `json:"file_matches" example:"true"`
+	AnchorMatches bool   `json:"anchor_matches" example:"true"`
+	BlockNumber   uint   `json:"block_number,omitempty" example:"42"`
+	BlockValid    bool   `json:"block_valid" example:"true"`
+	Verified      bool   `json:"verified" example:"true"`
+}
+
+func toDocumentItem(document *db.Document) DocumentItem {
+	return DocumentItem{
+		ID:            document.ID,
+		LoanID:        document.LoanID,
+		MemberID:      document.MemberID,
+		Category:      document.Category,
+		FileName:      document.FileName,
+		ContentType:   document.ContentType,
+		Size:          document.Size,
+		SHA256:        document.SHA256,
+		Description:   document.Description,
+		UploadedByID:  document.UploadedByID,
+		UploadedBy:    document.UploadedBy.Name,
+		UploadedAt:    document.CreatedAt.Format(time.RFC3339),
+		TransactionID: document.TransactionID,
+	}
+}
+
+// documentOwner is the member a document belongs to: the member it is
+// attached to, or the borrower of its loan.
+func documentOwner(document *db.Document) uint {
+	if document.MemberID != nil {
+		return *document.MemberID
+	}
+	if document.Loan != nil {
+		return document.Loan.BorrowerID
+	}
+	return 0
+}
+
+func documentAccount(document *db.Document) string {
+	if document.LoanID != nil {
+		return fmt.Sprintf("LOAN-%d", *document.LoanID)
+	}
+	return fmt.Sprintf("USER-%d", *document.MemberID)
+}
+
+func newDocumentKey(document *db.Document) (string, error) {
+	suffix := make([]byte, 16)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	if document.LoanID != nil {
+		return fmt.Sprintf("loans/%d/%s", *document.LoanID, hex.EncodeToString(suffix)), nil
+	}
+	return fmt.Sprintf("members/%d/%s", *document.MemberID, hex.EncodeToString(suffix)), nil
+}
+
+// readDocument loads a stored file and returns its contents with their hash.
+func readDocument(document *db.Document) ([]byte, string, error) {
+	file, err := documentStorage.Open(document.StorageKey)
+	if err != nil {
+		return nil, "", err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, "", err
+	}
+	sum := sha256.Sum256(data)
+	return data, hex.EncodeToString(sum[:]), nil
+}
+
+func parseDocumentID(c echo.Context) (uint, error) {
+	documentID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	return uint(documentID), err
+}
+
+// UploadDocument godoc
+// @Summary Upload a document
+// @Description Attaches a PDF, JPEG or PNG file to a loan or a member. The file's SHA-256 hash is anchored on the blockchain so a replaced file is detectable. Members may only attach documents to themselves or their own loans.
+// @Tags documents
+// @Accept multipart/form-data
+// @Produce json
+// @Security SessionAuth
+// @Param file formData file true "Document file (pdf, jpeg, png; max 10MB)"
+// @Param loan_id formData int false "Loan the document belongs to"
+// @Param member_id formData int false "Member the document belongs to"
+// @Param category formData string true "Category (agreement, id_copy, collateral, other)"
+// @Param description formData string false "Description"
+// @Success 200 {object} DocumentItem
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/documents [post]
+func UploadDocument(c echo.Context) error {
+	user := c.Get("user").(*repos.UserWithSession)
+
+	category := c.FormValue("category")
+	if !slices.Contains(db.DocumentCategories, category) {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Category must be 'agreement', 'id_copy', 'collateral' or 'other'"})
+	}
+
+	rawLoanID, rawMemberID := c.FormValue("loan_id"), c.FormValue("member_id")
+	if (rawLoanID == "") == (rawMemberID == "") {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Exactly one of loan_id or member_id is required"})
+	}
+
+	document := &db.Document{
+		Category:     category,
+		Description:  strings.TrimSpace(c.FormValue("description")),
+		UploadedByID: user.ID,
+	}
+	if rawLoanID != "" {
+		loanID, err := strconv.ParseUint(rawLoanID, 10, 32)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid loan ID"})
+		}
+		loan, err := loanRepoHandler.GetByID(uint(loanID))
+		if err != nil {
+			return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Loan not found"})
+		}
+		if user.Role == "member" && loan.BorrowerID != user.ID {
+			return c.JSON(http.StatusForbidden, ErrorResponse{Error: "Not authorized to attach documents to this loan"})
+		}
+		id := loan.ID
+		document.LoanID = &id
+	} else {
+		memberID, err := strconv.ParseUint(rawMemberID, 10, 32)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid member ID"})
+		}
+		if user.Role == "member" && uint(memberID) != user.ID {
+			return c.JSON(http.StatusForbidden, ErrorResponse{Error: "Not authorized to attach documents to this member"})
+		}
+		member, err := userRepoHandler.GetByID(uint(memberID))
+		if err != nil || member.Role != "member" {
+			return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Member not found"})
+		}
+		id := member.ID
+		document.MemberID = &id
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Document file is required"})
+	}
+	if fileHeader.Size > MaxDocumentFileBytes {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Document file is too large"})
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Failed to read document file"})
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, MaxDocumentFileBytes))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Failed to read document file"})
+	}
+	if len(data) == 0 {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Document file is empty"})
+	}
+
+	contentType := strings.Split(http.DetectContentType(data), ";")[0]
+	if !slices.Contains(documentContentTypes, contentType) {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Document must be a PDF, JPEG or PNG file"})
+	}
+
+	sum := sha256.Sum256(data)
+	document.SHA256 = hex.EncodeToString(sum[:])
+	document.FileName = fileHeader.Filename
+	document.ContentType = contentType
+	document.Size = int64(len(data))
+
+	document.StorageKey, err = newDocumentKey(document)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to store document"})
+	}
+	if err := documentStorage.Put(document.StorageKey, bytes.NewReader(data)); err != nil {
+		log.Printf("ERROR: Failed to store document %s: %v", document.StorageKey, err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to store document"})
+	}
+	if err := documentRepo.Create(document); err != nil {
+		if err := documentStorage.Delete(document.StorageKey); err != nil {
+			log.Printf("WARNING: Failed to remove orphaned document %s: %v", document.StorageKey, err)
+		}
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to save document"})
+	}
+
+	transaction := &db.Transaction{
+		TransactionID: transactionGenerator(),
+		Type:          "document_upload",
+		FromAccount:   fmt.Sprintf("USER-%d", user.ID),
+		ToAccount:     documentAccount(document),
+		Amount:        0,
+		Status:        "completed",
+		Description: fmt.Sprintf("Document #%d (%s) %s uploaded by %s %d; %d bytes; sha256 %s",
+			document.ID, document.Category, document.FileName, user.Role, user.ID, document.Size, document.SHA256),
+	}
+	if err := anchorTransaction(transaction); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to record document upload"})
+	}
+	if err := documentRepo.UpdateTransaction(document.ID, transaction.TransactionID); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to link document upload"})
+	}
+	document.TransactionID = transaction.TransactionID
+	document.UploadedBy.Name = user.Name
+
+	return c.JSON(http.StatusOK, toDocumentItem(document))
+}
+
+// ListDocuments godoc
+// @Summary List documents
+// @Description Lists documents attached to loans and members, newest first. Members only see documents on themselves and their own loans.
+// @Tags documents
+// @Produce json
+// @Security SessionAuth
+// @Param loan_id query int false "Filter by loan"
+// @Param member_id query int false "Filter by member"
+// @Success 200 {object} DocumentListResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/documents [get]
+func ListDocuments(c echo.Context) error {
+	user := c.Get("user").(*repos.UserWithSession)
+
+	var filter repos.DocumentFilter
+	if raw := c.QueryParam("loan_id"); raw != "" {
+		loanID, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid loan ID"})
+		}
+		id := uint(loanID)
+		filter.LoanID = &id
+	}
+	if raw := c.QueryParam("member_id"); raw != "" {
+		memberID, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid member ID"})
+		}
+		id := uint(memberID)
+		filter.MemberID = &id
+	}
+	if user.Role == "member" {
+		filter.OwnerID = &user.ID
+	}
+
+	documents, err := documentRepo.GetAll(filter)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch documents"})
+	}
+
+	response := DocumentListResponse{Documents: []DocumentItem{}}
+	for i := range documents {
+		response.Documents = append(response.Documents, toDocumentItem(&documents[i]))
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// DownloadDocument godoc
+// @Summary Download a document
+// @Description Returns the stored file. Members may only download documents on themselves or their own loans; managers and auditors may download any. Files that no longer match their recorded hash are refused.
+// @Tags documents
+// @Produce application/octet-stream
+// @Security SessionAuth
+// @Param id path int true "Document ID"
+// @Success 200 {file} file
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/documents/{id} [get]
+func DownloadDocument(c echo.Context) error {
+	user := c.Get("user").(*repos.UserWithSession)
+
+	documentID, err := parseDocumentID(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid document ID"})
+	}
+	document, err := documentRepo.GetByID(documentID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Document not found"})
+	}
+	if user.Role == "member" && documentOwner(document) != user.ID {
+		return c.JSON(http.StatusForbidden, ErrorResponse{Error: "Not authorized to view this document"})
+	}
+
+	data, fileHash, err := readDocument(document)
+	if errors.Is(err, storage.ErrNotFound) {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Document file is missing"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to read document"})
+	}
+	if fileHash != document.SHA256 {
+		log.Printf("WARNING: Document %d does not match its recorded hash", document.ID)
+		return c.JSON(http.StatusConflict, ErrorResponse{Error: "Document does not match its recorded hash"})
+	}
+
+	c.Response().Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", document.FileName))
+	return c.Blob(http.StatusOK, document.ContentType, data)
+}
+
+// VerifyDocument godoc
+// @Summary Verify a document against the blockchain
+// @Description Recomputes the stored file's SHA-256 hash and checks it against the recorded hash, the anchoring transaction and its block
+// @Tags documents
+// @Produce json
+// @Security SessionAuth
+// @Param id path int true "Document ID"
+// @Success 200 {object} DocumentVerificationResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/documents/{id}/verify [get]
+func VerifyDocument(c echo.Context) error {
+	documentID, err := parseDocumentID(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid document ID"})
+	}
+	document, err := documentRepo.GetByID(documentID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Document not found"})
+	}
+
+	response := DocumentVerificationResponse{
+		DocumentID: document.ID,
+		SHA256:     document.SHA256,
+	}
+
+	_, fileHash, err := readDocument(document)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to read document"})
+	}
+	response.FileHash = fileHash
+	response.FileMatches = fileHash == document.SHA256
+
+	if transaction, err := documentRepo.GetTransaction(document.TransactionID); err == nil {
+		response.AnchorMatches = strings.Contains(transaction.Description, document.SHA256)
+	}
+	if block, err := db.GetBlockByTransaction(document.TransactionID); err == nil {
+		response.BlockNumber = block.BlockNumber
+		response.BlockValid, _ = db.VerifyBlock(block.BlockNumber)
+	}
+	response.Verified = response.FileMatches && response.AnchorMatches && response.BlockValid
+
+	return c.JSON(http.StatusOK, response)
+}
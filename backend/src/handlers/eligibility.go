@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"backend/src/db"
+	"backend/src/eligibility"
+	"backend/src/lifecycle"
+	"backend/src/repos"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+var eligibilityRepo = repos.EligibilityRepo{}
+
+type EligibilityRuleItem struct {
+	Code     string  `json:"code" example:"max_savings_multiple"`
+	Name     string  `json:"name" example:"Maximum loan as a multiple of savings plus shares"`
+	Value    float64 `json:"value" example:"3"`
+	IsActive bool    `json:"is_active" example:"true"`
+}
+
+type EligibilityRuleListResponse struct {
+	Rules []EligibilityRuleItem `json:"rules"`
+}
+
+type UpdateEligibilityRuleRequest struct {
+	Value    *float64 `json:"value" example:"4"`
+	IsActive *bool    `json:"is_active" example:"true"`
+}
+
+type EligibilityReasonItem struct {
+	Rule    string `json:"rule" example:"max_concurrent_loans"`
+	Message string `json:"message" example:"You already have 1 open loan(s); the limit is 1"`
+}
+
+type LoanRefusedResponse struct {
+	Error   string                  `json:"error" example:"Loan request refused"`
+	Reasons []EligibilityReasonItem `json:"reasons"`
+}
+
+type LoanEligibilityResponse struct {
+	Eligible         bool                    `json:"eligible" example:"false"`
+	MaxAmount        *int                    `json:"max_amount,omitempty" example:"150000"`
+	AllowedDurations []int                   `json:"allowed_durations" example:"6,12,24"`
+	OpenLoans        int                     `json:"open_loans" example:"1"`
+	Arrears          int                     `json:"arrears" example:"0"`
+	Reasons          []EligibilityReasonItem `json:"reasons"`
+}
+
+func toEligibilityRuleItem(rule *db.EligibilityRule) EligibilityRuleItem {
+	return EligibilityRuleItem{
+		Code:     rule.Code,
+		Name:     rule.Name,
+		Value:    rule.Value,
+		IsActive: rule.IsActive,
+	}
+}
+
+func toEligibilityReasonItems(reasons []eligibility.Reason) []EligibilityReasonItem {
+	items := []EligibilityReasonItem{}
+	for _, reason := range reasons {
+		items = append(items, EligibilityReasonItem{Rule: reason.Rule, Message: reason.Message})
+	}
+	return items
+}
+
+// loanApplicant gathers what the eligibility rules look at for a member:
+// balances, join date, open loans and the arrears on those being repaid.
+func loanApplicant(userID uint, now time.Time) (eligibility.Applicant, error) {
+	var applicant eligibility.Applicant
+
+	user, err := userRepoHandler.GetByID(userID)
+	if err != nil {
+		return applicant, err
+	}
+	applicant.Savings = user.SavingsBalance
+	applicant.Shares = user.SharesBalance
+	applicant.MemberSince = user.CreatedAt
+
+	loans, err := loanRepoHandler.GetOpenByBorrower(userID)
+	if err != nil {
+		return applicant, err
+	}
+	applicant.OpenLoans = len(loans)
+	for i := range loans {
+		if lifecycle.IsActive(loans[i].Status) {
+			_, _, arrears := loanArrears(&loans[i], now)
+			applicant.Arrears += arrears
+		}
+	}
+
+	rates, err := interestRateRepo.GetCurrent(now.Unix())
+	if err != nil {
+		return applicant, err
+	}
+	applicant.Durations = []int{}
+	for _, rate := range rates {
+		applicant.Durations = append(applicant.Durations, rate.DurationMonths)
+	}
+
+	return applicant, nil
+}
+
+// ListEligibilityRules godoc
+// @Summary List loan eligibility rules
+// @Description Returns every eligibility rule checked when a member requests a loan, with its limit and whether it is enforced
+// @Tags loans
+// @Produce json
+// @Security SessionAuth
+// @Success 200 {object} EligibilityRuleListResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/loans/rules [get]
+func ListEligibilityRules(c echo.Context) error {
+	rules, err := eligibilityRepo.GetRules()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch eligibility rules"})
+	}
+
+	items := []EligibilityRuleItem{}
+	for i := range rules {
+		items = append(items, toEligibilityRuleItem(&rules[i]))
+	}
+
+	return c.JSON(http.StatusOK, EligibilityRuleListResponse{Rules: items})
+}
+
+// UpdateEligibilityRule godoc
+// @Summary Configure a loan eligibility rule (manager)
+// @Description Updates the limit or active flag of an eligibility rule. Omitted fields are left unchanged. The value is a multiple for max_savings_multiple, a count for max_concurrent_loans and days for min_membership_days; it is ignored by no_arrears and allowed_durations.
+// @Tags loans
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param code path string true "Rule code"
+// @Param request body UpdateEligibilityRuleRequest true "Rule settings"
+// @Success 200 {object} EligibilityRuleItem
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/loans/rules/{code} [post]
+func UpdateEligibilityRule(c echo.Context) error {
+	var req UpdateEligibilityRuleRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request"})
+	}
+
+	rule, err := eligibilityRepo.GetRuleByCode(c.Param("code"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Eligibility rule not found"})
+	}
+
+	if req.Value != nil {
+		if *req.Value < 0 {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Value cannot be negative"})
+		}
+		rule.Value = *req.Value
+	}
+	if req.IsActive != nil {
+		rule.IsActive = *req.IsActive
+	}
+
+	if err := eligibilityRepo.SaveRule(rule); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update eligibility rule"})
+	}
+
+	return c.JSON(http.StatusOK, toEligibilityRuleItem(rule))
+}
+
+// CheckLoanEligibility godoc
+// @Summary Check loan eligibility (member)
+// @Description Returns how much the member may borrow, the durations on offer and, when amount and duration are given, the reasons such a request would be refused
+// @Tags loans
+// @Produce json
+// @Security SessionAuth
+// @Param amount query int false "Amount to borrow"
+// @Param duration query int false "Duration in months"
+// @Success 200 {object} LoanEligibilityResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/loans/eligibility [get]
+func CheckLoanEligibility(c echo.Context) error {
+	user := c.Get("user").(*repos.UserWithSession)
+
+	amount, duration := 0, 0
+	if value := c.QueryParam("amount"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid amount"})
+		}
+		amount = parsed
+	}
+	if value := c.QueryParam("duration"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid duration"})
+		}
+		duration = parsed
+	}
+
+	rules, err := eligibilityRepo.GetRules()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch eligibility rules"})
+	}
+
+	now := time.Now()
+	applicant, err := loanApplicant(user.ID, now)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to check eligibility"})
+	}
+
+	// Without a specific request, only the member-level rules apply.
+	if amount == 0 {
+		if limit, ok := eligibility.MaxAmount(rules, applicant); ok {
+			amount = limit
+		}
+	}
+	if duration == 0 && len(applicant.Durations) > 0 {
+		duration = applicant.Durations[0]
+	}
+
+	reasons := eligibility.Evaluate(rules, applicant, amount, duration, now)
+	response := LoanEligibilityResponse{
+		Eligible:         len(reasons) == 0,
+		AllowedDurations: applicant.Durations,
+		OpenLoans:        applicant.OpenLoans,
+		Arrears:          applicant.Arrears,
+		Reasons:          toEligibilityReasonItems(reasons),
+	}
+	if limit, ok := eligibility.MaxAmount(rules, applicant); ok {
+		response.MaxAmount = &limit
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
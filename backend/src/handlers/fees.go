@@ -0,0 +1,702 @@
+package handlers
+
+import (
+	"backend/src/db"
+	"backend/src/repos"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+var feeRepo = repos.FeeRepo{}
+
+type FeeTypeItem struct {
+	ID          uint    `json:"id" example:"1"`
+	Code        string  `json:"code" example:"loan_processing"`
+	Name        string  `json:"name" example:"Loan processing fee"`
+	TriggerRule string  `json:"trigger_rule" example:"on_approval"`
+	Rate        float64 `json:"rate" example:"1.5"`
+	Amount      int     `json:"amount" example:"0"`
+	Cap         int     `json:"cap" example:"5000"`
+	IsActive    bool    `json:"is_active" example:"true"`
+}
+
+type FeeTypeListResponse struct {
+	FeeTypes []FeeTypeItem `json:"fee_types"`
+}
+
+type UpdateFeeTypeRequest struct {
+	Name        *string  `json:"name" example:"Loan processing fee"`
+	TriggerRule *string  `json:"trigger_rule" example:"on_approval"`
+	Rate        *float64 `json:"rate" example:"1.5"`
+	Amount      *int     `json:"amount" example:"0"`
+	Cap         *int     `json:"cap" example:"5000"`
+	IsActive    *bool    `json:"is_active" example:"true"`
+}
+
+type FeeChargeItem struct {
+	ID                  uint         `json:"id" example:"1"`
+	FeeCode             string       `json:"fee_code" example:"late_payment"`
+	FeeName             string       `json:"fee_name" example:"Late payment penalty"`
+	Member              BorrowerInfo `json:"member"`
+	LoanID              *uint        `json:"loan_id,omitempty" example:"3"`
+	BaseAmount          int          `json:"base_amount" example:"9000"`
+	Amount              int          `json:"amount" example:"450"`
+	PaidAmount          int          `json:"paid_amount" example:"0"`
+	WaivedAmount        int          `json:"waived_amount" example:"450"`
+	Status              string       `json:"status" example:"charged"`
+	TransactionID       string       `json:"transaction_id" example:"TXN-1234567890"`
+	WaivedBy            *ManagerInfo `json:"waived_by,omitempty"`
+	WaivedAt            string       `json:"waived_at,omitempty" example:"2025-12-03T10:00:00Z"`
+	WaiverReason        string       `json:"waiver_reason,omitempty" example:"Bank outage delayed the payment"`
+	WaiverTransactionID string       `json:"waiver_transaction_id,omitempty" example:"TXN-1234567891"`
+	CreatedAt           string       `json:"created_at" example:"2025-12-01T00:00:00Z"`
+}
+
+type FeeChargeListResponse struct {
+	Charges      []FeeChargeItem `json:"charges"`
+	TotalCharged int             `json:"total_charged" example:"1200"`
+	TotalWaived  int             `json:"total_waived" example:"450"`
+}
+
+type WaiveFeeRequest struct {
+	Reason string `json:"reason" binding:"required" example:"Bank outage delayed the payment"`
+}
+
+type WaiveFeeResponse struct {
+	OK            bool   `json:"ok" example:"true"`
+	TransactionID string `json:"transaction_id" example:"TXN-1234567891"`
+}
+
+type RunFeesResponse struct {
+	OK             bool `json:"ok" example:"true"`
+	ChargesCreated int  `json:"charges_created" example:"12"`
+	TotalAmount    int  `json:"total_amount" example:"6000"`
+}
+
+func toFeeTypeItem(feeType *db.FeeType) FeeTypeItem {
+	return FeeTypeItem{
+		ID:          feeType.ID,
+		Code:        feeType.Code,
+		Name:        feeType.Name,
+		TriggerRule: feeType.TriggerRule,
+		Rate:        feeType.Rate,
+		Amount:      feeType.Amount,
+		Cap:         feeType.Cap,
+		IsActive:    feeType.IsActive,
+	}
+}
+
+func toFeeChargeItem(charge *db.FeeCharge) FeeChargeItem {
+	item := FeeChargeItem{
+		ID:      charge.ID,
+		FeeCode: charge.FeeType.Code,
+		FeeName: charge.FeeType.Name,
+		Member: BorrowerInfo{
+			ID:          charge.User.ID,
+			Name:        charge.User.Name,
+			PhoneNumber: charge.User.PhoneNumber,
+		},
+		LoanID:              charge.LoanID,
+		BaseAmount:          charge.BaseAmount,
+		Amount:              charge.Amount,
+		PaidAmount:          charge.PaidAmount,
+		WaivedAmount:        charge.WaivedAmount,
+		Status:              charge.Status,
+		TransactionID:       charge.TransactionID,
+		WaiverReason:        charge.WaiverReason,
+		WaiverTransactionID: charge.WaiverTransactionID,
+		CreatedAt:           charge.CreatedAt.Format(time.RFC3339),
+	}
+	if charge.WaivedBy != nil {
+		item.WaivedBy = &ManagerInfo{
+			ID:   charge.WaivedBy.ID,
+			Name: charge.WaivedBy.Name,
+		}
+	}
+	if charge.WaivedAt != nil {
+		item.WaivedAt = time.Unix(*charge.WaivedAt, 0).Format(time.RFC3339)
+	}
+	return item
+}
+
+func isValidFeeTrigger(trigger string) bool {
+	for _, t := range db.FeeTriggers {
+		if t == trigger {
+			return true
+		}
+	}
+	return false
+}
+
+// chargeFee posts a fee. Fees on a loan are added to what the loan owes and
+// collected by repayments; other fees are taken from the member's available
+// savings, and whatever savings cannot cover stays outstanding until
+// collectSavingsCharges finds the funds. The charge key makes each charge
+// idempotent, so re-running a trigger never bills twice. A nil charge with no
+// error means nothing was owed.
+func chargeFee(feeType *db.FeeType, userID uint, loanID *uint, chargeKey string, base int, description string) (*db.FeeCharge, error) {
+	amount := feeType.Compute(base)
+	if amount <= 0 {
+		return nil, nil
+	}
+
+	exists, err := feeRepo.ChargeExists(chargeKey)
+	if err != nil || exists {
+		return nil, err
+	}
+
+	status := db.FeeStatusCharged
+	account := fmt.Sprintf("USER-%d", userID)
+	collected, paid := amount, 0
+	if loanID != nil {
+		status = db.FeeStatusOutstanding
+		account = fmt.Sprintf("LOAN-%d", *loanID)
+	} else {
+		available, err := availableSavings(userID)
+		if err != nil {
+			return nil, err
+		}
+		if available < amount {
+			status = db.FeeStatusOutstanding
+			collected = max(available, 0)
+			paid = collected
+		}
+	}
+
+	transactionID := ""
+	if collected > 0 {
+		transactionID = transactionGenerator()
+	}
+	charge := &db.FeeCharge{
+		FeeTypeID:     feeType.ID,
+		UserID:        userID,
+		LoanID:        loanID,
+		ChargeKey:     chargeKey,
+		BaseAmount:    base,
+		Amount:        amount,
+		PaidAmount:    paid,
+		Status:        status,
+		TransactionID: transactionID,
+	}
+	if err := feeRepo.CreateCharge(charge); err != nil {
+		return nil, err
+	}
+	if collected == 0 {
+		return charge, nil
+	}
+
+	transaction := &db.Transaction{
+		TransactionID: transactionID,
+		Type:          "fee_charge",
+		FromAccount:   account,
+		ToAccount:     "FEES",
+		Amount:        collected,
+		Status:        "completed",
+		Description:   description,
+	}
+	if err := anchorTransaction(transaction); err != nil {
+		db.DB.Unscoped().Delete(charge)
+		return nil, err
+	}
+
+	if loanID == nil {
+		if err := depositRepoHandler.UpdateUserBalance(userID, -collected); err != nil {
+			return nil, err
+		}
+	}
+
+	return charge, nil
+}
+
+// collectSavingsCharges takes the member fees that savings could not cover
+// when they were charged out of whatever savings are now available, oldest
+// first.
+func collectSavingsCharges(userID uint) error {
+	charges, err := feeRepo.GetOutstandingSavingsCharges(userID)
+	if err != nil || len(charges) == 0 {
+		return err
+	}
+
+	available, err := availableSavings(userID)
+	if err != nil {
+		return err
+	}
+
+	for i := range charges {
+		if available <= 0 {
+			break
+		}
+		charge := &charges[i]
+		amount := min(charge.Amount-charge.PaidAmount, available)
+
+		transaction := &db.Transaction{
+			TransactionID: transactionGenerator(),
+			Type:          "fee_charge",
+			FromAccount:   fmt.Sprintf("USER-%d", userID),
+			ToAccount:     "FEES",
+			Amount:        amount,
+			Status:        "completed",
+			Description:   fmt.Sprintf("%s collected from savings (charge #%d)", charge.FeeType.Name, charge.ID),
+		}
+		if err := anchorTransaction(transaction); err != nil {
+			return err
+		}
+		if err := depositRepoHandler.UpdateUserBalance(userID, -amount); err != nil {
+			return err
+		}
+
+		charge.PaidAmount += amount
+		if charge.PaidAmount >= charge.Amount {
+			charge.Status = db.FeeStatusCharged
+		}
+		if err := feeRepo.SaveChargePayment(charge); err != nil {
+			return err
+		}
+		available -= amount
+	}
+	return nil
+}
+
+// collectAllSavingsCharges retries every member fee still outstanding.
+func collectAllSavingsCharges() error {
+	userIDs, err := feeRepo.GetUsersWithOutstandingSavingsCharges()
+	if err != nil {
+		return err
+	}
+	for _, userID := range userIDs {
+		if err := collectSavingsCharges(userID); err != nil {
+			log.Printf("WARNING: Failed to collect outstanding fees for user %d: %v", userID, err)
+		}
+	}
+	return nil
+}
+
+// approvalFeeTypes returns the active fees charged when the loan is approved:
+// those of its product, or the general approval fees for loans without one.
+func approvalFeeTypes(loan *db.Loan) ([]db.FeeType, error) {
+	if loan.ProductID == nil {
+		return feeRepo.GetActiveTypesByTrigger(db.FeeTriggerOnApproval)
+	}
+	product, err := productRepo.GetByID(*loan.ProductID)
+	if err != nil {
+		return nil, err
+	}
+	var feeTypes []db.FeeType
+	for _, feeType := range product.Fees {
+		if feeType.IsActive && feeType.TriggerRule == db.FeeTriggerOnApproval {
+			feeTypes = append(feeTypes, feeType)
+		}
+	}
+	return feeTypes, nil
+}
+
+// chargeApprovalFees bills the loan's active on-approval fees against its
+// amount. Failures are logged so they never block the approval itself.
+func chargeApprovalFees(loan *db.Loan) {
+	feeTypes, err := approvalFeeTypes(loan)
+	if err != nil {
+		log.Printf("WARNING: Failed to load approval fees: %v", err)
+		return
+	}
+
+	for i := range feeTypes {
+		feeType := &feeTypes[i]
+		loanID := loan.ID
+		key := fmt.Sprintf("%s:LOAN-%d", feeType.Code, loan.ID)
+		description := fmt.Sprintf("%s for loan #%d", feeType.Name, loan.ID)
+		if _, err := chargeFee(feeType, loan.BorrowerID, &loanID, key, loan.Amount, description); err != nil {
+			log.Printf("WARNING: Failed to charge %s for loan %d: %v", feeType.Code, loan.ID, err)
+		}
+	}
+}
+
+// chargeStatementFees bills the member for a statement download at most
+// once per calendar month, so refreshing or retrying a download is free.
+func chargeStatementFees(userID uint, now time.Time) {
+	feeTypes, err := feeRepo.GetActiveTypesByTrigger(db.FeeTriggerOnStatement)
+	if err != nil {
+		log.Printf("WARNING: Failed to load statement fees: %v", err)
+		return
+	}
+
+	month := now.Format("2006-01")
+	for i := range feeTypes {
+		feeType := &feeTypes[i]
+		key := fmt.Sprintf("%s:USER-%d:%s", feeType.Code, userID, month)
+		description := fmt.Sprintf("%s for %s", feeType.Name, month)
+		if _, err := chargeFee(feeType, userID, nil, key, 0, description); err != nil {
+			log.Printf("WARNING: Failed to charge %s for user %d: %v", feeType.Code, userID, err)
+		}
+	}
+}
+
+// loanStartedAt is the date repayments are counted from.
+func loanStartedAt(loan *db.Loan) time.Time {
+	if loan.DisbursedAt != nil {
+		return time.Unix(*loan.DisbursedAt, 0)
+	}
+	if loan.ApprovedAt != nil {
+		return time.Unix(*loan.ApprovedAt, 0)
+	}
+	return loan.CreatedAt
+}
+
+func assessMonthlyFees(now time.Time) (int, int, error) {
+	feeTypes, err := feeRepo.GetActiveTypesByTrigger(db.FeeTriggerMonthly)
+	if err != nil || len(feeTypes) == 0 {
+		return 0, 0, err
+	}
+
+	members, err := feeRepo.GetActiveMembers()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	count, total := 0, 0
+	month := now.Format("2006-01")
+	for i := range feeTypes {
+		feeType := &feeTypes[i]
+		for _, member := range members {
+			key := fmt.Sprintf("%s:USER-%d:%s", feeType.Code, member.ID, month)
+			description := fmt.Sprintf("%s for %s", feeType.Name, month)
+			charge, err := chargeFee(feeType, member.ID, nil, key, member.SavingsBalance, description)
+			if err != nil {
+				log.Printf("WARNING: Failed to charge %s for user %d: %v", feeType.Code, member.ID, err)
+				continue
+			}
+			if charge != nil {
+				count++
+				total += charge.Amount
+			}
+		}
+	}
+	return count, total, nil
+}
+
+func assessOverdueFees(now time.Time) (int, int, error) {
+	feeTypes, err := feeRepo.GetActiveTypesByTrigger(db.FeeTriggerOnOverdue)
+	if err != nil || len(feeTypes) == 0 {
+		return 0, 0, err
+	}
+
+	loans, err := loanRepoHandler.GetActive()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	count, total := 0, 0
+	for i := range feeTypes {
+		feeType := &feeTypes[i]
+		for j := range loans {
+			loan := &loans[j]
+			installment, _, arrears := loanArrears(loan, now)
+			if arrears <= 0 {
+				continue
+			}
+
+			loanID := loan.ID
+			key := fmt.Sprintf("%s:LOAN-%d:%d", feeType.Code, loan.ID, installment)
+			description := fmt.Sprintf("%s on loan #%d installment %d", feeType.Name, loan.ID, installment)
+			charge, err := chargeFee(feeType, loan.BorrowerID, &loanID, key, arrears, description)
+			if err != nil {
+				log.Printf("WARNING: Failed to charge %s for loan %d: %v", feeType.Code, loan.ID, err)
+				continue
+			}
+			if charge != nil {
+				count++
+				total += charge.Amount
+			}
+		}
+	}
+	return count, total, nil
+}
+
+// runFeeAssessment collects member fees left outstanding, then charges
+// monthly and overdue fees that have fallen due.
+func runFeeAssessment(now time.Time) (int, int, error) {
+	if err := collectAllSavingsCharges(); err != nil {
+		return 0, 0, err
+	}
+	monthlyCount, monthlyTotal, err := assessMonthlyFees(now)
+	if err != nil {
+		return 0, 0, err
+	}
+	overdueCount, overdueTotal, err := assessOverdueFees(now)
+	if err != nil {
+		return monthlyCount, monthlyTotal, err
+	}
+	return monthlyCount + overdueCount, monthlyTotal + overdueTotal, nil
+}
+
+// ListFeeTypes godoc
+// @Summary List the fee catalog
+// @Description Returns every fee type with its trigger rule, rate, fixed amount and cap
+// @Tags fees
+// @Produce json
+// @Security SessionAuth
+// @Success 200 {object} FeeTypeListResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/fees/types [get]
+func ListFeeTypes(c echo.Context) error {
+	feeTypes, err := feeRepo.GetTypes()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch fee types"})
+	}
+
+	items := []FeeTypeItem{}
+	for i := range feeTypes {
+		items = append(items, toFeeTypeItem(&feeTypes[i]))
+	}
+
+	return c.JSON(http.StatusOK, FeeTypeListResponse{FeeTypes: items})
+}
+
+// UpdateFeeType godoc
+// @Summary Configure a fee type (manager)
+// @Description Updates the trigger rule, percentage rate, fixed amount, cap or active flag of a fee. Omitted fields are left unchanged. A cap of 0 means no cap.
+// @Tags fees
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param code path string true "Fee code"
+// @Param request body UpdateFeeTypeRequest true "Fee settings"
+// @Success 200 {object} FeeTypeItem
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/fees/types/{code} [post]
+func UpdateFeeType(c echo.Context) error {
+	var req UpdateFeeTypeRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request"})
+	}
+
+	feeType, err := feeRepo.GetTypeByCode(c.Param("code"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Fee type not found"})
+	}
+
+	if req.Name != nil {
+		if *req.Name == "" {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Name cannot be empty"})
+		}
+		feeType.Name = *req.Name
+	}
+	if req.TriggerRule != nil {
+		if !isValidFeeTrigger(*req.TriggerRule) {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid trigger rule"})
+		}
+		feeType.TriggerRule = *req.TriggerRule
+	}
+	if req.Rate != nil {
+		if *req.Rate < 0 || *req.Rate > 100 {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Rate must be between 0 and 100"})
+		}
+		feeType.Rate = *req.Rate
+	}
+	if req.Amount != nil {
+		if *req.Amount < 0 {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Amount cannot be negative"})
+		}
+		feeType.Amount = *req.Amount
+	}
+	if req.Cap != nil {
+		if *req.Cap < 0 {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Cap cannot be negative"})
+		}
+		feeType.Cap = *req.Cap
+	}
+	if req.IsActive != nil {
+		feeType.IsActive = *req.IsActive
+	}
+
+	if err := feeRepo.SaveType(feeType); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update fee type"})
+	}
+
+	return c.JSON(http.StatusOK, toFeeTypeItem(feeType))
+}
+
+// ListFeeCharges godoc
+// @Summary List fee charges (manager/auditor)
+// @Description Returns charged and waived fees, newest first
+// @Tags fees
+// @Produce json
+// @Security SessionAuth
+// @Param user_id query int false "Filter by member ID"
+// @Param status query string false "Filter by status (charged, outstanding, paid, waived)"
+// @Success 200 {object} FeeChargeListResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/fees/charges [get]
+func ListFeeCharges(c echo.Context) error {
+	var userID uint
+	if userIDStr := c.QueryParam("user_id"); userIDStr != "" {
+		id, err := strconv.ParseUint(userIDStr, 10, 32)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid user ID"})
+		}
+		userID = uint(id)
+	}
+
+	return respondWithFeeCharges(c, userID, c.QueryParam("status"))
+}
+
+// GetMemberFeeCharges godoc
+// @Summary List own fee charges (member)
+// @Description Returns the fees charged to the authenticated member
+// @Tags fees
+// @Produce json
+// @Security SessionAuth
+// @Success 200 {object} FeeChargeListResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/fees/member [get]
+func GetMemberFeeCharges(c echo.Context) error {
+	user := c.Get("user").(*repos.UserWithSession)
+	return respondWithFeeCharges(c, user.ID, "")
+}
+
+func respondWithFeeCharges(c echo.Context, userID uint, status string) error {
+	charges, err := feeRepo.GetCharges(userID, status)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch fee charges"})
+	}
+
+	response := FeeChargeListResponse{Charges: []FeeChargeItem{}}
+	for i := range charges {
+		response.Charges = append(response.Charges, toFeeChargeItem(&charges[i]))
+		response.TotalWaived += charges[i].WaivedAmount
+		response.TotalCharged += charges[i].Amount - charges[i].WaivedAmount
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// WaiveFeeCharge godoc
+// @Summary Waive a fee charge (manager)
+// @Description Reverses a fee. Savings fees are refunded to the member as far as they were collected; loan fees have their unpaid remainder written off the loan. A reason is required and the waiver is anchored on the blockchain.
+// @Tags fees
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param id path int true "Fee charge ID"
+// @Param request body WaiveFeeRequest true "Waiver reason"
+// @Success 200 {object} WaiveFeeResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/fees/charges/{id}/waive [post]
+func WaiveFeeCharge(c echo.Context) error {
+	user := c.Get("user").(*repos.UserWithSession)
+
+	chargeID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid fee charge ID"})
+	}
+
+	var req WaiveFeeRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request"})
+	}
+	if req.Reason == "" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "A reason is required to waive a fee"})
+	}
+
+	charge, err := feeRepo.GetCharge(uint(chargeID))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Fee charge not found"})
+	}
+	if charge.Status != db.FeeStatusCharged && charge.Status != db.FeeStatusOutstanding {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Only charged or outstanding fees can be waived"})
+	}
+
+	account := fmt.Sprintf("USER-%d", charge.UserID)
+	waivedAmount := charge.Amount
+	reversed := charge.Amount
+	if charge.Status == db.FeeStatusOutstanding {
+		if charge.LoanID != nil {
+			account = fmt.Sprintf("LOAN-%d", *charge.LoanID)
+			waivedAmount = charge.Amount - charge.PaidAmount
+			reversed = waivedAmount
+		} else {
+			// Savings only covered part of the fee: that part is refunded
+			// and the rest is never collected.
+			reversed = charge.PaidAmount
+		}
+	}
+
+	transactionID := transactionGenerator()
+	transaction := &db.Transaction{
+		TransactionID: transactionID,
+		Type:          "fee_waiver",
+		FromAccount:   "FEES",
+		ToAccount:     account,
+		Amount:        reversed,
+		Status:        "completed",
+		Description:   fmt.Sprintf("Waiver of %s (%s) by manager %d: %s", charge.FeeType.Name, charge.TransactionID, user.ID, req.Reason),
+	}
+	if err := anchorTransaction(transaction); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to record waiver"})
+	}
+
+	if charge.LoanID == nil && reversed > 0 {
+		if err := depositRepoHandler.UpdateUserBalance(charge.UserID, reversed); err != nil {
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update user balance"})
+		}
+	}
+
+	waivedByID := user.ID
+	waivedAt := time.Now().Unix()
+	charge.WaivedByID = &waivedByID
+	charge.WaivedAt = &waivedAt
+	charge.WaivedAmount = waivedAmount
+	charge.WaiverReason = req.Reason
+	charge.WaiverTransactionID = transactionID
+	if err := feeRepo.MarkWaived(charge); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to waive fee"})
+	}
+
+	return c.JSON(http.StatusOK, WaiveFeeResponse{
+		OK:            true,
+		TransactionID: transactionID,
+	})
+}
+
+// RunFees godoc
+// @Summary Run fee assessment now (manager)
+// @Description Charges monthly and overdue fees that have fallen due. This also runs automatically in the background; charges are never duplicated.
+// @Tags fees
+// @Produce json
+// @Security SessionAuth
+// @Success 200 {object} RunFeesResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/fees/run [post]
+func RunFees(c echo.Context) error {
+	count, total, err := runFeeAssessment(time.Now())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to assess fees"})
+	}
+
+	return c.JSON(http.StatusOK, RunFeesResponse{
+		OK:             true,
+		ChargesCreated: count,
+		TotalAmount:    total,
+	})
+}
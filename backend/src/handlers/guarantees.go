@@ -0,0 +1,493 @@
+package handlers
+
+import (
+	"backend/src/db"
+	"backend/src/lifecycle"
+	"backend/src/repos"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+var guaranteeRepo = repos.GuaranteeRepo{}
+
+type GuarantorNomination struct {
+	MemberID uint `json:"member_id" example:"3"`
+	Amount   int  `json:"amount" example:"50000"`
+}
+
+type GuaranteeItem struct {
+	ID            uint          `json:"id" example:"1"`
+	LoanID        uint          `json:"loan_id" example:"1"`
+	LoanStatus    string        `json:"loan_status" example:"Requested"`
+	Borrower      *BorrowerInfo `json:"borrower,omitempty"`
+	Guarantor     *BorrowerInfo `json:"guarantor,omitempty"`
+	Amount        int           `json:"amount" example:"50000"`
+	Locked        int           `json:"locked" example:"50000"`
+	Status        string        `json:"status" example:"accepted"`
+	RespondedAt   string        `json:"responded_at,omitempty" example:"2025-01-16T09:00:00Z"`
+	ReleasedAt    string        `json:"released_at,omitempty" example:"2025-12-16T09:00:00Z"`
+	TransactionID string        `json:"transaction_id" example:"TXN-1234567890"`
+	CreatedAt     string        `json:"created_at" example:"2025-01-15T10:00:00Z"`
+}
+
+type GuaranteeListResponse struct {
+	Guarantees  []GuaranteeItem `json:"guarantees"`
+	TotalLocked int             `json:"total_locked" example:"50000"`
+}
+
+type RespondGuaranteeRequest struct {
+	Decision string `json:"decision" binding:"required" example:"accept"`
+}
+
+type RespondGuaranteeResponse struct {
+	OK            bool   `json:"ok" example:"true"`
+	Status        string `json:"status" example:"accepted"`
+	TransactionID string `json:"transaction_id" example:"TXN-1234567890"`
+}
+
+type ReleaseGuaranteeRequest struct {
+	Reason string `json:"reason" binding:"required" example:"Guarantor settled their share with the committee"`
+}
+
+type GuarantorExposureItem struct {
+	MemberID       uint   `json:"member_id" example:"3"`
+	Name           string `json:"name" example:"John Doe"`
+	PhoneNumber    string `json:"phone_number" example:"+1234567890"`
+	SavingsBalance int    `json:"savings_balance" example:"120000"`
+	Guarantees     int    `json:"guarantees" example:"2"`
+	Guaranteed     int    `json:"guaranteed" example:"80000"`
+	Locked         int    `json:"locked" example:"64000"`
+	Available      int    `json:"available" example:"56000"`
+}
+
+type GuarantorExposureResponse struct {
+	Members         []GuarantorExposureItem `json:"members"`
+	TotalGuaranteed int                     `json:"total_guaranteed" example:"400000"`
+	TotalLocked     int                     `json:"total_locked" example:"310000"`
+}
+
+// guaranteeLocked is the part of the guarantor's savings held by an accepted
+// guarantee. The full amount is held until the loan is paid out, then it is
+// released in proportion to the principal repaid. Once the loan is written
+// off, the share of the written-off amount not yet recovered stays held
+// until the guarantee is released.
+func guaranteeLocked(guarantee *db.LoanGuarantee) int {
+	if guarantee.Status != db.GuaranteeStatusAccepted {
+		return 0
+	}
+	loan := &guarantee.Loan
+	switch {
+	case lifecycle.IsActive(loan.Status):
+		return proportionalLock(guarantee.Amount, loan.OutstandingBalance, loan.Principal)
+	case loan.Status == lifecycle.StatusWrittenOff:
+		return proportionalLock(guarantee.Amount, loan.WrittenOffAmount-loan.RecoveredAmount, loan.Principal)
+	case lifecycle.IsInitial(loan.Status):
+		return guarantee.Amount
+	}
+	return 0
+}
+
+// proportionalLock holds the guaranteed amount in proportion to what is
+// still owed of the principal, rounding up.
+func proportionalLock(amount, owed, principal int) int {
+	if principal <= 0 {
+		return amount
+	}
+	locked := (amount*owed + principal - 1) / principal
+	return min(max(locked, 0), amount)
+}
+
+// lockedSavings returns how much of the member's savings is held by the
+// guarantees they have accepted.
+func lockedSavings(userID uint) (int, error) {
+	guarantees, err := guaranteeRepo.GetAccepted(userID)
+	if err != nil {
+		return 0, err
+	}
+	locked := 0
+	for i := range guarantees {
+		locked += guaranteeLocked(&guarantees[i])
+	}
+	return locked, nil
+}
+
+// availableSavings returns the member's savings not held by guarantees.
+func availableSavings(userID uint) (int, error) {
+	member, err := userRepoHandler.GetByID(userID)
+	if err != nil {
+		return 0, err
+	}
+	locked, err := lockedSavings(userID)
+	if err != nil {
+		return 0, err
+	}
+	return member.SavingsBalance - locked, nil
+}
+
+// validateGuarantors checks nominations for a loan request and returns a
+// message describing the first problem, or "" when they are acceptable.
+func validateGuarantors(borrowerID uint, amount int, nominations []GuarantorNomination) string {
+	seen := map[uint]bool{}
+	total := 0
+	for _, nomination := range nominations {
+		if nomination.MemberID == borrowerID {
+			return "You cannot guarantee your own loan"
+		}
+		if seen[nomination.MemberID] {
+			return fmt.Sprintf("Member %d is nominated more than once", nomination.MemberID)
+		}
+		seen[nomination.MemberID] = true
+		if nomination.Amount <= 0 {
+			return "Guaranteed amounts must be positive"
+		}
+		total += nomination.Amount
+
+		guarantor, err := userRepoHandler.GetByID(nomination.MemberID)
+		if err != nil || guarantor.Role != "member" || !guarantor.IsActive {
+			return fmt.Sprintf("Member %d cannot act as a guarantor", nomination.MemberID)
+		}
+	}
+	if total > amount {
+		return "Guaranteed amounts cannot exceed the loan amount"
+	}
+	return ""
+}
+
+// anchorGuaranteeEvent records a guarantee nomination, response or release
+// on the chain and remembers the latest transaction on the guarantee. No
+// money moves, so the transaction carries no amount; the guaranteed amount
+// is part of the description.
+func anchorGuaranteeEvent(guarantee *db.LoanGuarantee, description string) error {
+	transaction := &db.Transaction{
+		TransactionID: transactionGenerator(),
+		Type:          "loan_guarantee",
+		FromAccount:   fmt.Sprintf("USER-%d", guarantee.GuarantorID),
+		ToAccount:     fmt.Sprintf("LOAN-%d", guarantee.LoanID),
+		Amount:        0,
+		Status:        "completed",
+		Description:   description,
+	}
+	if err := anchorTransaction(transaction); err != nil {
+		return err
+	}
+	guarantee.TransactionID = transaction.TransactionID
+	return nil
+}
+
+func nominateGuarantors(loan *db.Loan, nominations []GuarantorNomination) error {
+	for _, nomination := range nominations {
+		guarantee := &db.LoanGuarantee{
+			LoanID:      loan.ID,
+			GuarantorID: nomination.MemberID,
+			Amount:      nomination.Amount,
+			Status:      db.GuaranteeStatusPending,
+		}
+		description := fmt.Sprintf("Guarantee of %d on loan #%d requested from member %d", guarantee.Amount, loan.ID, guarantee.GuarantorID)
+		if err := anchorGuaranteeEvent(guarantee, description); err != nil {
+			return err
+		}
+		if err := guaranteeRepo.Create(guarantee); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// releaseGuarantees frees the guarantors of a loan that has been repaid,
+// fell through or had its write-off recovered in full. Pending nominations
+// are cancelled.
+func releaseGuarantees(loan *db.Loan) {
+	guarantees, err := guaranteeRepo.GetByLoan(loan.ID)
+	if err != nil {
+		log.Printf("WARNING: Failed to fetch guarantees for loan %d: %v", loan.ID, err)
+		return
+	}
+
+	now := time.Now().Unix()
+	for i := range guarantees {
+		guarantee := &guarantees[i]
+		switch guarantee.Status {
+		case db.GuaranteeStatusPending:
+			guarantee.Status = db.GuaranteeStatusCancelled
+		case db.GuaranteeStatusAccepted:
+			guarantee.Status = db.GuaranteeStatusReleased
+		default:
+			continue
+		}
+		guarantee.ReleasedAt = &now
+
+		description := fmt.Sprintf("Guarantee of %d on loan #%d %s: loan %s", guarantee.Amount, loan.ID, guarantee.Status, loan.Status)
+		if err := anchorGuaranteeEvent(guarantee, description); err != nil {
+			log.Printf("WARNING: Failed to anchor release of guarantee %d: %v", guarantee.ID, err)
+		}
+		if err := guaranteeRepo.Save(guarantee); err != nil {
+			log.Printf("WARNING: Failed to release guarantee %d: %v", guarantee.ID, err)
+		}
+	}
+}
+
+func toGuaranteeItem(guarantee *db.LoanGuarantee) GuaranteeItem {
+	item := GuaranteeItem{
+		ID:            guarantee.ID,
+		LoanID:        guarantee.LoanID,
+		LoanStatus:    guarantee.Loan.Status,
+		Amount:        guarantee.Amount,
+		Locked:        guaranteeLocked(guarantee),
+		Status:        guarantee.Status,
+		TransactionID: guarantee.TransactionID,
+		CreatedAt:     guarantee.CreatedAt.Format(time.RFC3339),
+	}
+	if guarantee.Loan.Borrower.ID != 0 {
+		item.Borrower = &BorrowerInfo{
+			ID:          guarantee.Loan.Borrower.ID,
+			Name:        guarantee.Loan.Borrower.Name,
+			PhoneNumber: guarantee.Loan.Borrower.PhoneNumber,
+		}
+	}
+	if guarantee.Guarantor.ID != 0 {
+		item.Guarantor = &BorrowerInfo{
+			ID:          guarantee.Guarantor.ID,
+			Name:        guarantee.Guarantor.Name,
+			PhoneNumber: guarantee.Guarantor.PhoneNumber,
+		}
+	}
+	if guarantee.RespondedAt != nil {
+		item.RespondedAt = time.Unix(*guarantee.RespondedAt, 0).Format(time.RFC3339)
+	}
+	if guarantee.ReleasedAt != nil {
+		item.ReleasedAt = time.Unix(*guarantee.ReleasedAt, 0).Format(time.RFC3339)
+	}
+	return item
+}
+
+// GetMyGuarantees godoc
+// @Summary List my guarantees (member)
+// @Description Returns the guarantees the member has been asked for or has given, with how much of their savings each one currently locks
+// @Tags guarantees
+// @Produce json
+// @Security SessionAuth
+// @Success 200 {object} GuaranteeListResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/guarantees [get]
+func GetMyGuarantees(c echo.Context) error {
+	user := c.Get("user").(*repos.UserWithSession)
+
+	guarantees, err := guaranteeRepo.GetByGuarantor(user.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch guarantees"})
+	}
+
+	response := GuaranteeListResponse{Guarantees: []GuaranteeItem{}}
+	for i := range guarantees {
+		item := toGuaranteeItem(&guarantees[i])
+		response.TotalLocked += item.Locked
+		response.Guarantees = append(response.Guarantees, item)
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// RespondToGuarantee godoc
+// @Summary Accept or decline a guarantee (member)
+// @Description The nominated guarantor accepts or declines guaranteeing a loan that is still awaiting approval. Accepting locks the guaranteed amount of their savings, which must be available. The response is anchored on the blockchain.
+// @Tags guarantees
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param id path int true "Guarantee ID"
+// @Param request body RespondGuaranteeRequest true "Decision (accept or decline)"
+// @Success 200 {object} RespondGuaranteeResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/guarantees/{id}/respond [post]
+func RespondToGuarantee(c echo.Context) error {
+	user := c.Get("user").(*repos.UserWithSession)
+
+	guaranteeID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid guarantee ID"})
+	}
+
+	var req RespondGuaranteeRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request"})
+	}
+	if req.Decision != "accept" && req.Decision != "decline" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Decision must be 'accept' or 'decline'"})
+	}
+
+	guarantee, err := guaranteeRepo.GetByID(uint(guaranteeID))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Guarantee not found"})
+	}
+
+	if guarantee.GuarantorID != user.ID {
+		return c.JSON(http.StatusForbidden, ErrorResponse{Error: "Not authorized to respond to this guarantee"})
+	}
+	if guarantee.Status != db.GuaranteeStatusPending {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Guarantee has already been answered"})
+	}
+	if !lifecycle.IsInitial(guarantee.Loan.Status) || guarantee.Loan.DisbursedAt != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Loan is no longer awaiting guarantees"})
+	}
+
+	guarantee.Status = db.GuaranteeStatusDeclined
+	if req.Decision == "accept" {
+		locked, err := lockedSavings(user.ID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to calculate locked savings"})
+		}
+		if available := guarantee.Guarantor.SavingsBalance - locked; available < guarantee.Amount {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error: fmt.Sprintf("Insufficient available savings: %d available, %d needed", available, guarantee.Amount),
+			})
+		}
+		guarantee.Status = db.GuaranteeStatusAccepted
+	}
+
+	now := time.Now().Unix()
+	guarantee.RespondedAt = &now
+
+	description := fmt.Sprintf("Guarantee of %d on loan #%d %s by member %d", guarantee.Amount, guarantee.LoanID, guarantee.Status, user.ID)
+	if err := anchorGuaranteeEvent(guarantee, description); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to record guarantee response"})
+	}
+	if err := guaranteeRepo.Save(guarantee); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update guarantee"})
+	}
+
+	return c.JSON(http.StatusOK, RespondGuaranteeResponse{
+		OK:            true,
+		Status:        guarantee.Status,
+		TransactionID: guarantee.TransactionID,
+	})
+}
+
+// ReleaseGuarantee godoc
+// @Summary Release a guarantee on a written-off loan (manager)
+// @Description Frees a guarantor whose accepted guarantee still locks savings on a written-off loan. Guarantees on written-off loans stay locked until they are released here or recoveries cover the amount written off. A reason is required and the release is anchored on the blockchain.
+// @Tags guarantees
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param id path int true "Guarantee ID"
+// @Param request body ReleaseGuaranteeRequest true "Release reason"
+// @Success 200 {object} RespondGuaranteeResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/guarantees/{id}/release [post]
+func ReleaseGuarantee(c echo.Context) error {
+	user := c.Get("user").(*repos.UserWithSession)
+
+	guaranteeID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid guarantee ID"})
+	}
+
+	var req ReleaseGuaranteeRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request"})
+	}
+	if req.Reason == "" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "A reason is required to release a guarantee"})
+	}
+
+	guarantee, err := guaranteeRepo.GetByID(uint(guaranteeID))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Guarantee not found"})
+	}
+	if guarantee.Status != db.GuaranteeStatusAccepted {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Only accepted guarantees can be released"})
+	}
+	if guarantee.Loan.Status != lifecycle.StatusWrittenOff {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Only guarantees on written-off loans can be released by hand"})
+	}
+
+	now := time.Now().Unix()
+	guarantee.Status = db.GuaranteeStatusReleased
+	guarantee.ReleasedAt = &now
+
+	description := fmt.Sprintf("Guarantee of %d on loan #%d released by manager %d: %s", guarantee.Amount, guarantee.LoanID, user.ID, req.Reason)
+	if err := anchorGuaranteeEvent(guarantee, description); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to record guarantee release"})
+	}
+	if err := guaranteeRepo.Save(guarantee); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update guarantee"})
+	}
+
+	return c.JSON(http.StatusOK, RespondGuaranteeResponse{
+		OK:            true,
+		Status:        guarantee.Status,
+		TransactionID: guarantee.TransactionID,
+	})
+}
+
+// GetGuarantorExposure godoc
+// @Summary Get guarantor exposure per member
+// @Description Returns, for every member with accepted guarantees on open or written-off loans, how much they have guaranteed, how much of their savings is locked and how much remains available
+// @Tags guarantees
+// @Produce json
+// @Security SessionAuth
+// @Param member_id query int false "Only this member"
+// @Success 200 {object} GuarantorExposureResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/guarantees/exposure [get]
+func GetGuarantorExposure(c echo.Context) error {
+	var memberID uint
+	if value := c.QueryParam("member_id"); value != "" {
+		parsed, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid member ID"})
+		}
+		memberID = uint(parsed)
+	}
+
+	guarantees, err := guaranteeRepo.GetAccepted(memberID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch guarantees"})
+	}
+
+	response := GuarantorExposureResponse{Members: []GuarantorExposureItem{}}
+	for i := range guarantees {
+		guarantee := &guarantees[i]
+		last := len(response.Members) - 1
+		if last < 0 || response.Members[last].MemberID != guarantee.GuarantorID {
+			response.Members = append(response.Members, GuarantorExposureItem{
+				MemberID:       guarantee.GuarantorID,
+				Name:           guarantee.Guarantor.Name,
+				PhoneNumber:    guarantee.Guarantor.PhoneNumber,
+				SavingsBalance: guarantee.Guarantor.SavingsBalance,
+				Available:      guarantee.Guarantor.SavingsBalance,
+			})
+			last++
+		}
+
+		locked := guaranteeLocked(guarantee)
+		member := &response.Members[last]
+		member.Guarantees++
+		member.Guaranteed += guarantee.Amount
+		member.Locked += locked
+		member.Available -= locked
+
+		response.TotalGuaranteed += guarantee.Amount
+		response.TotalLocked += locked
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"log"
+	"time"
+)
+
+type backgroundJob struct {
+	name    string
+	every   time.Duration
+	run     func(now time.Time) error
+	lastRun time.Time
+}
+
+var backgroundJobs = []backgroundJob{
+	{name: "fee_assessment", every: time.Hour, run: func(now time.Time) error {
+		_, _, err := runFeeAssessment(now)
+		return err
+	}},
+	{name: "delinquency_check", every: 24 * time.Hour, run: func(now time.Time) error {
+		_, err := runDelinquencyCheck(now)
+		return err
+	}},
+	{name: "loan_provisioning", every: 24 * time.Hour, run: func(now time.Time) error {
+		_, _, err := runProvisioning(now)
+		return err
+	}},
+	{name: "share_capital", every: time.Hour, run: func(now time.Time) error {
+		_, err := postShareCapital()
+		return err
+	}},
+	{name: "sms_reminders", every: time.Hour, run: func(now time.Time) error {
+		_, _, _, err := runReminders(now)
+		return err
+	}},
+}
+
+// StartBackgroundJobs runs the periodic jobs once at startup and then checks
+// every hour for jobs whose interval has elapsed. Each job is idempotent, so
+// overlapping or repeated runs are safe.
+func StartBackgroundJobs() {
+	go func() {
+		runBackgroundJobs(time.Now())
+
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			runBackgroundJobs(now)
+		}
+	}()
+}
+
+func runBackgroundJobs(now time.Time) {
+	for i := range backgroundJobs {
+		job := &backgroundJobs[i]
+		if !job.lastRun.IsZero() && now.Sub(job.lastRun) < job.every {
+			continue
+		}
+		job.lastRun = now
+		if err := job.run(now); err != nil {
+			log.Printf("WARNING: Background job %s failed: %v", job.name, err)
+		}
+	}
+}
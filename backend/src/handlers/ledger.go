@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"backend/src/db"
+	"log"
+)
+
+// anchorTransaction persists a ledger transaction and chains it into a new
+// block. A failure to create the block is logged but does not fail the
+// posting, matching how deposits and status changes are anchored.
+func anchorTransaction(transaction *db.Transaction) error {
+	if err := db.DB.Create(transaction).Error; err != nil {
+		return err
+	}
+
+	if _, err := db.CreateBlockForTransaction(transaction.TransactionID); err != nil {
+		log.Printf("WARNING: Failed to create blockchain block for %s: %v", transaction.Type, err)
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"backend/src/db"
+	"backend/src/lifecycle"
+	"backend/src/repos"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type LoanStatusHistoryItem struct {
+	ID             uint         `json:"id" example:"1"`
+	FromStatus     string       `json:"from_status,omitempty" example:"Requested"`
+	ToStatus       string       `json:"to_status" example:"Approved"`
+	ChangedBy      *ManagerInfo `json:"changed_by,omitempty"`
+	Reason         string       `json:"reason,omitempty" example:"Meets eligibility criteria"`
+	RequestVersion *int         `json:"request_version,omitempty" example:"2"`
+	CommentCount   *int         `json:"comment_count,omitempty" example:"3"`
+	CommentsDigest string       `json:"comments_digest,omitempty" example:"2c26b46b68ffc68f..."`
+	TransactionID  string       `json:"transaction_id" example:"TXN-1234567890"`
+	CreatedAt      string       `json:"created_at" example:"2025-01-16T09:00:00Z"`
+}
+
+type LoanStatusHistoryResponse struct {
+	LoanID             uint                    `json:"loan_id" example:"1"`
+	Status             string                  `json:"status" example:"Approved"`
+	AllowedTransitions []string                `json:"allowed_transitions" example:"Disbursed,Cancelled"`
+	History            []LoanStatusHistoryItem `json:"history"`
+}
+
+func statusChangeDescription(to string, actorID *uint) string {
+	if actorID == nil {
+		return fmt.Sprintf("Loan status changed to %s by system", to)
+	}
+	return fmt.Sprintf("Loan status changed to %s by user %d", to, *actorID)
+}
+
+func statusChangeTransaction(loanID uint, to string, actorID *uint) *db.Transaction {
+	return &db.Transaction{
+		TransactionID: transactionGenerator(),
+		Type:          "loan_status_change",
+		FromAccount:   fmt.Sprintf("LOAN-%d", loanID),
+		ToAccount:     to,
+		Amount:        0,
+		Status:        "completed",
+		Description:   statusChangeDescription(to, actorID),
+	}
+}
+
+// changeLoanStatus moves a loan through the state machine. It anchors the
+// given transaction, or a plain loan_status_change when nil, then applies the
+// new status with any accompanying column updates and records the change in
+// the loan's history. A nil actor marks a change made by the system.
+func changeLoanStatus(loan *db.Loan, to string, actorID *uint, reason string, transaction *db.Transaction, updates map[string]interface{}) (string, error) {
+	if err := lifecycle.Check(loan, to); err != nil {
+		return "", err
+	}
+
+	// Decisions on a request record the version of it they were made on and
+	// the discussion that led to them.
+	var requestVersion, commentCount *int
+	var digest string
+	if lifecycle.IsPending(loan.Status) {
+		version := loan.RequestVersion
+		requestVersion = &version
+
+		comments, err := commentRepo.GetByLoan(loan.ID, true)
+		if err != nil {
+			return "", err
+		}
+		count := len(comments)
+		commentCount = &count
+		digest = commentsDigest(comments)
+	}
+
+	if transaction == nil {
+		transaction = statusChangeTransaction(loan.ID, to, actorID)
+	}
+	if requestVersion != nil {
+		transaction.Description += fmt.Sprintf(" on request version %d", *requestVersion)
+	}
+	if commentCount != nil && *commentCount > 0 {
+		transaction.Description += fmt.Sprintf(" after %d comments (digest %s)", *commentCount, digest)
+	}
+	if err := anchorTransaction(transaction); err != nil {
+		return "", err
+	}
+
+	if updates == nil {
+		updates = map[string]interface{}{}
+	}
+	history := &db.LoanStatusHistory{
+		LoanID:         loan.ID,
+		FromStatus:     loan.Status,
+		ToStatus:       to,
+		ChangedByID:    actorID,
+		Reason:         reason,
+		TransactionID:  transaction.TransactionID,
+		CommentsDigest: digest,
+		RequestVersion: requestVersion,
+		CommentCount:   commentCount,
+	}
+	if err := loanRepoHandler.ApplyStatusChange(loan.ID, updates, history); err != nil {
+		return "", err
+	}
+
+	loan.Status = to
+
+	switch to {
+	case lifecycle.StatusPaidOff, lifecycle.StatusRejected, lifecycle.StatusCancelled:
+		releaseGuarantees(loan)
+		releaseCollateral(loan)
+	}
+
+	return transaction.TransactionID, nil
+}
+
+// recordLoanCreated anchors and records the initial status of a new loan.
+func recordLoanCreated(loan *db.Loan, actorID *uint, reason string) error {
+	transaction := statusChangeTransaction(loan.ID, loan.Status, actorID)
+	if err := anchorTransaction(transaction); err != nil {
+		return err
+	}
+
+	return loanRepoHandler.CreateStatusHistory(&db.LoanStatusHistory{
+		LoanID:        loan.ID,
+		ToStatus:      loan.Status,
+		ChangedByID:   actorID,
+		Reason:        reason,
+		TransactionID: transaction.TransactionID,
+	})
+}
+
+// statusChangeError maps a failed status change to a response: rejected
+// transitions are the caller's fault, anything else is ours.
+func statusChangeError(c echo.Context, err error) error {
+	if errors.Is(err, lifecycle.ErrInvalidTransition) {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+	return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update loan status"})
+}
+
+// GetLoanHistory godoc
+// @Summary Get loan status history
+// @Description Returns every status change of the loan with who made it, when, why and the anchored transaction, plus the states the loan may move to next. Decisions on a loan request also show the request version and the digest of the comments they were made after. Members may only view their own loans.
+// @Tags loans
+// @Produce json
+// @Security SessionAuth
+// @Param id path int true "Loan ID"
+// @Success 200 {object} LoanStatusHistoryResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/loans/{id}/history [get]
+func GetLoanHistory(c echo.Context) error {
+	user := c.Get("user").(*repos.UserWithSession)
+
+	loanID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid loan ID"})
+	}
+
+	loan, err := loanRepoHandler.GetByID(uint(loanID))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Loan not found"})
+	}
+
+	if user.Role == "member" && loan.BorrowerID != user.ID {
+		return c.JSON(http.StatusForbidden, ErrorResponse{Error: "Not authorized to view this loan"})
+	}
+
+	history, err := loanRepoHandler.GetStatusHistory(loan.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch loan history"})
+	}
+
+	response := LoanStatusHistoryResponse{
+		LoanID:             loan.ID,
+		Status:             loan.Status,
+		AllowedTransitions: lifecycle.Allowed(loan.Status),
+		History:            []LoanStatusHistoryItem{},
+	}
+	if response.AllowedTransitions == nil {
+		response.AllowedTransitions = []string{}
+	}
+
+	for _, entry := range history {
+		item := LoanStatusHistoryItem{
+			ID:             entry.ID,
+			FromStatus:     entry.FromStatus,
+			ToStatus:       entry.ToStatus,
+			Reason:         entry.Reason,
+			RequestVersion: entry.RequestVersion,
+			CommentCount:   entry.CommentCount,
+			CommentsDigest: entry.CommentsDigest,
+			TransactionID:  entry.TransactionID,
+			CreatedAt:      entry.CreatedAt.Format(time.RFC3339),
+		}
+		if entry.ChangedBy != nil {
+			item.ChangedBy = &ManagerInfo{
+				ID:   entry.ChangedBy.ID,
+				Name: entry.ChangedBy.Name,
+			}
+		}
+		response.History = append(response.History, item)
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
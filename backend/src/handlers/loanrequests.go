@@ -0,0 +1,424 @@
+package handlers
+
+import (
+	"backend/src/db"
+	"backend/src/eligibility"
+	"backend/src/lifecycle"
+	"backend/src/repos"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type CancelLoanRequest struct {
+	Reason string `json:"reason" example:"No longer needed"`
+}
+
+type AmendLoanRequest struct {
+	Amount   *int    `json:"amount" example:"80000"`
+	Duration *int    `json:"duration" example:"6"`
+	Reason   *string `json:"reason" example:"Home renovation, kitchen only"`
+	Note     string  `json:"note" example:"Reduced the amount after getting a quote"`
+}
+
+type AmendLoanResponse struct {
+	OK            bool   `json:"ok" example:"true"`
+	LoanID        uint   `json:"loan_id" example:"1"`
+	Version       int    `json:"version" example:"2"`
+	TransactionID string `json:"transaction_id" example:"TXN-1234567890"`
+}
+
+type LoanRequestChange struct {
+	Field string `json:"field" example:"amount"`
+	From  string `json:"from" example:"100000"`
+	To    string `json:"to" example:"80000"`
+}
+
+type LoanRequestVersionItem struct {
+	Version       int                 `json:"version" example:"2"`
+	Amount        int                 `json:"amount" example:"80000"`
+	Duration      int                 `json:"duration" example:"6"`
+	Reason        string              `json:"reason" example:"Home renovation, kitchen only"`
+	Note          string              `json:"note,omitempty" example:"Reduced the amount after getting a quote"`
+	Changes       []LoanRequestChange `json:"changes"`
+	ChangedBy     *ManagerInfo        `json:"changed_by,omitempty"`
+	TransactionID string              `json:"transaction_id" example:"TXN-1234567890"`
+	CreatedAt     string              `json:"created_at" example:"2025-01-15T10:30:00Z"`
+}
+
+type LoanRequestVersionsResponse struct {
+	LoanID         uint                     `json:"loan_id" example:"1"`
+	Status         string                   `json:"status" example:"Approved"`
+	CurrentVersion int                      `json:"current_version" example:"2"`
+	DecidedVersion *int                     `json:"decided_version,omitempty" example:"2"`
+	Versions       []LoanRequestVersionItem `json:"versions"`
+}
+
+// recordRequestVersion anchors the loan's current request terms as its
+// current request version.
+func recordRequestVersion(loan *db.Loan, actorID *uint) (*db.LoanRequestVersion, error) {
+	transaction := requestVersionTransaction(loan.ID, loan.RequestVersion, loan.Amount, loan.Duration, loan.Reason, actorID)
+	if err := anchorTransaction(transaction); err != nil {
+		return nil, err
+	}
+
+	version := &db.LoanRequestVersion{
+		LoanID:        loan.ID,
+		Version:       loan.RequestVersion,
+		Amount:        loan.Amount,
+		Duration:      loan.Duration,
+		Reason:        loan.Reason,
+		ChangedByID:   actorID,
+		TransactionID: transaction.TransactionID,
+	}
+	if err := loanRepoHandler.CreateRequestVersion(version); err != nil {
+		return nil, err
+	}
+	return version, nil
+}
+
+// requestVersionTransaction anchors a version of a loan request. A request
+// moves no money, so the requested amount is only part of the description.
+func requestVersionTransaction(loanID uint, version, amount, duration int, reason string, actorID *uint) *db.Transaction {
+	by := "system"
+	if actorID != nil {
+		by = fmt.Sprintf("user %d", *actorID)
+	}
+	return &db.Transaction{
+		TransactionID: transactionGenerator(),
+		Type:          "loan_request_version",
+		FromAccount:   fmt.Sprintf("LOAN-%d", loanID),
+		ToAccount:     fmt.Sprintf("REQUEST-V%d", version),
+		Amount:        0,
+		Status:        "completed",
+		Description: fmt.Sprintf("Loan #%d request version %d by %s: amount %d over %d months; reason %q",
+			loanID, version, by, amount, duration, reason),
+	}
+}
+
+func requestChanges(previous, current *db.LoanRequestVersion) []LoanRequestChange {
+	changes := []LoanRequestChange{}
+	if previous == nil {
+		return changes
+	}
+	if previous.Amount != current.Amount {
+		changes = append(changes, LoanRequestChange{"amount", strconv.Itoa(previous.Amount), strconv.Itoa(current.Amount)})
+	}
+	if previous.Duration != current.Duration {
+		changes = append(changes, LoanRequestChange{"duration", strconv.Itoa(previous.Duration), strconv.Itoa(current.Duration)})
+	}
+	if previous.Reason != current.Reason {
+		changes = append(changes, LoanRequestChange{"reason", previous.Reason, current.Reason})
+	}
+	return changes
+}
+
+// CancelLoan godoc
+// @Summary Cancel a loan request (member)
+// @Description Member withdraws their own loan request before a manager has acted on it. Pending guarantor nominations are cancelled.
+// @Tags loans
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param id path int true "Loan ID"
+// @Param request body CancelLoanRequest false "Reason"
+// @Success 200 {object} OKResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/loans/{id}/cancel [post]
+func CancelLoan(c echo.Context) error {
+	user := c.Get("user").(*repos.UserWithSession)
+
+	var req CancelLoanRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request"})
+	}
+
+	loanID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid loan ID"})
+	}
+
+	loan, err := loanRepoHandler.GetByID(uint(loanID))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Loan not found"})
+	}
+	if loan.BorrowerID != user.ID {
+		return c.JSON(http.StatusForbidden, ErrorResponse{Error: "Not authorized to change this loan"})
+	}
+	if loan.Status != lifecycle.StatusRequested {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Only loan requests a manager has not acted on can be changed"})
+	}
+
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		reason = "Cancelled by member"
+	}
+
+	actorID := user.ID
+	if _, err := changeLoanStatus(loan, lifecycle.StatusCancelled, &actorID, reason, nil, nil); err != nil {
+		return statusChangeError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, OKResponse{
+		OK:      true,
+		Message: "Loan request cancelled",
+	})
+}
+
+// AmendLoan godoc
+// @Summary Amend a loan request (member)
+// @Description Member changes the amount, duration or reason of their own loan request before a manager has acted on it. The amended request must still pass the eligibility rules and product terms, and cover what guarantors have been asked for. A top-up must still exceed the payoff of the loan it refinances. Each amendment is anchored as a new request version.
+// @Tags loans
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param id path int true "Loan ID"
+// @Param request body AmendLoanRequest true "Changed terms; omitted fields are left unchanged"
+// @Success 200 {object} AmendLoanResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 422 {object} LoanRefusedResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/loans/{id}/amend [post]
+func AmendLoan(c echo.Context) error {
+	user := c.Get("user").(*repos.UserWithSession)
+
+	var req AmendLoanRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request"})
+	}
+
+	loanID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid loan ID"})
+	}
+
+	loan, err := loanRepoHandler.GetByID(uint(loanID))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Loan not found"})
+	}
+	if loan.BorrowerID != user.ID {
+		return c.JSON(http.StatusForbidden, ErrorResponse{Error: "Not authorized to change this loan"})
+	}
+	// Once a manager has acted on the request it is no longer the member's
+	// to change.
+	if loan.Status != lifecycle.StatusRequested {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Only loan requests a manager has not acted on can be changed"})
+	}
+
+	amount, duration, reason := loan.Amount, loan.Duration, loan.Reason
+	if req.Amount != nil {
+		amount = *req.Amount
+	}
+	if req.Duration != nil {
+		duration = *req.Duration
+	}
+	if req.Reason != nil {
+		reason = strings.TrimSpace(*req.Reason)
+	}
+	if amount <= 0 || duration <= 0 {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Amount and duration must be positive"})
+	}
+	if reason == "" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Reason cannot be empty"})
+	}
+	if amount == loan.Amount && duration == loan.Duration && reason == loan.Reason {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Amendment does not change the request"})
+	}
+
+	now := time.Now()
+	applicant, err := loanApplicant(user.ID, now)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to check eligibility"})
+	}
+	// The request being amended is already one of the member's open loans.
+	applicant.OpenLoans--
+	if loan.TopUpOfID != nil {
+		previous, err := loanRepoHandler.GetByID(*loan.TopUpOfID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch the loan being topped up"})
+		}
+		problem, err := checkTopUp(previous, user.ID, amount, loan.ID, now)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to calculate payoff amount"})
+		}
+		if problem != "" {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: problem})
+		}
+		applicant.OpenLoans--
+	}
+	if loan.ProductID != nil {
+		product, err := productRepo.GetByID(*loan.ProductID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Loan product not found"})
+		}
+		if message := productTermsError(product, amount, duration); message != "" {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: message})
+		}
+		applicant.Durations = product.DurationList()
+	}
+	rules, err := eligibilityRepo.GetRules()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch eligibility rules"})
+	}
+	if reasons := eligibility.Evaluate(rules, applicant, amount, duration, now); len(reasons) > 0 {
+		return c.JSON(http.StatusUnprocessableEntity, LoanRefusedResponse{
+			Error:   "Amended loan request refused",
+			Reasons: toEligibilityReasonItems(reasons),
+		})
+	}
+
+	guarantees, err := guaranteeRepo.GetByLoan(loan.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch guarantors"})
+	}
+	guaranteed := 0
+	for _, guarantee := range guarantees {
+		if guarantee.Status == db.GuaranteeStatusPending || guarantee.Status == db.GuaranteeStatusAccepted {
+			guaranteed += guarantee.Amount
+		}
+	}
+	if guaranteed > amount {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: fmt.Sprintf("Guarantors have been asked to cover %d; the amount cannot be lower", guaranteed),
+		})
+	}
+
+	// Requests made before versioning have no record of their original
+	// terms; keep them as the first version before amending.
+	versions, err := loanRepoHandler.GetRequestVersions(loan.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch request versions"})
+	}
+	if len(versions) == 0 {
+		borrowerID := loan.BorrowerID
+		if _, err := recordRequestVersion(loan, &borrowerID); err != nil {
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to record original request"})
+		}
+	}
+
+	actorID := user.ID
+	next := loan.RequestVersion + 1
+	transaction := requestVersionTransaction(loan.ID, next, amount, duration, reason, &actorID)
+	if err := anchorTransaction(transaction); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to record amendment"})
+	}
+
+	version := &db.LoanRequestVersion{
+		LoanID:        loan.ID,
+		Version:       next,
+		Amount:        amount,
+		Duration:      duration,
+		Reason:        reason,
+		Note:          strings.TrimSpace(req.Note),
+		ChangedByID:   &actorID,
+		TransactionID: transaction.TransactionID,
+	}
+	applied, err := loanRepoHandler.AmendRequest(loan.ID, lifecycle.StatusRequested, loan.RequestVersion, map[string]interface{}{
+		"amount":              amount,
+		"principal":           amount,
+		"outstanding_balance": amount,
+		"duration":            duration,
+		"reason":              reason,
+	}, version)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to amend loan request"})
+	}
+	if !applied {
+		return c.JSON(http.StatusConflict, ErrorResponse{Error: "Loan request changed while amending; reload it and try again"})
+	}
+
+	return c.JSON(http.StatusOK, AmendLoanResponse{
+		OK:            true,
+		LoanID:        loan.ID,
+		Version:       next,
+		TransactionID: transaction.TransactionID,
+	})
+}
+
+// GetLoanRequestVersions godoc
+// @Summary Get loan request versions
+// @Description Returns the member's original request and every amendment with what changed, and the version the final decision was made on. Members may only view their own loans.
+// @Tags loans
+// @Produce json
+// @Security SessionAuth
+// @Param id path int true "Loan ID"
+// @Success 200 {object} LoanRequestVersionsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/loans/{id}/versions [get]
+func GetLoanRequestVersions(c echo.Context) error {
+	user := c.Get("user").(*repos.UserWithSession)
+
+	loanID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid loan ID"})
+	}
+
+	loan, err := loanRepoHandler.GetByID(uint(loanID))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Loan not found"})
+	}
+	if user.Role == "member" && loan.BorrowerID != user.ID {
+		return c.JSON(http.StatusForbidden, ErrorResponse{Error: "Not authorized to view this loan"})
+	}
+
+	versions, err := loanRepoHandler.GetRequestVersions(loan.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch request versions"})
+	}
+	history, err := loanRepoHandler.GetStatusHistory(loan.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch loan history"})
+	}
+
+	response := LoanRequestVersionsResponse{
+		LoanID:         loan.ID,
+		Status:         loan.Status,
+		CurrentVersion: loan.RequestVersion,
+		Versions:       []LoanRequestVersionItem{},
+	}
+	for _, entry := range history {
+		if entry.RequestVersion != nil && !lifecycle.IsPending(entry.ToStatus) {
+			response.DecidedVersion = entry.RequestVersion
+		}
+	}
+
+	for i := range versions {
+		version := &versions[i]
+		var previous *db.LoanRequestVersion
+		if i > 0 {
+			previous = &versions[i-1]
+		}
+		item := LoanRequestVersionItem{
+			Version:       version.Version,
+			Amount:        version.Amount,
+			Duration:      version.Duration,
+			Reason:        version.Reason,
+			Note:          version.Note,
+			Changes:       requestChanges(previous, version),
+			TransactionID: version.TransactionID,
+			CreatedAt:     version.CreatedAt.Format(time.RFC3339),
+		}
+		if version.ChangedBy != nil {
+			item.ChangedBy = &ManagerInfo{ID: version.ChangedBy.ID, Name: version.ChangedBy.Name}
+		}
+		response.Versions = append(response.Versions, item)
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
@@ -1,8 +1,12 @@
 package handlers
 
 import (
+	"backend/src/amortization"
 	"backend/src/db"
+	"backend/src/eligibility"
+	"backend/src/lifecycle"
 	"backend/src/repos"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -29,6 +33,7 @@ type LoanSummary struct {
 	Reason             string  `json:"reason" example:"Home renovation"`
 	MonthlyPayment     int     `json:"monthly_payment" example:"9000"`
 	OutstandingBalance int     `json:"outstanding_balance" example:"95000"`
+	TopUpOfID          *uint   `json:"top_up_of_id,omitempty" example:"3"`
 	CreatedAt          string  `json:"created_at" example:"2025-01-15T10:00:00Z"`
 }
 
@@ -44,18 +49,39 @@ type ManagerLoansResponse struct {
 }
 
 type LoanDetailResponse struct {
-	ID                 uint         `json:"id" example:"1"`
-	Borrower           BorrowerInfo `json:"borrower"`
-	ApprovedBy         *ManagerInfo `json:"approved_by,omitempty"`
-	Amount             int          `json:"amount" example:"100000"`
-	Principal          int          `json:"principal" example:"100000"`
-	Duration           int          `json:"duration" example:"12"`
-	InterestRate       float64      `json:"interest_rate" example:"12.5"`
-	Status             string       `json:"status" example:"Approved"`
-	Reason             string       `json:"reason" example:"Home renovation"`
-	MonthlyPayment     int          `json:"monthly_payment" example:"9000"`
-	OutstandingBalance int          `json:"outstanding_balance" example:"95000"`
-	CreatedAt          string       `json:"created_at" example:"2025-01-15T10:00:00Z"`
+	ID                 uint             `json:"id" example:"1"`
+	Borrower           BorrowerInfo     `json:"borrower"`
+	ApprovedBy         *ManagerInfo     `json:"approved_by,omitempty"`
+	ProductID          *uint            `json:"product_id,omitempty" example:"1"`
+	ProductName        string           `json:"product_name,omitempty" example:"Development loan"`
+	ProductTerms       *LoanProductItem `json:"product_terms,omitempty"`
+	Amount             int              `json:"amount" example:"100000"`
+	InterestRateID     *uint            `json:"interest_rate_id,omitempty" example:"3"`
+	Principal          int              `json:"principal" example:"100000"`
+	Duration           int              `json:"duration" example:"12"`
+	InterestRate       float64          `json:"interest_rate" example:"12.5"`
+	Status             string           `json:"status" example:"Approved"`
+	Reason             string           `json:"reason" example:"Home renovation"`
+	RequestVersion     int              `json:"request_version" example:"1"`
+	RepaymentMethod    string           `json:"repayment_method" example:"flat"`
+	MonthlyPayment     int              `json:"monthly_payment" example:"9000"`
+	OutstandingBalance int              `json:"outstanding_balance" example:"95000"`
+	DisbursedAt        string           `json:"disbursed_at,omitempty" example:"2025-01-20T10:00:00Z"`
+	DisbursementMethod string           `json:"disbursement_method,omitempty" example:"bank"`
+	DisbursementRef    string           `json:"disbursement_reference,omitempty" example:"BANK-TX-98765"`
+	DaysPastDue        int              `json:"days_past_due" example:"0"`
+	ArrearsAmount      int              `json:"arrears_amount" example:"0"`
+	WrittenOffAt       string           `json:"written_off_at,omitempty" example:"2025-06-30T10:00:00Z"`
+	WrittenOffAmount   int              `json:"written_off_amount,omitempty" example:"83333"`
+	RecoveredAmount    int              `json:"recovered_amount,omitempty" example:"10000"`
+	TopUpOfID          *uint            `json:"top_up_of_id,omitempty" example:"3"`
+	TopUpAmount        int              `json:"top_up_amount,omitempty" example:"42000"`
+	RefinancedIntoID   *uint            `json:"refinanced_into_id,omitempty" example:"7"`
+	Guarantors         []GuaranteeItem  `json:"guarantors"`
+	Collateral         []CollateralItem `json:"collateral"`
+	CollateralValue    int              `json:"collateral_value" example:"250000"`
+	CoverageRatio      float64          `json:"coverage_ratio" example:"2.63"`
+	CreatedAt          string           `json:"created_at" example:"2025-01-15T10:00:00Z"`
 }
 
 type BorrowerInfo struct {
@@ -70,7 +96,9 @@ type ManagerInfo struct {
 }
 
 type UpdateLoanStatusRequest struct {
-	Status string `json:"status" binding:"required" example:"Approved"`
+	Status         string `json:"status" binding:"required" example:"Approved"`
+	Reason         string `json:"reason" example:"Meets eligibility criteria"`
+	RequestVersion *int   `json:"request_version" example:"2"`
 }
 
 type UpdateLoanStatusResponse struct {
@@ -79,9 +107,13 @@ type UpdateLoanStatusResponse struct {
 }
 
 type RequestLoanRequest struct {
-	Amount   int    `json:"amount" binding:"required" example:"100000"`
-	Duration int    `json:"duration" binding:"required" example:"12"`
-	Reason   string `json:"reason" binding:"required" example:"Home renovation"`
+	ProductID       *uint                 `json:"product_id" example:"1"`
+	Amount          int                   `json:"amount" binding:"required" example:"100000"`
+	Duration        int                   `json:"duration" binding:"required" example:"12"`
+	Reason          string                `json:"reason" binding:"required" example:"Home renovation"`
+	RepaymentMethod string                `json:"repayment_method" example:"flat"`
+	Guarantors      []GuarantorNomination `json:"guarantors"`
+	TopUpOfID       *uint                 `json:"top_up_of_id" example:"3"`
 }
 
 type RequestLoanResponse struct {
@@ -90,12 +122,13 @@ type RequestLoanResponse struct {
 }
 
 type AddLoanRequest struct {
-	BorrowerID   uint    `json:"borrower_id" binding:"required" example:"1"`
-	Amount       int     `json:"amount" binding:"required" example:"100000"`
-	Duration     int     `json:"duration" binding:"required" example:"12"`
-	InterestRate float64 `json:"interest_rate" binding:"required" example:"12.5"`
-	Reason       string  `json:"reason" example:"Home renovation"`
-	Status       string  `json:"status" example:"Approved"`
+	BorrowerID      uint   `json:"borrower_id" binding:"required" example:"1"`
+	ProductID       *uint  `json:"product_id" example:"1"`
+	Amount          int    `json:"amount" binding:"required" example:"100000"`
+	Duration        int    `json:"duration" binding:"required" example:"12"`
+	Reason          string `json:"reason" example:"Home renovation"`
+	Status          string `json:"status" example:"Requested"`
+	RepaymentMethod string `json:"repayment_method" example:"reducing_balance"`
 }
 
 type AddDepositRequest struct {
@@ -109,11 +142,26 @@ type AddDepositResponse struct {
 	TransactionID string `json:"transaction_id" example:"TXN-1234567890"`
 }
 
+type AddWithdrawalRequest struct {
+	UserID    uint   `json:"user_id" binding:"required" example:"1"`
+	Amount    int    `json:"amount" binding:"required" example:"5000"`
+	Reference string `json:"reference" example:"BANK-TX-12346"`
+}
+
+type AddWithdrawalResponse struct {
+	OK             bool   `json:"ok" example:"true"`
+	TransactionID  string `json:"transaction_id" example:"TXN-1234567890"`
+	SavingsBalance int    `json:"savings_balance" example:"45000"`
+	Locked         int    `json:"locked" example:"20000"`
+}
+
 type InterestRateResponse struct {
-	Rates []InterestRateItem `json:"rates"`
+	Rates     []InterestRateItem `json:"rates"`
+	Scheduled []InterestRateItem `json:"scheduled"`
 }
 
 type InterestRateItem struct {
+	ID             uint    `json:"id" example:"1"`
 	DurationMonths int     `json:"duration_months" example:"12"`
 	Rate           float64 `json:"rate" example:"12.5"`
 	EffectiveFrom  string  `json:"effective_from" example:"2025-01-01T00:00:00Z"`
@@ -122,6 +170,24 @@ type InterestRateItem struct {
 type SetInterestRateRequest struct {
 	DurationMonths int     `json:"duration_months" binding:"required" example:"12"`
 	Rate           float64 `json:"rate" binding:"required" example:"12.5"`
+	EffectiveFrom  string  `json:"effective_from" example:"2025-02-01"`
+}
+
+type SetInterestRateResponse struct {
+	OK      bool                    `json:"ok" example:"true"`
+	Version InterestRateVersionItem `json:"version"`
+}
+
+type InterestRateVersionItem struct {
+	InterestRateItem
+	Status        string       `json:"status" example:"current"`
+	SetBy         *ManagerInfo `json:"set_by,omitempty"`
+	TransactionID string       `json:"transaction_id,omitempty" example:"TXN-1234567890"`
+	CreatedAt     string       `json:"created_at" example:"2025-01-01T00:00:00Z"`
+}
+
+type InterestRateHistoryResponse struct {
+	Versions []InterestRateVersionItem `json:"versions"`
 }
 
 // GetMemberLoans godoc
@@ -158,10 +224,11 @@ func GetMemberLoans(c echo.Context) error {
 			Reason:             loan.Reason,
 			MonthlyPayment:     loan.MonthlyPayment,
 			OutstandingBalance: loan.OutstandingBalance,
+			TopUpOfID:          loan.TopUpOfID,
 			CreatedAt:          loan.CreatedAt.Format(time.RFC3339),
 		})
 
-		if loan.Status == "Approved" || loan.Status == "Disbursed" {
+		if lifecycle.IsActive(loan.Status) {
 			totalDue += loan.OutstandingBalance
 			monthlyPaymentTotal += loan.MonthlyPayment
 		}
@@ -186,7 +253,7 @@ func GetMemberLoans(c echo.Context) error {
 // @Failure 500 {object} ErrorResponse
 // @Router /api/v1/loans/manager [get]
 func GetManagerLoans(c echo.Context) error {
-	requestedLoans, err := loanRepoHandler.GetByStatus("Requested")
+	requestedLoans, err := loanRepoHandler.GetByStatus(lifecycle.StatusRequested)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch requested loans"})
 	}
@@ -210,12 +277,13 @@ func GetManagerLoans(c echo.Context) error {
 			Reason:             loan.Reason,
 			MonthlyPayment:     loan.MonthlyPayment,
 			OutstandingBalance: loan.OutstandingBalance,
+			TopUpOfID:          loan.TopUpOfID,
 			CreatedAt:          loan.CreatedAt.Format(time.RFC3339),
 		})
 	}
 
 	for _, loan := range allLoans {
-		if loan.Status != "Requested" {
+		if loan.Status != lifecycle.StatusRequested {
 			otherSummaries = append(otherSummaries, LoanSummary{
 				ID:                 loan.ID,
 				Amount:             loan.Amount,
@@ -226,6 +294,7 @@ func GetManagerLoans(c echo.Context) error {
 				Reason:             loan.Reason,
 				MonthlyPayment:     loan.MonthlyPayment,
 				OutstandingBalance: loan.OutstandingBalance,
+				TopUpOfID:          loan.TopUpOfID,
 				CreatedAt:          loan.CreatedAt.Format(time.RFC3339),
 			})
 		}
@@ -271,16 +340,46 @@ func GetLoanByID(c echo.Context) error {
 			PhoneNumber: loan.Borrower.PhoneNumber,
 		},
 		Amount:             loan.Amount,
+		InterestRateID:     loan.InterestRateID,
 		Principal:          loan.Principal,
 		Duration:           loan.Duration,
 		InterestRate:       loan.InterestRate,
 		Status:             loan.Status,
 		Reason:             loan.Reason,
+		RequestVersion:     loan.RequestVersion,
+		RepaymentMethod:    loanRepaymentMethod(loan),
 		MonthlyPayment:     loan.MonthlyPayment,
 		OutstandingBalance: loan.OutstandingBalance,
+		DisbursementMethod: loan.DisbursementMethod,
+		DisbursementRef:    loan.DisbursementRef,
+		DaysPastDue:        loan.DaysPastDue,
+		ArrearsAmount:      loan.ArrearsAmount,
+		TopUpOfID:          loan.TopUpOfID,
+		TopUpAmount:        loan.TopUpAmount,
 		CreatedAt:          loan.CreatedAt.Format(time.RFC3339),
 	}
 
+	if loan.DisbursedAt != nil {
+		response.DisbursedAt = time.Unix(*loan.DisbursedAt, 0).Format(time.RFC3339)
+	}
+
+	response.RefinancedIntoID, err = refinancedInto(loan.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch top-ups"})
+	}
+
+	if loan.WrittenOffAt != nil {
+		response.WrittenOffAt = time.Unix(*loan.WrittenOffAt, 0).Format(time.RFC3339)
+		response.WrittenOffAmount = loan.WrittenOffAmount
+		response.RecoveredAmount = loan.RecoveredAmount
+	}
+
+	if loan.Product != nil {
+		response.ProductID = loan.ProductID
+		response.ProductName = loan.Product.Name
+	}
+	response.ProductTerms = loanProductTerms(loan)
+
 	if loan.ApprovedBy != nil {
 		response.ApprovedBy = &ManagerInfo{
 			ID:   loan.ApprovedBy.ID,
@@ -288,12 +387,30 @@ func GetLoanByID(c echo.Context) error {
 		}
 	}
 
+	guarantees, err := guaranteeRepo.GetByLoan(loan.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch guarantors"})
+	}
+	response.Guarantors = []GuaranteeItem{}
+	for i := range guarantees {
+		guarantees[i].Loan = *loan
+		item := toGuaranteeItem(&guarantees[i])
+		item.Borrower = nil
+		response.Guarantors = append(response.Guarantors, item)
+	}
+
+	response.Collateral, response.CollateralValue, err = loanCollateral(loan.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch collateral"})
+	}
+	response.CoverageRatio = coverageRatio(response.CollateralValue, loan.OutstandingBalance)
+
 	return c.JSON(http.StatusOK, response)
 }
 
 // UpdateLoanStatus godoc
-// @Summary Update loan status (approve/reject)
-// @Description Manager approves or rejects a loan. On approval, sets interest rate and monthly payment
+// @Summary Update loan status (review/approve/reject)
+// @Description Manager puts a loan under review, approves or rejects it. The change must be allowed by the loan state machine and is recorded in the loan's history with the request version it was made on. Passing the reviewed request_version refuses the decision if the member has amended the request since. Approving or rejecting casts the manager's vote under the approval policy for the loan's amount: the loan stays under review until the band's quorum of approvals is reached, or until enough committee members reject it that quorum cannot be, and the anchored decision lists every vote. On approval, sets interest rate, generates the repayment schedule and sets the monthly payment
 // @Tags loans
 // @Accept json
 // @Produce json
@@ -305,6 +422,7 @@ func GetLoanByID(c echo.Context) error {
 // @Failure 401 {object} ErrorResponse
 // @Failure 403 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /api/v1/loans/{id}/update_status [post]
 func UpdateLoanStatus(c echo.Context) error {
@@ -321,8 +439,10 @@ func UpdateLoanStatus(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request"})
 	}
 
-	if req.Status != "Approved" && req.Status != "Rejected" {
-		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Status must be 'Approved' or 'Rejected'"})
+	switch req.Status {
+	case lifecycle.StatusUnderReview, lifecycle.StatusApproved, lifecycle.StatusRejected:
+	default:
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Status must be 'UnderReview', 'Approved' or 'Rejected'"})
 	}
 
 	loan, err := loanRepoHandler.GetByID(uint(loanID))
@@ -330,49 +450,126 @@ func UpdateLoanStatus(c echo.Context) error {
 		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Loan not found"})
 	}
 
-	if loan.Status != "Requested" {
-		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Only requested loans can be approved/rejected"})
+	if err := lifecycle.Check(loan, req.Status); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 	}
 
-	var interestRate float64
-	var monthlyPayment int
+	if req.RequestVersion != nil && *req.RequestVersion != loan.RequestVersion {
+		return c.JSON(http.StatusConflict, ErrorResponse{
+			Error: fmt.Sprintf("Loan request has been amended to version %d; review it before deciding", loan.RequestVersion),
+		})
+	}
 
-	if req.Status == "Approved" {
-		rate, err := interestRateRepo.GetByDuration(loan.Duration)
+	// Approvals and rejections are votes under the approval policy for the
+	// loan's amount, and only decide the loan once the vote is settled.
+	var round *approvalRound
+	if req.Status != lifecycle.StatusUnderReview {
+		round, err = loadApprovalRound(loan)
 		if err != nil {
-			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Interest rate not found for this duration"})
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch approval policy"})
+		}
+		if !round.canVote(user.ID) {
+			return c.JSON(http.StatusForbidden, ErrorResponse{Error: "Only the approval committee for this amount can decide on this loan"})
+		}
+		if round.hasVoted(user.ID) {
+			return c.JSON(http.StatusConflict, ErrorResponse{Error: "You have already voted on this version of the request"})
 		}
-
-		interestRate = rate.Rate
-		totalAmount := float64(loan.Amount) * (1 + interestRate/100)
-		monthlyPayment = int(totalAmount / float64(loan.Duration))
 	}
 
-	err = loanRepoHandler.UpdateStatus(uint(loanID), req.Status, user.ID, interestRate, monthlyPayment)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update loan status"})
+	updates := map[string]interface{}{}
+	var schedule []amortization.Installment
+
+	if req.Status == lifecycle.StatusApproved {
+		now := time.Now()
+		var rate float64
+		if loan.ProductID != nil {
+			product, err := productRepo.GetByID(*loan.ProductID)
+			if err != nil {
+				return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Loan product not found"})
+			}
+			accepted, err := guaranteeRepo.CountAccepted(loan.ID)
+			if err != nil {
+				return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch guarantors"})
+			}
+			if accepted < product.RequiredGuarantors {
+				return c.JSON(http.StatusBadRequest, ErrorResponse{
+					Error: fmt.Sprintf("%s needs %d accepted guarantor(s); %d have accepted", product.Name, product.RequiredGuarantors, accepted),
+				})
+			}
+
+			rate = product.InterestRate
+			loan.RepaymentMethod = product.RepaymentMethod
+			updates["repayment_method"] = product.RepaymentMethod
+			updates["product_terms"] = productTerms(product)
+		} else {
+			interestRate, err := interestRateRepo.GetByDuration(loan.Duration, now.Unix())
+			if err != nil {
+				return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Interest rate not found for this duration"})
+			}
+			rate = interestRate.Rate
+			updates["interest_rate_id"] = interestRate.ID
+		}
+
+		schedule, err = generateLoanSchedule(loan, rate, now)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		}
+
+		updates["approved_by_id"] = user.ID
+		updates["approved_at"] = now.Unix()
+		updates["interest_rate"] = rate
+		updates["monthly_payment"] = schedule[0].Payment
 	}
 
-	// Create blockchain block for loan status change
-	transactionID := transactionGenerator()
-	transaction := &db.Transaction{
-		TransactionID: transactionID,
-		Type:          "loan_status_change",
-		FromAccount:   fmt.Sprintf("LOAN-%d", loanID),
-		ToAccount:     req.Status,
-		Amount:        0,
-		Status:        "completed",
-		Description:   fmt.Sprintf("Loan status changed to %s by manager %d", req.Status, user.ID),
+	actorID := user.ID
+	if round != nil {
+		decision := db.VoteApprove
+		if req.Status == lifecycle.StatusRejected {
+			decision = db.VoteReject
+		}
+		// The vote is only saved once the status change it causes has been
+		// applied, so a failed change leaves the manager free to vote again.
+		vote := castApprovalVote(loan, round, user, decision, req.Reason)
+
+		if (decision == db.VoteApprove && !round.approved()) || (decision == db.VoteReject && !round.rejected()) {
+			tally := fmt.Sprintf("%d of %d approvals", round.Approvals, round.Policy.RequiredApprovals)
+			if loan.Status == lifecycle.StatusRequested {
+				if _, err := changeLoanStatus(loan, lifecycle.StatusUnderReview, &actorID, "Awaiting approval: "+tally, nil, nil); err != nil {
+					return statusChangeError(c, err)
+				}
+			}
+			if err := saveApprovalVote(vote); err != nil {
+				return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to record vote"})
+			}
+			return c.JSON(http.StatusOK, UpdateLoanStatusResponse{
+				OK:      true,
+				Message: fmt.Sprintf("Vote recorded with %s; the loan stays under review", tally),
+			})
+		}
+
+		transaction := statusChangeTransaction(loan.ID, req.Status, &actorID)
+		transaction.Description += "; votes: " + round.voters()
+		if _, err := changeLoanStatus(loan, req.Status, &actorID, req.Reason, transaction, updates); err != nil {
+			return statusChangeError(c, err)
+		}
+		if err := saveApprovalVote(vote); err != nil {
+			log.Printf("WARNING: Failed to record deciding vote of user %d on loan %d: %v", user.ID, loan.ID, err)
+		}
+	} else if _, err := changeLoanStatus(loan, req.Status, &actorID, req.Reason, nil, updates); err != nil {
+		return statusChangeError(c, err)
 	}
-	if err := db.DB.Create(transaction).Error; err != nil {
-		log.Printf("WARNING: Failed to create transaction for loan status change: %v", err)
-	} else {
-		if _, err := db.CreateBlockForTransaction(transactionID); err != nil {
-			log.Printf("WARNING: Failed to create blockchain block for loan status change: %v", err)
+
+	if schedule != nil {
+		if err := saveLoanSchedule(loan.ID, schedule); err != nil {
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to save repayment schedule"})
 		}
 	}
 
-	message := fmt.Sprintf("Loan %s successfully", req.Status)
+	if req.Status == lifecycle.StatusApproved {
+		chargeApprovalFees(loan)
+	}
+
+	message := fmt.Sprintf("Loan status changed to %s successfully", req.Status)
 	return c.JSON(http.StatusOK, UpdateLoanStatusResponse{
 		OK:      true,
 		Message: message,
@@ -381,7 +578,7 @@ func UpdateLoanStatus(c echo.Context) error {
 
 // RequestLoan godoc
 // @Summary Request a new loan (member)
-// @Description Member requests a loan with amount, duration, and reason
+// @Description Member requests a loan with amount, duration, and reason, optionally nominating other members to guarantee part of it. Each guarantor then accepts or declines. A loan for a product must fit its durations and amount limits, nominate its required number of guarantors and is repaid by its method. Passing top_up_of_id requests a top-up of one of the member's active loans: the amount must exceed that loan's payoff today, and on disbursement the new loan settles the old one and pays out only the difference.
 // @Tags loans
 // @Accept json
 // @Produce json
@@ -391,6 +588,7 @@ func UpdateLoanStatus(c echo.Context) error {
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
 // @Failure 403 {object} ErrorResponse
+// @Failure 422 {object} LoanRefusedResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /api/v1/loans/request [post]
 func RequestLoan(c echo.Context) error {
@@ -401,22 +599,106 @@ func RequestLoan(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request"})
 	}
 
+	repaymentMethod := req.RepaymentMethod
+	if repaymentMethod == "" {
+		repaymentMethod = amortization.MethodFlat
+	}
+	if !amortization.IsValidMethod(repaymentMethod) {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Repayment method must be 'flat' or 'reducing_balance'"})
+	}
+
+	if req.Amount <= 0 || req.Duration <= 0 {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Amount and duration must be positive"})
+	}
+
+	var product *db.LoanProduct
+	if req.ProductID != nil {
+		var err error
+		product, err = productRepo.GetByID(*req.ProductID)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Loan product not found"})
+		}
+		if message := productTermsError(product, req.Amount, req.Duration); message != "" {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: message})
+		}
+		if len(req.Guarantors) < product.RequiredGuarantors {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error: fmt.Sprintf("%s needs at least %d guarantor(s)", product.Name, product.RequiredGuarantors),
+			})
+		}
+		repaymentMethod = product.RepaymentMethod
+	}
+
+	rules, err := eligibilityRepo.GetRules()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch eligibility rules"})
+	}
+	now := time.Now()
+	applicant, err := loanApplicant(user.ID, now)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to check eligibility"})
+	}
+	if product != nil {
+		applicant.Durations = product.DurationList()
+	}
+	if req.TopUpOfID != nil {
+		previous, err := loanRepoHandler.GetByID(*req.TopUpOfID)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Loan to top up not found"})
+		}
+		problem, err := checkTopUp(previous, user.ID, req.Amount, 0, now)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to calculate payoff amount"})
+		}
+		if problem != "" {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: problem})
+		}
+		// The loan being topped up is closed by the new one.
+		applicant.OpenLoans--
+	}
+	if reasons := eligibility.Evaluate(rules, applicant, req.Amount, req.Duration, now); len(reasons) > 0 {
+		return c.JSON(http.StatusUnprocessableEntity, LoanRefusedResponse{
+			Error:   "Loan request refused",
+			Reasons: toEligibilityReasonItems(reasons),
+		})
+	}
+
+	if message := validateGuarantors(user.ID, req.Amount, req.Guarantors); message != "" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: message})
+	}
+
 	loan := &db.Loan{
 		BorrowerID:         user.ID,
+		ProductID:          req.ProductID,
 		Amount:             req.Amount,
 		Principal:          req.Amount,
 		Duration:           req.Duration,
 		InterestRate:       0,
-		Status:             "Requested",
+		Status:             lifecycle.StatusRequested,
 		Reason:             req.Reason,
+		RepaymentMethod:    repaymentMethod,
 		MonthlyPayment:     0,
 		OutstandingBalance: req.Amount,
+		TopUpOfID:          req.TopUpOfID,
 	}
 
 	if err := loanRepoHandler.Create(loan); err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to create loan request"})
 	}
 
+	actorID := user.ID
+	if err := recordLoanCreated(loan, &actorID, "Requested by member"); err != nil {
+		log.Printf("WARNING: Failed to record status history for loan %d: %v", loan.ID, err)
+	}
+
+	if _, err := recordRequestVersion(loan, &actorID); err != nil {
+		log.Printf("WARNING: Failed to record request version for loan %d: %v", loan.ID, err)
+	}
+
+	if err := nominateGuarantors(loan, req.Guarantors); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to nominate guarantors"})
+	}
+
 	return c.JSON(http.StatusOK, RequestLoanResponse{
 		OK:     true,
 		LoanID: loan.ID,
@@ -424,8 +706,8 @@ func RequestLoan(c echo.Context) error {
 }
 
 // AddLoan godoc
-// @Summary Add a new loan directly (manager)
-// @Description Manager directly adds a loan with all details
+// @Summary Add a loan request on a member's behalf (manager)
+// @Description Manager files a loan request for a member, as Requested (the default) or UnderReview. The request must pass the member's eligibility rules and is decided through the approval committee like any other request. A loan for a product takes its repayment method from the product and must fit its durations and amount limits; other loans need an interest rate in force for their duration.
 // @Tags loans
 // @Accept json
 // @Produce json
@@ -435,6 +717,7 @@ func RequestLoan(c echo.Context) error {
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
 // @Failure 403 {object} ErrorResponse
+// @Failure 422 {object} LoanRefusedResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /api/v1/loans/add [post]
 func AddLoan(c echo.Context) error {
@@ -445,25 +728,89 @@ func AddLoan(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request"})
 	}
 
+	// Approval is a committee decision, so a manager can only file the
+	// request; it is approved through UpdateLoanStatus.
 	status := req.Status
 	if status == "" {
-		status = "Approved"
+		status = lifecycle.StatusRequested
+	}
+	if !lifecycle.IsPending(status) {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Status must be 'Requested' or 'UnderReview'"})
 	}
 
-	totalAmount := float64(req.Amount) * (1 + req.InterestRate/100)
-	monthlyPayment := int(totalAmount / float64(req.Duration))
+	if req.Amount <= 0 || req.Duration <= 0 {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Amount and duration must be positive"})
+	}
+
+	borrower, err := userRepoHandler.GetByID(req.BorrowerID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Borrower not found"})
+	}
+	if borrower.Role != "member" || !borrower.IsActive {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("User #%d is not an active member", borrower.ID)})
+	}
+
+	repaymentMethod := req.RepaymentMethod
+	if repaymentMethod == "" {
+		repaymentMethod = amortization.MethodFlat
+	}
+	if !amortization.IsValidMethod(repaymentMethod) {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Repayment method must be 'flat' or 'reducing_balance'"})
+	}
+
+	now := time.Now()
+	var interestRate float64
+	var interestRateID *uint
+	var product *db.LoanProduct
+	if req.ProductID != nil {
+		product, err = productRepo.GetByID(*req.ProductID)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Loan product not found"})
+		}
+		if message := productTermsError(product, req.Amount, req.Duration); message != "" {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: message})
+		}
+		interestRate = product.InterestRate
+		repaymentMethod = product.RepaymentMethod
+	} else {
+		rate, err := interestRateRepo.GetByDuration(req.Duration, now.Unix())
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("No interest rate is set for %d-month loans", req.Duration)})
+		}
+		interestRate = rate.Rate
+		interestRateID = &rate.ID
+	}
+
+	rules, err := eligibilityRepo.GetRules()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch eligibility rules"})
+	}
+	applicant, err := loanApplicant(borrower.ID, now)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to check eligibility"})
+	}
+	if product != nil {
+		applicant.Durations = product.DurationList()
+	}
+	if reasons := eligibility.Evaluate(rules, applicant, req.Amount, req.Duration, now); len(reasons) > 0 {
+		return c.JSON(http.StatusUnprocessableEntity, LoanRefusedResponse{
+			Error:   "Loan request refused",
+			Reasons: toEligibilityReasonItems(reasons),
+		})
+	}
 
-	approvedByID := user.ID
 	loan := &db.Loan{
-		BorrowerID:         req.BorrowerID,
-		ApprovedByID:       &approvedByID,
+		BorrowerID:         borrower.ID,
+		ProductID:          req.ProductID,
+		InterestRateID:     interestRateID,
 		Amount:             req.Amount,
 		Principal:          req.Amount,
 		Duration:           req.Duration,
-		InterestRate:       req.InterestRate,
+		InterestRate:       interestRate,
 		Status:             status,
 		Reason:             req.Reason,
-		MonthlyPayment:     monthlyPayment,
+		RepaymentMethod:    repaymentMethod,
+		MonthlyPayment:     0,
 		OutstandingBalance: req.Amount,
 	}
 
@@ -471,6 +818,15 @@ func AddLoan(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to create loan"})
 	}
 
+	actorID := user.ID
+	if err := recordLoanCreated(loan, &actorID, "Added by manager"); err != nil {
+		log.Printf("WARNING: Failed to record status history for loan %d: %v", loan.ID, err)
+	}
+
+	if _, err := recordRequestVersion(loan, &actorID); err != nil {
+		log.Printf("WARNING: Failed to record request version for loan %d: %v", loan.ID, err)
+	}
+
 	return c.JSON(http.StatusOK, RequestLoanResponse{
 		OK:     true,
 		LoanID: loan.ID,
@@ -497,36 +853,52 @@ func AddDeposit(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request"})
 	}
 
+	transactionID, err := recordDeposit(req.UserID, req.Amount, req.Reference, fmt.Sprintf("Deposit: %s", req.Reference))
+	if err != nil {
+		log.Printf("ERROR: Failed to record deposit for user %d: %v", req.UserID, err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to record deposit"})
+	}
+
+	return c.JSON(http.StatusOK, AddDepositResponse{
+		OK:            true,
+		TransactionID: transactionID,
+	})
+}
+
+// recordDeposit credits a member's savings with money received at the bank,
+// recording the deposit and its anchored transaction. It returns the
+// transaction ID.
+func recordDeposit(userID uint, amount int, reference, description string) (string, error) {
 	transactionID := transactionGenerator()
 
 	deposit := &db.Deposit{
 		TransactionID: transactionID,
-		UserID:        req.UserID,
-		Amount:        req.Amount,
+		UserID:        userID,
+		Amount:        amount,
 		Status:        "completed",
-		Reference:     req.Reference,
+		Reference:     reference,
 	}
 
 	if err := depositRepoHandler.Create(deposit); err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to create deposit"})
+		return "", fmt.Errorf("failed to create deposit: %w", err)
 	}
 
 	transaction := &db.Transaction{
 		TransactionID: transactionID,
 		Type:          "deposit",
 		FromAccount:   "BANK",
-		ToAccount:     fmt.Sprintf("USER-%d", req.UserID),
-		Amount:        req.Amount,
+		ToAccount:     fmt.Sprintf("USER-%d", userID),
+		Amount:        amount,
 		Status:        "completed",
-		Description:   fmt.Sprintf("Deposit: %s", req.Reference),
+		Description:   description,
 	}
 
 	if err := db.DB.Create(transaction).Error; err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to create transaction"})
+		return "", fmt.Errorf("failed to create transaction: %w", err)
 	}
 
-	if err := depositRepoHandler.UpdateUserBalance(req.UserID, req.Amount); err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update user balance"})
+	if err := depositRepoHandler.UpdateUserBalance(userID, amount); err != nil {
+		return "", fmt.Errorf("failed to update user balance: %w", err)
 	}
 
 	// Create blockchain block for deposit
@@ -534,69 +906,263 @@ func AddDeposit(c echo.Context) error {
 		log.Printf("WARNING: Failed to create blockchain block for deposit: %v", err)
 	}
 
-	return c.JSON(http.StatusOK, AddDepositResponse{
-		OK:            true,
-		TransactionID: transactionID,
+	if err := collectSavingsCharges(userID); err != nil {
+		log.Printf("WARNING: Failed to collect outstanding fees for user %d: %v", userID, err)
+	}
+
+	return transactionID, nil
+}
+
+// AddWithdrawal godoc
+// @Summary Withdraw savings (manager)
+// @Description Manager pays out part of a member's savings, creates transaction & block and reduces the savings balance. Savings locked by accepted loan guarantees cannot be withdrawn.
+// @Tags deposits
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param request body AddWithdrawalRequest true "Add Withdrawal Request"
+// @Success 200 {object} AddWithdrawalResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/withdrawal [post]
+func AddWithdrawal(c echo.Context) error {
+	var req AddWithdrawalRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request"})
+	}
+
+	if req.Amount <= 0 {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Amount must be positive"})
+	}
+
+	member, err := userRepoHandler.GetByID(req.UserID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "User not found"})
+	}
+
+	locked, err := lockedSavings(member.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to calculate locked savings"})
+	}
+	if available := member.SavingsBalance - locked; req.Amount > available {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: fmt.Sprintf("Only %d of %d in savings is available; %d is locked by loan guarantees", max(available, 0), member.SavingsBalance, locked),
+		})
+	}
+
+	transaction := &db.Transaction{
+		TransactionID: transactionGenerator(),
+		Type:          "withdrawal",
+		FromAccount:   fmt.Sprintf("USER-%d", member.ID),
+		ToAccount:     "BANK",
+		Amount:        req.Amount,
+		Status:        "completed",
+		Description:   fmt.Sprintf("Withdrawal: %s", req.Reference),
+	}
+	if err := anchorTransaction(transaction); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to record withdrawal"})
+	}
+
+	if err := depositRepoHandler.UpdateUserBalance(member.ID, -req.Amount); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update user balance"})
+	}
+
+	return c.JSON(http.StatusOK, AddWithdrawalResponse{
+		OK:             true,
+		TransactionID:  transaction.TransactionID,
+		SavingsBalance: member.SavingsBalance - req.Amount,
+		Locked:         locked,
 	})
 }
 
+func toInterestRateItem(rate *db.InterestRate) InterestRateItem {
+	return InterestRateItem{
+		ID:             rate.ID,
+		DurationMonths: rate.DurationMonths,
+		Rate:           rate.Rate,
+		EffectiveFrom:  time.Unix(rate.EffectiveFrom, 0).Format(time.RFC3339),
+	}
+}
+
+func toInterestRateVersionItem(rate *db.InterestRate, status string) InterestRateVersionItem {
+	item := InterestRateVersionItem{
+		InterestRateItem: toInterestRateItem(rate),
+		Status:           status,
+		TransactionID:    rate.TransactionID,
+		CreatedAt:        rate.CreatedAt.Format(time.RFC3339),
+	}
+	if rate.SetBy != nil {
+		item.SetBy = &ManagerInfo{ID: rate.SetBy.ID, Name: rate.SetBy.Name}
+	}
+	return item
+}
+
+// rateVersionStatuses labels rate versions ordered by duration and then
+// newest first: versions not yet in effect are scheduled, the newest one in
+// effect is current and older ones are superseded.
+func rateVersionStatuses(rates []db.InterestRate, now int64) []string {
+	statuses := make([]string, len(rates))
+	current := map[int]bool{}
+	for i, rate := range rates {
+		switch {
+		case rate.EffectiveFrom > now:
+			statuses[i] = "scheduled"
+		case !current[rate.DurationMonths]:
+			statuses[i] = "current"
+			current[rate.DurationMonths] = true
+		default:
+			statuses[i] = "superseded"
+		}
+	}
+	return statuses
+}
+
 // GetInterestRates godoc
 // @Summary Get all interest rates
-// @Description Returns list of all interest rates by duration
+// @Description Returns the interest rate in force for each duration, and the rate changes scheduled to take effect later
 // @Tags interest-rates
 // @Produce json
 // @Success 200 {object} InterestRateResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /api/v1/interest_rates [get]
 func GetInterestRates(c echo.Context) error {
-	rates, err := interestRateRepo.GetAll()
+	now := time.Now().Unix()
+	rates, err := interestRateRepo.GetCurrent(now)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch interest rates"})
+	}
+	history, err := interestRateRepo.GetHistory(0)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch interest rates"})
 	}
 
-	var items []InterestRateItem
-	for _, rate := range rates {
-		items = append(items, InterestRateItem{
-			DurationMonths: rate.DurationMonths,
-			Rate:           rate.Rate,
-			EffectiveFrom:  time.Unix(rate.EffectiveFrom, 0).Format(time.RFC3339),
-		})
+	response := InterestRateResponse{Rates: []InterestRateItem{}, Scheduled: []InterestRateItem{}}
+	for i := range rates {
+		response.Rates = append(response.Rates, toInterestRateItem(&rates[i]))
+	}
+	for i := range history {
+		if history[i].EffectiveFrom > now {
+			response.Scheduled = append(response.Scheduled, toInterestRateItem(&history[i]))
+		}
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// GetInterestRateHistory godoc
+// @Summary Get interest rate history
+// @Description Returns every version of the interest rates, newest first per duration, marked scheduled, current or superseded, with the manager who set it and the anchoring transaction
+// @Tags interest-rates
+// @Produce json
+// @Security SessionAuth
+// @Param duration_months query int false "Only this loan duration"
+// @Success 200 {object} InterestRateHistoryResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/interest_rates/history [get]
+func GetInterestRateHistory(c echo.Context) error {
+	durationMonths := 0
+	if value := c.QueryParam("duration_months"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid duration"})
+		}
+		durationMonths = parsed
 	}
 
-	return c.JSON(http.StatusOK, InterestRateResponse{Rates: items})
+	rates, err := interestRateRepo.GetHistory(durationMonths)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch interest rate history"})
+	}
+
+	statuses := rateVersionStatuses(rates, time.Now().Unix())
+	versions := []InterestRateVersionItem{}
+	for i := range rates {
+		versions = append(versions, toInterestRateVersionItem(&rates[i], statuses[i]))
+	}
+
+	return c.JSON(http.StatusOK, InterestRateHistoryResponse{Versions: versions})
 }
 
 // SetInterestRate godoc
 // @Summary Set interest rate for duration (manager)
-// @Description Manager sets or updates interest rate for a specific loan duration
+// @Description Manager adds a new version of the interest rate for a loan duration, in effect now or from a later date (YYYY-MM-DD). Earlier versions are kept as history, and loans approved before the change keep their rate. Each change is anchored as a transaction.
 // @Tags interest-rates
 // @Accept json
 // @Produce json
 // @Security SessionAuth
 // @Param request body SetInterestRateRequest true "Set Interest Rate Request"
-// @Success 200 {object} map[string]bool
+// @Success 200 {object} SetInterestRateResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
 // @Failure 403 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /api/v1/interest_rates/set [post]
 func SetInterestRate(c echo.Context) error {
+	user := c.Get("user").(*repos.UserWithSession)
+
 	var req SetInterestRateRequest
 	if err := c.Bind(&req); err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request"})
 	}
+	if req.DurationMonths <= 0 || req.Rate <= 0 {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Duration and rate must be positive"})
+	}
+
+	now := time.Now()
+	effectiveFrom := now
+	if req.EffectiveFrom != "" {
+		day, err := time.ParseInLocation("2006-01-02", req.EffectiveFrom, time.Local)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid effective date, expected YYYY-MM-DD"})
+		}
+		if day.Before(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)) {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Effective date cannot be in the past"})
+		}
+		// A change dated today takes effect now so loans approved earlier in
+		// the day stay under the rate that was in force.
+		if day.After(now) {
+			effectiveFrom = day
+		}
+	}
+
+	transaction := &db.Transaction{
+		TransactionID: transactionGenerator(),
+		Type:          "interest_rate_change",
+		FromAccount:   "RATES",
+		ToAccount:     fmt.Sprintf("RATE-%dM", req.DurationMonths),
+		Amount:        0,
+		Status:        "completed",
+		Description: fmt.Sprintf("Interest rate for %d-month loans set to %.2f%% from %s by manager %d",
+			req.DurationMonths, req.Rate, effectiveFrom.Format(time.RFC3339), user.ID),
+	}
+	if err := anchorTransaction(transaction); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to record interest rate change"})
+	}
 
+	setByID := user.ID
 	rate := &db.InterestRate{
 		DurationMonths: req.DurationMonths,
 		Rate:           req.Rate,
-		EffectiveFrom:  time.Now().Unix(),
+		EffectiveFrom:  effectiveFrom.Unix(),
+		SetByID:        &setByID,
+		TransactionID:  transaction.TransactionID,
 	}
-
-	if err := interestRateRepo.Upsert(rate); err != nil {
+	if err := interestRateRepo.Create(rate); err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to set interest rate"})
 	}
+	status := "current"
+	if rate.EffectiveFrom > now.Unix() {
+		status = "scheduled"
+	}
+	version := toInterestRateVersionItem(rate, status)
+	version.SetBy = &ManagerInfo{ID: user.ID, Name: user.Name}
 
-	return c.JSON(http.StatusOK, map[string]bool{"ok": true})
+	return c.JSON(http.StatusOK, SetInterestRateResponse{OK: true, Version: version})
 }
 
 func generateTransactionID() string {
@@ -604,21 +1170,21 @@ func generateTransactionID() string {
 }
 
 type MakePaymentRequest struct {
-	LoanID          uint `json:"loan_id" binding:"required" example:"1"`
-	Amount          int  `json:"amount" binding:"required" example:"9000"`
-	PrincipalAmount int  `json:"principal_amount" binding:"required" example:"8000"`
-	InterestAmount  int  `json:"interest_amount" binding:"required" example:"1000"`
+	LoanID  uint  `json:"loan_id" binding:"required" example:"1"`
+	Amount  int   `json:"amount" binding:"required" example:"9000"`
+	QuoteID *uint `json:"quote_id" example:"1"`
 }
 
 type MakePaymentResponse struct {
-	OK            bool   `json:"ok" example:"true"`
-	TransactionID string `json:"transaction_id" example:"TXN-1234567890"`
-	BalanceAfter  int    `json:"balance_after" example:"86000"`
+	OK            bool              `json:"ok" example:"true"`
+	TransactionID string            `json:"transaction_id" example:"TXN-1234567890"`
+	BalanceAfter  int               `json:"balance_after" example:"86000"`
+	Allocation    PaymentAllocation `json:"allocation"`
 }
 
 // MakePayment godoc
 // @Summary Make a loan payment
-// @Description Member makes a payment towards their loan, creates transaction and blockchain block
+// @Description Member makes a payment towards their loan, creates transaction and blockchain block. The server splits the amount across penalties, fees, interest and principal in the configured waterfall order; any overpayment is prepaid against principal or credited to savings. A payment that would clear the loan ahead of its schedule is refused: paying the total of an unexpired payoff quote with its quote_id is the only way to settle early, and closes the loan as PaidOff. A loan closes only once its principal and charges are all paid.
 // @Tags loans
 // @Accept json
 // @Produce json
@@ -648,65 +1214,148 @@ func MakePayment(c echo.Context) error {
 		return c.JSON(http.StatusForbidden, ErrorResponse{Error: "Not authorized to pay this loan"})
 	}
 
-	if loan.Status != "Approved" && loan.Status != "Disbursed" {
+	if !lifecycle.IsActive(loan.Status) {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Loan is not active"})
 	}
 
-	transactionID := transactionGenerator()
+	if req.Amount <= 0 {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Amount must be positive"})
+	}
 
-	newBalance := loan.OutstandingBalance - req.PrincipalAmount
-	if newBalance < 0 {
-		newBalance = 0
+	now := time.Now()
+	dues, charges, err := loanDues(loan, now)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to calculate amount due"})
 	}
 
+	var quote *db.PayoffQuote
+	if req.QuoteID != nil {
+		quote, err = payoffRepo.GetQuote(*req.QuoteID)
+		if err != nil || quote.LoanID != loan.ID {
+			return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Payoff quote not found"})
+		}
+		problem, err := checkPayoffQuote(loan, quote, req.Amount, now)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to check payoff quote"})
+		}
+		if problem != "" {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: problem})
+		}
+
+		if err := chargePayoffFees(loan); err != nil {
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to charge early settlement fee"})
+		}
+		charges, err = feeRepo.GetOutstandingLoanCharges(loan.ID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to calculate amount due"})
+		}
+		dues = quoteDues(quote)
+	}
+	payment, allocation, err := applyLoanPayment(loan, user.ID, req.Amount, dues, charges, fmt.Sprintf("Loan payment for loan #%d", loan.ID), now)
+	if errors.Is(err, errEarlySettlement) {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "This payment would settle the loan early; request a payoff quote and pay its total with the quote_id"})
+	}
+	if err != nil {
+		log.Printf("ERROR: Failed to record payment for loan %d: %v", loan.ID, err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to record payment"})
+	}
+
+	if quote != nil {
+		if err := payoffRepo.MarkSettled(quote.ID, payment.TransactionID); err != nil {
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update payoff quote"})
+		}
+	}
+
+	return c.JSON(http.StatusOK, MakePaymentResponse{
+		OK:            true,
+		TransactionID: payment.TransactionID,
+		BalanceAfter:  payment.BalanceAfter,
+		Allocation:    toPaymentAllocation(allocation),
+	})
+}
+
+// errEarlySettlement is returned for a payment that would prepay the whole
+// remaining principal. Settling early goes through a payoff quote so the
+// interest accrued to date and any early settlement fee are collected.
+var errEarlySettlement = errors.New("payment would settle the loan early")
+
+// applyLoanPayment splits a payment from payerID across the loan's dues,
+// records and anchors it, settles the charges it covers, credits any excess
+// to the payer's savings and closes the loan once neither principal nor
+// charges are outstanding. A payment that would clear the loan ahead of its
+// schedule without a payoff quote's dues fails with errEarlySettlement.
+func applyLoanPayment(loan *db.Loan, payerID uint, amount int, dues amortization.Dues, charges []db.FeeCharge, description string, now time.Time) (*db.LoanPayment, amortization.Allocation, error) {
+	allocation := amortization.Allocate(amount, dues, paymentWaterfall(), prepayOverpayments())
+	if allocation.SettlesEarly(dues) {
+		return nil, allocation, errEarlySettlement
+	}
+
+	transactionID := transactionGenerator()
+
+	newBalance := loan.OutstandingBalance - allocation.TotalPrincipal()
+
 	payment := &db.LoanPayment{
-		LoanID:          req.LoanID,
-		TransactionID:   transactionID,
-		Amount:          req.Amount,
-		PrincipalAmount: req.PrincipalAmount,
-		InterestAmount:  req.InterestAmount,
-		BalanceAfter:    newBalance,
-		Status:          "completed",
-		PaymentDate:     time.Now().Unix(),
+		LoanID:           loan.ID,
+		TransactionID:    transactionID,
+		Amount:           amount,
+		PrincipalAmount:  allocation.TotalPrincipal(),
+		InterestAmount:   allocation.Interest,
+		PenaltyAmount:    allocation.Penalties,
+		FeeAmount:        allocation.Fees,
+		PrepaymentAmount: allocation.Prepayment,
+		CreditAmount:     allocation.Credit,
+		BalanceAfter:     newBalance,
+		Status:           "completed",
+		PaymentDate:      now.Unix(),
 	}
 
 	if err := db.DB.Create(payment).Error; err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to create payment"})
+		return nil, allocation, fmt.Errorf("failed to create payment: %w", err)
 	}
 
 	transaction := &db.Transaction{
 		TransactionID: transactionID,
 		Type:          "loan_payment",
-		FromAccount:   fmt.Sprintf("USER-%d", user.ID),
+		FromAccount:   fmt.Sprintf("USER-%d", payerID),
 		ToAccount:     "BANK",
-		Amount:        req.Amount,
+		Amount:        amount,
 		Status:        "completed",
-		Description:   fmt.Sprintf("Loan payment for loan #%d", req.LoanID),
+		Description:   description,
 	}
 
 	if err := db.DB.Create(transaction).Error; err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to create transaction"})
+		return nil, allocation, fmt.Errorf("failed to create transaction: %w", err)
 	}
 
 	if _, err := db.CreateBlockForTransaction(transactionID); err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to create blockchain entry"})
+		return nil, allocation, fmt.Errorf("failed to create blockchain entry: %w", err)
 	}
 
-	if err := db.DB.Model(&db.Loan{}).Where("id = ?", req.LoanID).Update("outstanding_balance", newBalance).Error; err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update loan balance"})
+	if err := db.DB.Model(&db.Loan{}).Where("id = ?", loan.ID).Update("outstanding_balance", newBalance).Error; err != nil {
+		return nil, allocation, fmt.Errorf("failed to update loan balance: %w", err)
 	}
 
-	if newBalance == 0 {
-		paidOffAt := time.Now().Unix()
-		db.DB.Model(&db.Loan{}).Where("id = ?", req.LoanID).Updates(map[string]interface{}{
-			"status":      "PaidOff",
-			"paid_off_at": paidOffAt,
-		})
+	if err := settleLoanCharges(charges, allocation.Penalties, allocation.Fees); err != nil {
+		return nil, allocation, fmt.Errorf("failed to settle loan fees: %w", err)
 	}
 
-	return c.JSON(http.StatusOK, MakePaymentResponse{
-		OK:            true,
-		TransactionID: transactionID,
-		BalanceAfter:  newBalance,
-	})
+	if allocation.Credit > 0 {
+		if err := depositRepoHandler.UpdateUserBalance(payerID, allocation.Credit); err != nil {
+			return nil, allocation, fmt.Errorf("failed to credit overpayment: %w", err)
+		}
+	}
+
+	if newBalance == 0 && allocation.Settles(dues) {
+		loan.OutstandingBalance = newBalance
+		actorID := payerID
+		if _, err := changeLoanStatus(loan, lifecycle.StatusPaidOff, &actorID, "Paid in full", nil, map[string]interface{}{
+			"paid_off_at": now.Unix(),
+		}); err != nil {
+			log.Printf("WARNING: Failed to mark loan %d as paid off: %v", loan.ID, err)
+		}
+	}
+
+	sendPaymentConfirmation(loan, payment, now)
+
+	return payment, allocation, nil
 }
@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"backend/src/amortization"
+	"backend/src/db"
+	"backend/src/lifecycle"
+	"backend/src/repos"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+var payoffRepo = repos.PayoffRepo{}
+
+type PayoffQuoteResponse struct {
+	QuoteID           uint   `json:"quote_id" example:"1"`
+	LoanID            uint   `json:"loan_id" example:"1"`
+	SettlementDate    string `json:"settlement_date" example:"2025-06-30"`
+	ExpiresAt         string `json:"expires_at" example:"2025-07-01T00:00:00Z"`
+	Principal         int    `json:"outstanding_principal" example:"50000"`
+	AccruedInterest   int    `json:"accrued_interest" example:"350"`
+	Penalties         int    `json:"penalties" example:"0"`
+	Fees              int    `json:"fees" example:"500"`
+	PrepaymentPenalty int    `json:"prepayment_penalty" example:"1000"`
+	InterestRebate    int    `json:"interest_rebate" example:"2650"`
+	Total             int    `json:"total" example:"51850"`
+	Status            string `json:"status" example:"issued"`
+}
+
+func toPayoffQuoteResponse(quote *db.PayoffQuote) PayoffQuoteResponse {
+	return PayoffQuoteResponse{
+		QuoteID:           quote.ID,
+		LoanID:            quote.LoanID,
+		SettlementDate:    time.Unix(quote.SettlementDate, 0).Format("2006-01-02"),
+		ExpiresAt:         time.Unix(quote.ExpiresAt, 0).Format(time.RFC3339),
+		Principal:         quote.Principal,
+		AccruedInterest:   quote.Interest,
+		Penalties:         quote.Penalties,
+		Fees:              quote.Fees,
+		PrepaymentPenalty: quote.PrepaymentPenalty,
+		InterestRebate:    quote.InterestRebate,
+		Total:             quote.Total,
+		Status:            quote.Status,
+	}
+}
+
+// accruedInterest returns the interest earned by the given time that has not
+// been collected, and the scheduled interest settling then forgoes. Interest
+// accrues evenly over each installment period.
+func accruedInterest(installments []db.LoanInstallment, paidInterest int, at time.Time) (int, int) {
+	scheduled, accrued := 0, 0
+	var periodStart int64
+	for i, installment := range installments {
+		if i == 0 {
+			periodStart = time.Unix(installment.DueDate, 0).AddDate(0, -1, 0).Unix()
+		}
+		scheduled += installment.Interest
+		switch {
+		case installment.DueDate <= at.Unix():
+			accrued += installment.Interest
+		case at.Unix() > periodStart:
+			elapsed := float64(at.Unix()-periodStart) / float64(installment.DueDate-periodStart)
+			accrued += int(math.Round(float64(installment.Interest) * elapsed))
+		}
+		periodStart = installment.DueDate
+	}
+	return max(0, accrued-paidInterest), max(0, scheduled-max(accrued, paidInterest))
+}
+
+func payoffFeeKey(feeType *db.FeeType, loanID uint) string {
+	return fmt.Sprintf("%s:LOAN-%d", feeType.Code, loanID)
+}
+
+// payoffFees sums the active early settlement fees on the loan's balance.
+// A fee already billed, by an earlier settlement attempt or a top-up, is
+// among the loan's unpaid fees and is not counted again.
+func payoffFees(loan *db.Loan) (int, error) {
+	feeTypes, err := feeRepo.GetActiveTypesByTrigger(db.FeeTriggerOnPayoff)
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	for i := range feeTypes {
+		billed, err := feeRepo.ChargeExists(payoffFeeKey(&feeTypes[i], loan.ID))
+		if err != nil {
+			return 0, err
+		}
+		if !billed {
+			total += feeTypes[i].Compute(loan.OutstandingBalance)
+		}
+	}
+	return total, nil
+}
+
+// payoffQuote works out what settles the loan in full by the end of the
+// given day: the outstanding principal, interest accrued to then, unpaid
+// penalties and fees, and any early settlement fee. Scheduled interest that
+// has not accrued is rebated.
+func payoffQuote(loan *db.Loan, day, now time.Time) (*db.PayoffQuote, error) {
+	settlement := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	expiresAt := settlement.AddDate(0, 0, 1)
+
+	dues, _, err := loanDues(loan, now)
+	if err != nil {
+		return nil, err
+	}
+	installments, _, _, err := loanSchedule(loan)
+	if err != nil {
+		return nil, err
+	}
+	_, paidInterest, err := scheduleRepo.GetPaidSplit(loan.ID, scheduleStart(loan))
+	if err != nil {
+		return nil, err
+	}
+	interest, rebate := accruedInterest(installments, paidInterest, expiresAt)
+
+	penalty, err := payoffFees(loan)
+	if err != nil {
+		return nil, err
+	}
+
+	return &db.PayoffQuote{
+		LoanID:            loan.ID,
+		SettlementDate:    settlement.Unix(),
+		ExpiresAt:         expiresAt.Unix(),
+		Principal:         loan.OutstandingBalance,
+		Interest:          interest,
+		Penalties:         dues.Penalties,
+		Fees:              dues.Fees,
+		PrepaymentPenalty: penalty,
+		InterestRebate:    rebate,
+		Total:             loan.OutstandingBalance + interest + dues.Penalties + dues.Fees + penalty,
+		Status:            db.PayoffQuoteIssued,
+	}, nil
+}
+
+// checkPayoffQuote reports why a payment cannot settle the loan with the
+// quote. The quote is recomputed so a loan that has changed since it was
+// issued is not closed on stale figures.
+func checkPayoffQuote(loan *db.Loan, quote *db.PayoffQuote, amount int, now time.Time) (string, error) {
+	if quote.Status != db.PayoffQuoteIssued {
+		return "Payoff quote has already been used", nil
+	}
+	if now.Unix() >= quote.ExpiresAt {
+		return fmt.Sprintf("Payoff quote expired at %s; request a new quote", time.Unix(quote.ExpiresAt, 0).Format(time.RFC3339)), nil
+	}
+	if amount != quote.Total {
+		return fmt.Sprintf("Pay the quoted amount of %d to settle the loan", quote.Total), nil
+	}
+
+	current, err := payoffQuote(loan, time.Unix(quote.SettlementDate, 0), now)
+	if err != nil {
+		return "", err
+	}
+	if current.Total != quote.Total {
+		return "The loan has changed since the quote was issued; request a new quote", nil
+	}
+	return "", nil
+}
+
+// quoteDues is what a payoff quote settles: everything at once, including
+// interest accrued on installments not yet due.
+func quoteDues(quote *db.PayoffQuote) amortization.Dues {
+	return amortization.Dues{
+		Penalties:   quote.Penalties,
+		Fees:        quote.Fees + quote.PrepaymentPenalty,
+		Interest:    quote.Interest,
+		Principal:   quote.Principal,
+		Outstanding: quote.Principal,
+	}
+}
+
+// chargePayoffFees bills the active early settlement fees on the loan so the
+// settling payment collects them.
+func chargePayoffFees(loan *db.Loan) error {
+	feeTypes, err := feeRepo.GetActiveTypesByTrigger(db.FeeTriggerOnPayoff)
+	if err != nil {
+		return err
+	}
+	for i := range feeTypes {
+		feeType := &feeTypes[i]
+		loanID := loan.ID
+		description := fmt.Sprintf("%s for loan #%d", feeType.Name, loan.ID)
+		if _, err := chargeFee(feeType, loan.BorrowerID, &loanID, payoffFeeKey(feeType, loan.ID), loan.OutstandingBalance, description); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetPayoffQuote godoc
+// @Summary Get an early payoff quote
+// @Description Quotes the exact amount that settles the loan in full on the given date (default today): outstanding principal, interest accrued to that date, unpaid penalties and fees, and the early settlement fee when one is active. Scheduled interest that has not accrued is rebated. The quote expires at the end of the settlement date; paying its total with the quote ID closes the loan as PaidOff. Members may only quote their own loans.
+// @Tags loans
+// @Produce json
+// @Security SessionAuth
+// @Param id path int true "Loan ID"
+// @Param date query string false "Settlement date (YYYY-MM-DD)"
+// @Success 200 {object} PayoffQuoteResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/loans/{id}/payoff_quote [get]
+func GetPayoffQuote(c echo.Context) error {
+	user := c.Get("user").(*repos.UserWithSession)
+
+	loanID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid loan ID"})
+	}
+
+	now := time.Now()
+	day := now
+	if value := c.QueryParam("date"); value != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid date, expected YYYY-MM-DD"})
+		}
+		if parsed.Before(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)) {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Settlement date cannot be in the past"})
+		}
+		day = parsed
+	}
+
+	loan, err := loanRepoHandler.GetByID(uint(loanID))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Loan not found"})
+	}
+
+	if user.Role == "member" && loan.BorrowerID != user.ID {
+		return c.JSON(http.StatusForbidden, ErrorResponse{Error: "Not authorized to view this loan"})
+	}
+
+	if !lifecycle.IsActive(loan.Status) || loan.OutstandingBalance <= 0 {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Loan is not active"})
+	}
+
+	quote, err := payoffQuote(loan, day, now)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to calculate payoff amount"})
+	}
+	quote.RequestedByID = user.ID
+	if err := payoffRepo.CreateQuote(quote); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to save payoff quote"})
+	}
+
+	return c.JSON(http.StatusOK, toPayoffQuoteResponse(quote))
+}
@@ -0,0 +1,523 @@
+package handlers
+
+import (
+	"backend/src/db"
+	"backend/src/lifecycle"
+	"backend/src/payroll"
+	"backend/src/repos"
+	"backend/src/tabular"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const MaxPayrollFileBytes = 10 << 20
+
+var payrollRepo = repos.PayrollRepo{}
+
+type PayrollRowItem struct {
+	ID                     uint   `json:"id" example:"1"`
+	LineNumber             int    `json:"line_number" example:"2"`
+	MemberID               *uint  `json:"member_id,omitempty" example:"12"`
+	MemberName             string `json:"member_name,omitempty" example:"John Doe"`
+	Phone                  string `json:"phone,omitempty" example:"+919876543210"`
+	LoanID                 *uint  `json:"loan_id,omitempty" example:"7"`
+	RepaymentAmount        int    `json:"repayment_amount" example:"4500"`
+	SavingsAmount          int    `json:"savings_amount" example:"1000"`
+	Reference              string `json:"reference,omitempty" example:"EMP-0042"`
+	Status                 string `json:"status" example:"valid"`
+	Error                  string `json:"error,omitempty" example:"Member has no active loan"`
+	RepaymentTransactionID string `json:"repayment_transaction_id,omitempty" example:"TXN-1234567890"`
+	DepositTransactionID   string `json:"deposit_transaction_id,omitempty" example:"TXN-1234567891"`
+}
+
+type PayrollBatchItem struct {
+	ID             uint         `json:"id" example:"1"`
+	Filename       string       `json:"filename" example:"acme-november.xlsx"`
+	Format         string       `json:"format" example:"xlsx"`
+	FileHash       string       `json:"file_hash" example:"9f86d081884c7d65..."`
+	Employer       string       `json:"employer,omitempty" example:"Acme Ltd"`
+	Status         string       `json:"status" example:"previewed"`
+	UploadedBy     ManagerInfo  `json:"uploaded_by"`
+	PostedBy       *ManagerInfo `json:"posted_by,omitempty"`
+	PostedAt       string       `json:"posted_at,omitempty" example:"2025-12-01T10:05:00Z"`
+	RowCount       int          `json:"row_count" example:"42"`
+	PostedCount    int          `json:"posted_count" example:"40"`
+	FailedCount    int          `json:"failed_count" example:"2"`
+	RepaymentTotal int          `json:"repayment_total" example:"180000"`
+	SavingsTotal   int          `json:"savings_total" example:"42000"`
+	TransactionID  string       `json:"transaction_id,omitempty" example:"TXN-1234567890"`
+	CreatedAt      string       `json:"created_at" example:"2025-12-01T10:00:00Z"`
+}
+
+type PayrollBatchResponse struct {
+	Batch PayrollBatchItem `json:"batch"`
+	Rows  []PayrollRowItem `json:"rows"`
+}
+
+type PayrollBatchListResponse struct {
+	Batches []PayrollBatchItem `json:"batches"`
+}
+
+func toPayrollBatchItem(batch *db.PayrollBatch) PayrollBatchItem {
+	item := PayrollBatchItem{
+		ID:             batch.ID,
+		Filename:       batch.Filename,
+		Format:         batch.Format,
+		FileHash:       batch.FileHash,
+		Employer:       batch.Employer,
+		Status:         batch.Status,
+		UploadedBy:     ManagerInfo{ID: batch.UploadedBy.ID, Name: batch.UploadedBy.Name},
+		RowCount:       batch.RowCount,
+		PostedCount:    batch.PostedCount,
+		FailedCount:    batch.FailedCount,
+		RepaymentTotal: batch.RepaymentTotal,
+		SavingsTotal:   batch.SavingsTotal,
+		TransactionID:  batch.TransactionID,
+		CreatedAt:      batch.CreatedAt.Format(time.RFC3339),
+	}
+	if batch.PostedBy != nil {
+		item.PostedBy = &ManagerInfo{ID: batch.PostedBy.ID, Name: batch.PostedBy.Name}
+	}
+	if batch.PostedAt != nil {
+		item.PostedAt = time.Unix(*batch.PostedAt, 0).Format(time.RFC3339)
+	}
+	return item
+}
+
+func toPayrollRowItem(row *db.PayrollBatchRow) PayrollRowItem {
+	item := PayrollRowItem{
+		ID:                     row.ID,
+		LineNumber:             row.LineNumber,
+		MemberID:               row.MemberID,
+		Phone:                  row.Phone,
+		LoanID:                 row.LoanID,
+		RepaymentAmount:        row.RepaymentAmount,
+		SavingsAmount:          row.SavingsAmount,
+		Reference:              row.Reference,
+		Status:                 row.Status,
+		Error:                  row.Error,
+		RepaymentTransactionID: row.RepaymentTransactionID,
+		DepositTransactionID:   row.DepositTransactionID,
+	}
+	if row.Member != nil {
+		item.MemberName = row.Member.Name
+	}
+	return item
+}
+
+func toPayrollBatchResponse(batch *db.PayrollBatch) PayrollBatchResponse {
+	response := PayrollBatchResponse{
+		Batch: toPayrollBatchItem(batch),
+		Rows:  []PayrollRowItem{},
+	}
+	for i := range batch.Rows {
+		response.Rows = append(response.Rows, toPayrollRowItem(&batch.Rows[i]))
+	}
+	return response
+}
+
+// checkPayrollRow resolves the member a remittance row is for and, when it
+// carries a repayment, the loan it pays, and reports why the row cannot be
+// posted. Without a loan ID the repayment goes to the member's only active
+// loan.
+func checkPayrollRow(row *db.PayrollBatchRow) (*db.User, *db.Loan, string, error) {
+	if row.RepaymentAmount < 0 || row.SavingsAmount < 0 {
+		return nil, nil, "Amounts cannot be negative", nil
+	}
+	if row.RepaymentAmount == 0 && row.SavingsAmount == 0 {
+		return nil, nil, "Row has no repayment or savings amount", nil
+	}
+
+	var member *db.User
+	var err error
+	switch {
+	case row.MemberID != nil:
+		if member, err = userRepoHandler.GetByID(*row.MemberID); err != nil {
+			return nil, nil, fmt.Sprintf("Member #%d not found", *row.MemberID), nil
+		}
+		if row.Phone != "" && member.PhoneNumber != row.Phone {
+			return nil, nil, fmt.Sprintf("Phone number %s does not belong to member #%d", row.Phone, member.ID), nil
+		}
+	case row.Phone != "":
+		if member, err = userRepoHandler.FindByPhoneNumber(row.Phone); err != nil {
+			return nil, nil, fmt.Sprintf("No member with phone number %s", row.Phone), nil
+		}
+	default:
+		return nil, nil, "Row does not identify a member", nil
+	}
+	if member.Role != "member" || !member.IsActive {
+		return nil, nil, fmt.Sprintf("User #%d is not an active member", member.ID), nil
+	}
+
+	if row.RepaymentAmount == 0 {
+		return member, nil, "", nil
+	}
+
+	loanID := uint(0)
+	if row.LoanID != nil {
+		loanID = *row.LoanID
+	} else {
+		loans, err := loanRepoHandler.GetOpenByBorrower(member.ID)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		var active []uint
+		for _, loan := range loans {
+			if lifecycle.IsActive(loan.Status) {
+				active = append(active, loan.ID)
+			}
+		}
+		switch len(active) {
+		case 0:
+			return member, nil, fmt.Sprintf("Member #%d has no active loan to repay", member.ID), nil
+		case 1:
+			loanID = active[0]
+		default:
+			return member, nil, fmt.Sprintf("Member #%d has %d active loans; the row needs a loan ID", member.ID, len(active)), nil
+		}
+	}
+
+	loan, err := loanRepoHandler.GetByID(loanID)
+	if err != nil {
+		return member, nil, fmt.Sprintf("Loan #%d not found", loanID), nil
+	}
+	if loan.BorrowerID != member.ID {
+		return member, nil, fmt.Sprintf("Loan #%d does not belong to member #%d", loan.ID, member.ID), nil
+	}
+	if !lifecycle.IsActive(loan.Status) {
+		return member, nil, fmt.Sprintf("Loan #%d is not active", loan.ID), nil
+	}
+	return member, loan, "", nil
+}
+
+// ImportPayrollBatch godoc
+// @Summary Import a payroll deduction batch (manager)
+// @Description Uploads an employer remittance as CSV or XLSX with one row per member, identified by member_id or phone, with a repayment amount, a savings amount, or both, and optionally the loan_id to repay. Every row is checked against members and active loans and the batch is saved as a preview; nothing is posted until the batch is posted. Rows that cannot be read or fail a check are reported with the reason.
+// @Tags payroll
+// @Accept multipart/form-data
+// @Produce json
+// @Security SessionAuth
+// @Param file formData file true "Remittance file"
+// @Param format formData string false "Format (csv, xlsx); detected from the file when omitted"
+// @Param decimal formData string false "Decimal mark of the amounts (. or ,); worked out from the file when omitted, and amounts that could be read either way are reported on their row"
+// @Param employer formData string false "Employer that sent the remittance"
+// @Success 200 {object} PayrollBatchResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/payroll/batches [post]
+func ImportPayrollBatch(c echo.Context) error {
+	user := c.Get("user").(*repos.UserWithSession)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Remittance file is required"})
+	}
+	if fileHeader.Size > MaxPayrollFileBytes {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Remittance file is too large"})
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Failed to read remittance file"})
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, MaxPayrollFileBytes))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Failed to read remittance file"})
+	}
+
+	sum := sha256.Sum256(data)
+	fileHash := hex.EncodeToString(sum[:])
+	if existing, err := payrollRepo.FindBatchByHash(fileHash); err == nil {
+		return c.JSON(http.StatusConflict, ErrorResponse{Error: fmt.Sprintf("Remittance already imported as batch #%d", existing.ID)})
+	}
+
+	format := strings.ToLower(c.FormValue("format"))
+	if format == "" {
+		format = payroll.DetectFormat(fileHeader.Filename, data)
+	}
+
+	decimal := c.FormValue("decimal")
+	if err := tabular.CheckDecimal(decimal); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	parsed, err := payroll.Parse(format, data, decimal)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+	if len(parsed) == 0 {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Remittance contains no rows"})
+	}
+
+	batch := &db.PayrollBatch{
+		Filename:     fileHeader.Filename,
+		Format:       format,
+		FileHash:     fileHash,
+		Employer:     strings.TrimSpace(c.FormValue("employer")),
+		Status:       repos.PayrollBatchPreviewed,
+		UploadedByID: user.ID,
+		RowCount:     len(parsed),
+	}
+	members := map[int]*db.User{}
+	for _, line := range parsed {
+		row := db.PayrollBatchRow{
+			LineNumber:      line.Line,
+			Phone:           line.Phone,
+			RepaymentAmount: line.Repayment,
+			SavingsAmount:   line.Savings,
+			Reference:       line.Reference,
+			Status:          repos.PayrollRowValid,
+			Error:           line.Error,
+		}
+		if line.MemberID != 0 {
+			memberID := line.MemberID
+			row.MemberID = &memberID
+		}
+		if line.LoanID != 0 {
+			loanID := line.LoanID
+			row.LoanID = &loanID
+		}
+
+		if row.Error == "" {
+			member, loan, problem, err := checkPayrollRow(&row)
+			if err != nil {
+				return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to check remittance rows"})
+			}
+			row.Error = problem
+			if member != nil {
+				row.MemberID = &member.ID
+				members[row.LineNumber] = member
+			}
+			if loan != nil {
+				row.LoanID = &loan.ID
+			}
+		}
+
+		if row.Error != "" {
+			row.Status = repos.PayrollRowInvalid
+			batch.FailedCount++
+		} else {
+			batch.RepaymentTotal += row.RepaymentAmount
+			batch.SavingsTotal += row.SavingsAmount
+		}
+		batch.Rows = append(batch.Rows, row)
+	}
+
+	if err := payrollRepo.CreateBatch(batch); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to save payroll batch"})
+	}
+	batch.UploadedBy = db.User{Name: user.Name}
+	batch.UploadedBy.ID = user.ID
+	for i := range batch.Rows {
+		batch.Rows[i].Member = members[batch.Rows[i].LineNumber]
+	}
+
+	return c.JSON(http.StatusOK, toPayrollBatchResponse(batch))
+}
+
+// ListPayrollBatches godoc
+// @Summary List payroll deduction batches
+// @Description Returns every imported payroll batch with its status, row counts and totals
+// @Tags payroll
+// @Produce json
+// @Security SessionAuth
+// @Success 200 {object} PayrollBatchListResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/payroll/batches [get]
+func ListPayrollBatches(c echo.Context) error {
+	batches, err := payrollRepo.GetBatches()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch payroll batches"})
+	}
+
+	response := PayrollBatchListResponse{Batches: []PayrollBatchItem{}}
+	for i := range batches {
+		response.Batches = append(response.Batches, toPayrollBatchItem(&batches[i]))
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// GetPayrollBatch godoc
+// @Summary Get a payroll deduction batch
+// @Description Returns a payroll batch with every row, its status and, for rows that were not posted, the reason
+// @Tags payroll
+// @Produce json
+// @Security SessionAuth
+// @Param id path int true "Batch ID"
+// @Success 200 {object} PayrollBatchResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/v1/payroll/batches/{id} [get]
+func GetPayrollBatch(c echo.Context) error {
+	batchID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid batch ID"})
+	}
+
+	batch, err := payrollRepo.GetBatch(uint(batchID))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Payroll batch not found"})
+	}
+
+	return c.JSON(http.StatusOK, toPayrollBatchResponse(batch))
+}
+
+// PostPayrollBatch godoc
+// @Summary Post a payroll deduction batch (manager)
+// @Description Posts every valid row of a previewed batch: the repayment is applied to the loan like a member payment and the savings amount is deposited. Each row is checked again first, and a row that fails, or whose posting fails, is reported with the reason while the other rows are still posted. The batch, with a hash of what each row posted, is anchored as a payroll_batch transaction. A batch can only be posted once.
+// @Tags payroll
+// @Produce json
+// @Security SessionAuth
+// @Param id path int true "Batch ID"
+// @Success 200 {object} PayrollBatchResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/payroll/batches/{id}/post [post]
+func PostPayrollBatch(c echo.Context) error {
+	user := c.Get("user").(*repos.UserWithSession)
+
+	batchID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid batch ID"})
+	}
+
+	batch, err := payrollRepo.GetBatch(uint(batchID))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Payroll batch not found"})
+	}
+
+	now := time.Now()
+	claimed, err := payrollRepo.ClaimBatch(batch.ID, user.ID, now.Unix())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to post payroll batch"})
+	}
+	if !claimed {
+		return c.JSON(http.StatusConflict, ErrorResponse{Error: fmt.Sprintf("Payroll batch #%d has already been posted", batch.ID)})
+	}
+
+	postedCount, failedCount, repaymentTotal, savingsTotal := 0, 0, 0, 0
+	var postDigest strings.Builder
+	for i := range batch.Rows {
+		row := &batch.Rows[i]
+		if row.Status != repos.PayrollRowValid {
+			failedCount++
+			continue
+		}
+
+		member, loan, problem, err := checkPayrollRow(row)
+		if err != nil {
+			problem = "Failed to check row"
+			log.Printf("ERROR: Failed to check payroll batch %d row %d: %v", batch.ID, row.LineNumber, err)
+		}
+		if problem == "" && loan != nil {
+			dues, charges, err := loanDues(loan, now)
+			if err != nil {
+				problem = "Failed to calculate amount due"
+			} else if payment, _, err := applyLoanPayment(loan, member.ID, row.RepaymentAmount, dues, charges,
+				fmt.Sprintf("Payroll batch #%d line %d: loan payment for loan #%d", batch.ID, row.LineNumber, loan.ID), now); errors.Is(err, errEarlySettlement) {
+				problem = fmt.Sprintf("Repayment would settle loan #%d early; settle it with a payoff quote", loan.ID)
+			} else if err != nil {
+				log.Printf("ERROR: Failed to post payroll batch %d row %d repayment: %v", batch.ID, row.LineNumber, err)
+				problem = "Failed to post repayment"
+			} else {
+				row.RepaymentTransactionID = payment.TransactionID
+				row.LoanID = &loan.ID
+			}
+		}
+		if problem == "" && row.SavingsAmount > 0 {
+			description := fmt.Sprintf("Payroll batch #%d line %d: savings deposit", batch.ID, row.LineNumber)
+			if row.Reference != "" {
+				description += fmt.Sprintf(" (%s)", row.Reference)
+			}
+			transactionID, err := recordDeposit(member.ID, row.SavingsAmount, fmt.Sprintf("PAYROLL-%d", batch.ID), description)
+			if err != nil {
+				log.Printf("ERROR: Failed to post payroll batch %d row %d deposit: %v", batch.ID, row.LineNumber, err)
+				problem = "Failed to post savings deposit"
+				if row.RepaymentTransactionID != "" {
+					problem = "Repayment posted but the savings deposit failed"
+				}
+			} else {
+				row.DepositTransactionID = transactionID
+			}
+		}
+
+		row.Status = repos.PayrollRowPosted
+		row.Error = problem
+		if problem != "" {
+			row.Status = repos.PayrollRowFailed
+			failedCount++
+		} else {
+			postedCount++
+			repaymentTotal += row.RepaymentAmount
+			savingsTotal += row.SavingsAmount
+			fmt.Fprintf(&postDigest, "%d:%s:%s;", row.LineNumber, row.RepaymentTransactionID, row.DepositTransactionID)
+		}
+		if err := payrollRepo.UpdateRow(row.ID, map[string]interface{}{
+			"status":                   row.Status,
+			"error":                    row.Error,
+			"loan_id":                  row.LoanID,
+			"repayment_transaction_id": row.RepaymentTransactionID,
+			"deposit_transaction_id":   row.DepositTransactionID,
+		}); err != nil {
+			log.Printf("WARNING: Failed to update payroll batch %d row %d: %v", batch.ID, row.LineNumber, err)
+		}
+	}
+
+	source := batch.Filename
+	if batch.Employer != "" {
+		source = fmt.Sprintf("%s from %s", batch.Filename, batch.Employer)
+	}
+	digest := sha256.Sum256([]byte(postDigest.String()))
+	transaction := &db.Transaction{
+		TransactionID: transactionGenerator(),
+		Type:          "payroll_batch",
+		FromAccount:   "BANK",
+		ToAccount:     fmt.Sprintf("PAYROLL-%d", batch.ID),
+		Amount:        0,
+		Status:        "completed",
+		Description: fmt.Sprintf("Payroll batch #%d (%s) posted by manager %d; file hash %s; %d of %d rows posted (repayments %d, savings %d; post hash %s), %d not posted",
+			batch.ID, source, user.ID, batch.FileHash, postedCount, batch.RowCount, repaymentTotal, savingsTotal, hex.EncodeToString(digest[:]), failedCount),
+	}
+	if err := anchorTransaction(transaction); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to record payroll batch"})
+	}
+
+	if err := payrollRepo.UpdateBatch(batch.ID, map[string]interface{}{
+		"posted_count":    postedCount,
+		"failed_count":    failedCount,
+		"repayment_total": repaymentTotal,
+		"savings_total":   savingsTotal,
+		"transaction_id":  transaction.TransactionID,
+	}); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update payroll batch"})
+	}
+
+	batch, err = payrollRepo.GetBatch(batch.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch payroll batch"})
+	}
+	return c.JSON(http.StatusOK, toPayrollBatchResponse(batch))
+}
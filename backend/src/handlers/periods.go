@@ -0,0 +1,453 @@
+package handlers
+
+import (
+	"backend/src/db"
+	"backend/src/repos"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+var fiscalPeriodRepo = repos.FiscalPeriodRepo{}
+
+type FiscalPeriodItem struct {
+	ID                   uint         `json:"id" example:"1"`
+	Year                 int          `json:"year" example:"2025"`
+	Month                int          `json:"month" example:"11"`
+	StartsAt             string       `json:"starts_at" example:"2025-11-01T00:00:00Z"`
+	EndsAt               string       `json:"ends_at" example:"2025-12-01T00:00:00Z"`
+	Status               string       `json:"status" example:"closed"`
+	ClosedBy             *ManagerInfo `json:"closed_by,omitempty"`
+	ClosedAt             string       `json:"closed_at,omitempty" example:"2025-12-02T09:00:00Z"`
+	ClosingTransactionID string       `json:"closing_transaction_id,omitempty" example:"TXN-1234567890"`
+	PeriodHash           string       `json:"period_hash,omitempty" example:"9f86d081884c7d65..."`
+	TransactionCount     int          `json:"transaction_count" example:"120"`
+}
+
+type FiscalPeriodListResponse struct {
+	Periods []FiscalPeriodItem `json:"periods"`
+}
+
+type ClosingBalanceItem struct {
+	Account string `json:"account" example:"loans_receivable"`
+	Balance int    `json:"balance" example:"50000"`
+}
+
+type ReopenRequestItem struct {
+	ID            uint        `json:"id" example:"1"`
+	PeriodID      uint        `json:"period_id" example:"1"`
+	RequestedBy   ManagerInfo `json:"requested_by"`
+	Reason        string      `json:"reason" example:"Late bank charge for November"`
+	Status        string      `json:"status" example:"pending"`
+	Comment       string      `json:"comment,omitempty" example:"Consent given"`
+	TransactionID string      `json:"transaction_id,omitempty" example:"TXN-1234567890"`
+	CreatedAt     string      `json:"created_at" example:"2025-12-03T10:00:00Z"`
+}
+
+type FiscalPeriodDetailResponse struct {
+	FiscalPeriodItem
+	ClosingBalances []ClosingBalanceItem `json:"closing_balances"`
+	ReopenRequests  []ReopenRequestItem  `json:"reopen_requests"`
+}
+
+type ClosePeriodRequest struct {
+	Year  int `json:"year" binding:"required" example:"2025"`
+	Month int `json:"month" example:"11"`
+}
+
+type ClosePeriodResponse struct {
+	OK                   bool   `json:"ok" example:"true"`
+	PeriodID             uint   `json:"period_id" example:"1"`
+	PeriodHash           string `json:"period_hash" example:"9f86d081884c7d65..."`
+	TransactionCount     int    `json:"transaction_count" example:"120"`
+	ClosingTransactionID string `json:"closing_transaction_id" example:"TXN-1234567890"`
+}
+
+type ReopenPeriodRequest struct {
+	Reason string `json:"reason" binding:"required" example:"Late bank charge for November"`
+}
+
+type ReopenPeriodResponse struct {
+	OK        bool `json:"ok" example:"true"`
+	RequestID uint `json:"request_id" example:"1"`
+}
+
+type DecideReopenRequest struct {
+	Approve bool   `json:"approve" example:"true"`
+	Comment string `json:"comment" example:"Consent given"`
+}
+
+type PendingReopenRequestsResponse struct {
+	Requests []ReopenRequestItem `json:"requests"`
+}
+
+func periodLabel(period *db.FiscalPeriod) string {
+	if period.Month == 0 {
+		return fmt.Sprintf("%d", period.Year)
+	}
+	return fmt.Sprintf("%d-%02d", period.Year, period.Month)
+}
+
+func toFiscalPeriodItem(period *db.FiscalPeriod) FiscalPeriodItem {
+	item := FiscalPeriodItem{
+		ID:                   period.ID,
+		Year:                 period.Year,
+		Month:                period.Month,
+		StartsAt:             time.Unix(period.StartsAt, 0).Format(time.RFC3339),
+		EndsAt:               time.Unix(period.EndsAt, 0).Format(time.RFC3339),
+		Status:               period.Status,
+		ClosingTransactionID: period.ClosingTransactionID,
+		PeriodHash:           period.PeriodHash,
+		TransactionCount:     period.TransactionCount,
+	}
+	if period.ClosedBy != nil {
+		item.ClosedBy = &ManagerInfo{ID: period.ClosedBy.ID, Name: period.ClosedBy.Name}
+	}
+	if period.ClosedAt != nil {
+		item.ClosedAt = time.Unix(*period.ClosedAt, 0).Format(time.RFC3339)
+	}
+	return item
+}
+
+func toReopenRequestItem(request *db.PeriodReopenRequest) ReopenRequestItem {
+	return ReopenRequestItem{
+		ID:       request.ID,
+		PeriodID: request.FiscalPeriodID,
+		RequestedBy: ManagerInfo{
+			ID:   request.RequestedBy.ID,
+			Name: request.RequestedBy.Name,
+		},
+		Reason:        request.Reason,
+		Status:        request.Status,
+		Comment:       request.Comment,
+		TransactionID: request.TransactionID,
+		CreatedAt:     request.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// ListFiscalPeriods godoc
+// @Summary List fiscal periods
+// @Description Returns all fiscal periods that have been opened or closed, newest first
+// @Tags periods
+// @Produce json
+// @Security SessionAuth
+// @Success 200 {object} FiscalPeriodListResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/periods [get]
+func ListFiscalPeriods(c echo.Context) error {
+	periods, err := fiscalPeriodRepo.GetAll()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch fiscal periods"})
+	}
+
+	items := make([]FiscalPeriodItem, len(periods))
+	for i := range periods {
+		items[i] = toFiscalPeriodItem(&periods[i])
+	}
+
+	return c.JSON(http.StatusOK, FiscalPeriodListResponse{Periods: items})
+}
+
+// GetFiscalPeriod godoc
+// @Summary Get fiscal period details
+// @Description Returns a fiscal period with its closing balances and reopen history
+// @Tags periods
+// @Produce json
+// @Security SessionAuth
+// @Param id path int true "Period ID"
+// @Success 200 {object} FiscalPeriodDetailResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/v1/periods/{id} [get]
+func GetFiscalPeriod(c echo.Context) error {
+	periodID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid period ID"})
+	}
+
+	period, err := fiscalPeriodRepo.GetByID(uint(periodID))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Fiscal period not found"})
+	}
+
+	response := FiscalPeriodDetailResponse{
+		FiscalPeriodItem: toFiscalPeriodItem(period),
+		ClosingBalances:  make([]ClosingBalanceItem, len(period.ClosingBalances)),
+		ReopenRequests:   make([]ReopenRequestItem, 0, len(period.ReopenRequests)),
+	}
+	for i, balance := range period.ClosingBalances {
+		response.ClosingBalances[i] = ClosingBalanceItem{Account: balance.Account, Balance: balance.Balance}
+	}
+	for i := range period.ReopenRequests {
+		response.ReopenRequests = append(response.ReopenRequests, toReopenRequestItem(&period.ReopenRequests[i]))
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// CloseFiscalPeriod godoc
+// @Summary Close a fiscal period (manager)
+// @Description Closes a month (1-12) or a full year (month 0). Stores closing balances, hashes every transaction in the period and anchors a period_close transaction. New postings dated inside a closed period are rejected.
+// @Tags periods
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param request body ClosePeriodRequest true "Close Period Request"
+// @Success 200 {object} ClosePeriodResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/periods/close [post]
+func CloseFiscalPeriod(c echo.Context) error {
+	user := c.Get("user").(*repos.UserWithSession)
+
+	var req ClosePeriodRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request"})
+	}
+
+	period, err := fiscalPeriodRepo.FindOrCreate(req.Year, req.Month)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	if period.Status == db.PeriodStatusClosed {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Fiscal period is already closed"})
+	}
+
+	if period.EndsAt > time.Now().Unix() {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Fiscal period has not ended yet"})
+	}
+
+	transactions, err := fiscalPeriodRepo.GetTransactionsInRange(period.StartsAt, period.EndsAt)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch period transactions"})
+	}
+
+	periodHash, err := db.HashTransactionSet(transactions)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to hash period transactions"})
+	}
+
+	accountBalances, err := fiscalPeriodRepo.GetAccountBalancesAt(period.EndsAt)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to compute closing balances"})
+	}
+
+	balances := make([]db.PeriodClosingBalance, len(accountBalances))
+	for i, balance := range accountBalances {
+		balances[i] = db.PeriodClosingBalance{
+			FiscalPeriodID: period.ID,
+			Account:        balance.Account,
+			Balance:        balance.Balance,
+		}
+	}
+
+	transactionID := transactionGenerator()
+	transaction := &db.Transaction{
+		TransactionID: transactionID,
+		Type:          "period_close",
+		FromAccount:   fmt.Sprintf("PERIOD-%s", periodLabel(period)),
+		ToAccount:     "CLOSED",
+		Amount:        0,
+		Status:        "completed",
+		Description: fmt.Sprintf("Fiscal period %s closed by manager %d; %d transactions; period hash %s",
+			periodLabel(period), user.ID, len(transactions), periodHash),
+	}
+	closedAt := time.Now().Unix()
+	closedByID := user.ID
+	period.ClosedByID = &closedByID
+	period.ClosedAt = &closedAt
+	period.ClosingTransactionID = transactionID
+	period.PeriodHash = periodHash
+	period.TransactionCount = len(transactions)
+
+	if err := fiscalPeriodRepo.MarkClosed(period, balances, transaction); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to close fiscal period"})
+	}
+
+	if _, err := db.CreateBlockForTransaction(transactionID); err != nil {
+		log.Printf("WARNING: Failed to create blockchain block for period close: %v", err)
+	}
+
+	return c.JSON(http.StatusOK, ClosePeriodResponse{
+		OK:                   true,
+		PeriodID:             period.ID,
+		PeriodHash:           periodHash,
+		TransactionCount:     len(transactions),
+		ClosingTransactionID: transactionID,
+	})
+}
+
+// RequestPeriodReopen godoc
+// @Summary Request reopening of a closed period (manager)
+// @Description Files a reopen request that takes effect only after an auditor consents
+// @Tags periods
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param id path int true "Period ID"
+// @Param request body ReopenPeriodRequest true "Reopen Period Request"
+// @Success 200 {object} ReopenPeriodResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/periods/{id}/reopen [post]
+func RequestPeriodReopen(c echo.Context) error {
+	user := c.Get("user").(*repos.UserWithSession)
+
+	periodID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid period ID"})
+	}
+
+	var req ReopenPeriodRequest
+	if err := c.Bind(&req); err != nil || req.Reason == "" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "A reason is required"})
+	}
+
+	period, err := fiscalPeriodRepo.GetByID(uint(periodID))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Fiscal period not found"})
+	}
+
+	if period.Status != db.PeriodStatusClosed {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Only closed periods can be reopened"})
+	}
+
+	for _, existing := range period.ReopenRequests {
+		if existing.Status == "pending" {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "A reopen request is already pending for this period"})
+		}
+	}
+
+	request := &db.PeriodReopenRequest{
+		FiscalPeriodID: period.ID,
+		RequestedByID:  user.ID,
+		Reason:         req.Reason,
+		Status:         "pending",
+	}
+	if err := fiscalPeriodRepo.CreateReopenRequest(request); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to create reopen request"})
+	}
+
+	return c.JSON(http.StatusOK, ReopenPeriodResponse{OK: true, RequestID: request.ID})
+}
+
+// GetPendingReopenRequests godoc
+// @Summary List pending period reopen requests (auditor)
+// @Description Returns reopen requests awaiting auditor consent
+// @Tags periods
+// @Produce json
+// @Security SessionAuth
+// @Success 200 {object} PendingReopenRequestsResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/periods/reopen_requests [get]
+func GetPendingReopenRequests(c echo.Context) error {
+	requests, err := fiscalPeriodRepo.GetPendingReopenRequests()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch reopen requests"})
+	}
+
+	items := make([]ReopenRequestItem, len(requests))
+	for i := range requests {
+		items[i] = toReopenRequestItem(&requests[i])
+	}
+
+	return c.JSON(http.StatusOK, PendingReopenRequestsResponse{Requests: items})
+}
+
+// DecidePeriodReopen godoc
+// @Summary Consent to or refuse a period reopen (auditor)
+// @Description Auditor approves or rejects a pending reopen request. The decision is anchored as a period_reopen transaction.
+// @Tags periods
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param id path int true "Reopen Request ID"
+// @Param request body DecideReopenRequest true "Decision"
+// @Success 200 {object} OKResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/periods/reopen_requests/{id}/decide [post]
+func DecidePeriodReopen(c echo.Context) error {
+	user := c.Get("user").(*repos.UserWithSession)
+
+	requestID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request ID"})
+	}
+
+	var req DecideReopenRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request"})
+	}
+
+	request, err := fiscalPeriodRepo.GetReopenRequest(uint(requestID))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Reopen request not found"})
+	}
+
+	if request.Status != "pending" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Reopen request has already been decided"})
+	}
+
+	decision := "rejected"
+	if req.Approve {
+		decision = "approved"
+	}
+
+	transactionID := transactionGenerator()
+	transaction := &db.Transaction{
+		TransactionID: transactionID,
+		Type:          "period_reopen",
+		FromAccount:   fmt.Sprintf("PERIOD-%s", periodLabel(&request.FiscalPeriod)),
+		ToAccount:     decision,
+		Amount:        0,
+		Status:        "completed",
+		Description: fmt.Sprintf("Reopen of fiscal period %s requested by manager %d %s by auditor %d: %s",
+			periodLabel(&request.FiscalPeriod), request.RequestedByID, decision, user.ID, request.Reason),
+	}
+	if err := anchorTransaction(transaction); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to record reopen decision"})
+	}
+
+	decidedAt := time.Now().Unix()
+	decidedByID := user.ID
+	request.Status = decision
+	request.DecidedByID = &decidedByID
+	request.DecidedAt = &decidedAt
+	request.Comment = req.Comment
+	request.TransactionID = transactionID
+	if err := fiscalPeriodRepo.SaveReopenRequest(request); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update reopen request"})
+	}
+
+	if req.Approve {
+		if err := fiscalPeriodRepo.Reopen(request.FiscalPeriodID); err != nil {
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to reopen fiscal period"})
+		}
+		log.Printf("Fiscal period %s reopened by auditor %d", periodLabel(&request.FiscalPeriod), user.ID)
+	}
+
+	return c.JSON(http.StatusOK, OKResponse{
+		OK:      true,
+		Message: fmt.Sprintf("Reopen request %s", decision),
+	})
+}
@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"backend/src/amortization"
+	"backend/src/db"
+	"backend/src/repos"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+var productRepo = repos.ProductRepo{}
+
+type LoanProductRequest struct {
+	Name               *string  `json:"name" example:"Emergency loan"`
+	Description        *string  `json:"description" example:"Short loans for urgent needs"`
+	InterestRate       *float64 `json:"interest_rate" example:"10"`
+	RepaymentMethod    *string  `json:"repayment_method" example:"flat"`
+	Durations          []int    `json:"durations" example:"3,6"`
+	MinAmount          *int     `json:"min_amount" example:"5000"`
+	MaxAmount          *int     `json:"max_amount" example:"50000"`
+	RequiredGuarantors *int     `json:"required_guarantors" example:"1"`
+	FeeCodes           []string `json:"fee_codes" example:"loan_processing"`
+	IsActive           *bool    `json:"is_active" example:"true"`
+}
+
+type LoanProductItem struct {
+	ID                 uint     `json:"id" example:"1"`
+	Name               string   `json:"name" example:"Emergency loan"`
+	Description        string   `json:"description,omitempty" example:"Short loans for urgent needs"`
+	InterestRate       float64  `json:"interest_rate" example:"10"`
+	RepaymentMethod    string   `json:"repayment_method" example:"flat"`
+	Durations          []int    `json:"durations" example:"3,6"`
+	MinAmount          int      `json:"min_amount" example:"5000"`
+	MaxAmount          int      `json:"max_amount" example:"50000"`
+	RequiredGuarantors int      `json:"required_guarantors" example:"1"`
+	FeeCodes           []string `json:"fee_codes" example:"loan_processing"`
+	IsActive           bool     `json:"is_active" example:"true"`
+}
+
+type LoanProductListResponse struct {
+	Products []LoanProductItem `json:"products"`
+}
+
+func toLoanProductItem(product *db.LoanProduct) LoanProductItem {
+	item := LoanProductItem{
+		ID:                 product.ID,
+		Name:               product.Name,
+		Description:        product.Description,
+		InterestRate:       product.InterestRate,
+		RepaymentMethod:    product.RepaymentMethod,
+		Durations:          product.DurationList(),
+		MinAmount:          product.MinAmount,
+		MaxAmount:          product.MaxAmount,
+		RequiredGuarantors: product.RequiredGuarantors,
+		FeeCodes:           []string{},
+		IsActive:           product.IsActive,
+	}
+	for _, fee := range product.Fees {
+		item.FeeCodes = append(item.FeeCodes, fee.Code)
+	}
+	return item
+}
+
+// productTerms snapshots the product as it stands so the loan keeps the
+// terms it was approved under when the product later changes.
+func productTerms(product *db.LoanProduct) string {
+	data, err := json.Marshal(toLoanProductItem(product))
+	if err != nil {
+		log.Printf("WARNING: Failed to snapshot loan product %d: %v", product.ID, err)
+		return ""
+	}
+	return string(data)
+}
+
+// loanProductTerms returns the product terms recorded on the loan at
+// approval, or nil when it has none.
+func loanProductTerms(loan *db.Loan) *LoanProductItem {
+	if loan.ProductTerms == "" {
+		return nil
+	}
+	var terms LoanProductItem
+	if err := json.Unmarshal([]byte(loan.ProductTerms), &terms); err != nil {
+		log.Printf("WARNING: Invalid product terms on loan %d: %v", loan.ID, err)
+		return nil
+	}
+	return &terms
+}
+
+// productTermsError returns why a loan of the given amount and duration does
+// not fit the product, or "" when it does.
+func productTermsError(product *db.LoanProduct, amount, duration int) string {
+	if !product.IsActive {
+		return fmt.Sprintf("%s is not currently offered", product.Name)
+	}
+	if durations := product.DurationList(); !slices.Contains(durations, duration) {
+		return fmt.Sprintf("%s is offered over %v months", product.Name, durations)
+	}
+	if amount < product.MinAmount {
+		return fmt.Sprintf("%s starts at %d", product.Name, product.MinAmount)
+	}
+	if product.MaxAmount > 0 && amount > product.MaxAmount {
+		return fmt.Sprintf("%s is limited to %d", product.Name, product.MaxAmount)
+	}
+	return ""
+}
+
+// applyProductRequest copies the given fields onto the product and returns a
+// message describing the first invalid one, or "" when the result is valid.
+func applyProductRequest(product *db.LoanProduct, req *LoanProductRequest) string {
+	if req.Name != nil {
+		product.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Description != nil {
+		product.Description = strings.TrimSpace(*req.Description)
+	}
+	if req.InterestRate != nil {
+		product.InterestRate = *req.InterestRate
+	}
+	if req.RepaymentMethod != nil {
+		product.RepaymentMethod = *req.RepaymentMethod
+	}
+	if req.Durations != nil {
+		product.SetDurations(req.Durations)
+	}
+	if req.MinAmount != nil {
+		product.MinAmount = *req.MinAmount
+	}
+	if req.MaxAmount != nil {
+		product.MaxAmount = *req.MaxAmount
+	}
+	if req.RequiredGuarantors != nil {
+		product.RequiredGuarantors = *req.RequiredGuarantors
+	}
+	if req.IsActive != nil {
+		product.IsActive = *req.IsActive
+	}
+
+	if product.Name == "" {
+		return "Name is required"
+	}
+	if product.InterestRate < 0 {
+		return "Interest rate cannot be negative"
+	}
+	if !amortization.IsValidMethod(product.RepaymentMethod) {
+		return "Repayment method must be 'flat' or 'reducing_balance'"
+	}
+	if slices.ContainsFunc(req.Durations, func(months int) bool { return months <= 0 }) {
+		return "Durations must be positive"
+	}
+	if len(product.DurationList()) == 0 {
+		return "At least one duration is required"
+	}
+	if product.MinAmount < 0 || product.MaxAmount < 0 {
+		return "Amounts cannot be negative"
+	}
+	if product.MaxAmount > 0 && product.MaxAmount < product.MinAmount {
+		return "Maximum amount cannot be below the minimum"
+	}
+	if product.RequiredGuarantors < 0 {
+		return "Required guarantors cannot be negative"
+	}
+
+	if req.FeeCodes != nil {
+		product.Fees = []db.FeeType{}
+		for _, code := range req.FeeCodes {
+			feeType, err := feeRepo.GetTypeByCode(code)
+			if err != nil {
+				return fmt.Sprintf("Unknown fee %s", code)
+			}
+			if feeType.TriggerRule != db.FeeTriggerOnApproval {
+				return fmt.Sprintf("Fee %s is not charged on approval", code)
+			}
+			product.Fees = append(product.Fees, *feeType)
+		}
+	}
+	return ""
+}
+
+// ListLoanProducts godoc
+// @Summary List loan products
+// @Description Returns the loan products with their rate, repayment method, durations, amount limits, required guarantors and approval fees. Members only see products on offer.
+// @Tags loan-products
+// @Produce json
+// @Security SessionAuth
+// @Success 200 {object} LoanProductListResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/loan_products [get]
+func ListLoanProducts(c echo.Context) error {
+	user := c.Get("user").(*repos.UserWithSession)
+
+	products, err := productRepo.GetAll(user.Role == "member")
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch loan products"})
+	}
+
+	items := []LoanProductItem{}
+	for i := range products {
+		items = append(items, toLoanProductItem(&products[i]))
+	}
+
+	return c.JSON(http.StatusOK, LoanProductListResponse{Products: items})
+}
+
+// CreateLoanProduct godoc
+// @Summary Create a loan product (manager)
+// @Description Adds a loan product. Name, interest rate and durations are required; the repayment method defaults to flat, a maximum amount of 0 means no limit and fee codes name the on-approval fees charged on its loans.
+// @Tags loan-products
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param request body LoanProductRequest true "Product terms"
+// @Success 200 {object} LoanProductItem
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/loan_products [post]
+func CreateLoanProduct(c echo.Context) error {
+	var req LoanProductRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request"})
+	}
+	if req.InterestRate == nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Interest rate is required"})
+	}
+
+	product := &db.LoanProduct{RepaymentMethod: amortization.MethodFlat, IsActive: true}
+	if message := applyProductRequest(product, &req); message != "" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: message})
+	}
+
+	if err := productRepo.Create(product); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to create loan product"})
+	}
+
+	return c.JSON(http.StatusOK, toLoanProductItem(product))
+}
+
+// UpdateLoanProduct godoc
+// @Summary Update a loan product (manager)
+// @Description Changes the terms of a loan product. Omitted fields are left unchanged; fee_codes replaces the product's fee list when given. Loans already approved keep the terms they were approved under.
+// @Tags loan-products
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param id path int true "Product ID"
+// @Param request body LoanProductRequest true "Product terms"
+// @Success 200 {object} LoanProductItem
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/loan_products/{id} [post]
+func UpdateLoanProduct(c echo.Context) error {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid product ID"})
+	}
+
+	var req LoanProductRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request"})
+	}
+
+	product, err := productRepo.GetByID(uint(productID))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Loan product not found"})
+	}
+
+	if message := applyProductRequest(product, &req); message != "" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: message})
+	}
+
+	if err := productRepo.Save(product); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update loan product"})
+	}
+
+	return c.JSON(http.StatusOK, toLoanProductItem(product))
+}
@@ -0,0 +1,574 @@
+package handlers
+
+import (
+	"backend/src/bankstatement"
+	"backend/src/db"
+	"backend/src/repos"
+	"backend/src/tabular"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	DefaultMatchWindowDays = 3
+	MaxStatementFileBytes  = 10 << 20
+)
+
+var reconciliationRepo = repos.ReconciliationRepo{}
+
+type ImportStatementResponse struct {
+	OK             bool   `json:"ok" example:"true"`
+	StatementID    uint   `json:"statement_id" example:"1"`
+	Format         string `json:"format" example:"csv"`
+	LineCount      int    `json:"line_count" example:"42"`
+	MatchedCount   int    `json:"matched_count" example:"38"`
+	SuggestedCount int    `json:"suggested_count" example:"2"`
+	TransactionID  string `json:"transaction_id" example:"TXN-1234567890"`
+}
+
+type BankStatementItem struct {
+	ID            uint        `json:"id" example:"1"`
+	Filename      string      `json:"filename" example:"november.csv"`
+	Format        string      `json:"format" example:"csv"`
+	FileHash      string      `json:"file_hash" example:"9f86d081884c7d65..."`
+	UploadedBy    ManagerInfo `json:"uploaded_by"`
+	LineCount     int         `json:"line_count" example:"42"`
+	MatchedCount  int         `json:"matched_count" example:"38"`
+	TransactionID string      `json:"transaction_id" example:"TXN-1234567890"`
+	CreatedAt     string      `json:"created_at" example:"2025-12-01T10:00:00Z"`
+}
+
+type BankStatementListResponse struct {
+	Statements []BankStatementItem `json:"statements"`
+}
+
+type StatementLineItem struct {
+	ID            uint              `json:"id" example:"10"`
+	StatementID   uint              `json:"statement_id" example:"1"`
+	Date          string            `json:"date" example:"2025-11-14T00:00:00Z"`
+	Amount        int               `json:"amount" example:"10000"`
+	Reference     string            `json:"reference" example:"BANK-TX-12345"`
+	Description   string            `json:"description" example:"NEFT JOHN DOE"`
+	Status        string            `json:"status" example:"suggested"`
+	Deposit       *ReconDepositItem `json:"deposit,omitempty"`
+	Note          string            `json:"note,omitempty" example:"Bank charges"`
+	TransactionID string            `json:"transaction_id,omitempty" example:"TXN-1234567890"`
+}
+
+type ReconDepositItem struct {
+	ID            uint   `json:"id" example:"5"`
+	TransactionID string `json:"transaction_id" example:"TXN-1234567890"`
+	UserID        uint   `json:"user_id" example:"1"`
+	UserName      string `json:"user_name" example:"John Doe"`
+	Amount        int    `json:"amount" example:"10000"`
+	Reference     string `json:"reference" example:"BANK-TX-12345"`
+	Date          string `json:"date" example:"2025-11-14T09:30:00Z"`
+}
+
+type ReconciliationWorklistResponse struct {
+	UnmatchedLines       []StatementLineItem `json:"unmatched_lines"`
+	SuggestedMatches     []StatementLineItem `json:"suggested_matches"`
+	UnreconciledDeposits []ReconDepositItem  `json:"unreconciled_deposits"`
+	UnmatchedLineTotal   int                 `json:"unmatched_line_total" example:"5000"`
+	UnreconciledTotal    int                 `json:"unreconciled_total" example:"7000"`
+}
+
+type MatchLineRequest struct {
+	DepositID uint   `json:"deposit_id" example:"5"`
+	Note      string `json:"note" example:"Confirmed with member"`
+}
+
+type LineActionRequest struct {
+	Reason string `json:"reason" binding:"required" example:"Bank charges, not a deposit"`
+}
+
+func toReconDepositItem(deposit *db.Deposit) *ReconDepositItem {
+	if deposit == nil {
+		return nil
+	}
+	return &ReconDepositItem{
+		ID:            deposit.ID,
+		TransactionID: deposit.TransactionID,
+		UserID:        deposit.UserID,
+		UserName:      deposit.User.Name,
+		Amount:        deposit.Amount,
+		Reference:     deposit.Reference,
+		Date:          deposit.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+func toStatementLineItem(line *db.BankStatementLine) StatementLineItem {
+	return StatementLineItem{
+		ID:            line.ID,
+		StatementID:   line.BankStatementID,
+		Date:          time.Unix(line.Date, 0).Format(time.RFC3339),
+		Amount:        line.Amount,
+		Reference:     line.Reference,
+		Description:   line.Description,
+		Status:        line.Status,
+		Deposit:       toReconDepositItem(line.Deposit),
+		Note:          line.Note,
+		TransactionID: line.TransactionID,
+	}
+}
+
+// ImportBankStatement godoc
+// @Summary Import a bank statement (manager)
+// @Description Uploads a CSV, OFX or camt.053 statement, matches credit lines to deposits by reference, amount and date window, and anchors the import
+// @Tags reconciliation
+// @Accept multipart/form-data
+// @Produce json
+// @Security SessionAuth
+// @Param file formData file true "Statement file"
+// @Param format formData string false "Format (csv, ofx, camt053); detected from the file when omitted"
+// @Param decimal formData string false "Decimal mark of CSV amounts (. or ,); worked out from the file when omitted, and amounts that could be read either way are rejected"
+// @Param window_days formData int false "Date window in days for matching (default 3)"
+// @Success 200 {object} ImportStatementResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/reconciliation/statements [post]
+func ImportBankStatement(c echo.Context) error {
+	user := c.Get("user").(*repos.UserWithSession)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Statement file is required"})
+	}
+	if fileHeader.Size > MaxStatementFileBytes {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Statement file is too large"})
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Failed to read statement file"})
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, MaxStatementFileBytes))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Failed to read statement file"})
+	}
+
+	sum := sha256.Sum256(data)
+	fileHash := hex.EncodeToString(sum[:])
+	if existing, err := reconciliationRepo.FindStatementByHash(fileHash); err == nil {
+		return c.JSON(http.StatusConflict, ErrorResponse{Error: fmt.Sprintf("Statement already imported as #%d", existing.ID)})
+	}
+
+	format := strings.ToLower(c.FormValue("format"))
+	if format == "" {
+		format = bankstatement.DetectFormat(fileHeader.Filename, data)
+	}
+
+	windowDays := DefaultMatchWindowDays
+	if raw := c.FormValue("window_days"); raw != "" {
+		if d, err := strconv.Atoi(raw); err == nil && d >= 0 {
+			windowDays = d
+		}
+	}
+	window := time.Duration(windowDays) * 24 * time.Hour
+
+	decimal := c.FormValue("decimal")
+	if err := tabular.CheckDecimal(decimal); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	parsed, err := bankstatement.Parse(format, data, decimal)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+	if len(parsed) == 0 {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Statement contains no lines"})
+	}
+
+	earliest, latest := parsed[0].Date, parsed[0].Date
+	for _, line := range parsed {
+		if line.Date.Before(earliest) {
+			earliest = line.Date
+		}
+		if line.Date.After(latest) {
+			latest = line.Date
+		}
+	}
+	from := earliest.Add(-window)
+	to := latest.Add(window + 24*time.Hour)
+
+	deposits, err := reconciliationRepo.GetUnreconciledDeposits(&from, &to)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch deposits"})
+	}
+
+	candidates := make([]bankstatement.Candidate, len(deposits))
+	for i, deposit := range deposits {
+		candidates[i] = bankstatement.Candidate{
+			ID:        deposit.ID,
+			Amount:    deposit.Amount,
+			Date:      deposit.CreatedAt,
+			Reference: deposit.Reference,
+		}
+	}
+	matches := bankstatement.MatchLines(parsed, candidates, window)
+
+	statement := &db.BankStatement{
+		Filename:     fileHeader.Filename,
+		Format:       format,
+		FileHash:     fileHash,
+		UploadedByID: user.ID,
+		LineCount:    len(parsed),
+	}
+	for i, line := range parsed {
+		statement.Lines = append(statement.Lines, db.BankStatementLine{
+			LineNumber:  i + 1,
+			Date:        line.Date.Unix(),
+			Amount:      line.Amount,
+			Reference:   line.Reference,
+			Description: line.Description,
+			Status:      repos.LineStatusUnmatched,
+		})
+	}
+
+	matchedCount, suggestedCount := 0, 0
+	matchedAt := time.Now().Unix()
+	var matchDigest strings.Builder
+	for _, match := range matches {
+		depositID := match.CandidateID
+		line := &statement.Lines[match.LineIndex]
+		line.DepositID = &depositID
+		if match.Confirmed {
+			line.Status = repos.LineStatusMatched
+			line.MatchedAt = &matchedAt
+			line.Note = "Auto-matched on reference, amount and date"
+			matchedCount++
+			fmt.Fprintf(&matchDigest, "%d:%d;", line.LineNumber, depositID)
+		} else {
+			line.Status = repos.LineStatusSuggested
+			line.Note = "Amount and date match; awaiting manager confirmation"
+			suggestedCount++
+		}
+	}
+	statement.MatchedCount = matchedCount
+
+	if err := reconciliationRepo.CreateStatement(statement); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to save statement"})
+	}
+
+	digest := sha256.Sum256([]byte(matchDigest.String()))
+	transactionID := transactionGenerator()
+	transaction := &db.Transaction{
+		TransactionID: transactionID,
+		Type:          "bank_statement_import",
+		FromAccount:   "BANK",
+		ToAccount:     fmt.Sprintf("STATEMENT-%d", statement.ID),
+		Amount:        0,
+		Status:        "completed",
+		Description: fmt.Sprintf("Bank statement %s (%s) imported by manager %d; file hash %s; %d lines, %d auto-matched (match hash %s), %d suggested",
+			fileHeader.Filename, format, user.ID, fileHash, len(parsed), matchedCount, hex.EncodeToString(digest[:]), suggestedCount),
+	}
+	if err := anchorTransaction(transaction); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to record statement import"})
+	}
+
+	if err := reconciliationRepo.UpdateStatementTransaction(statement.ID, transactionID); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to link statement import"})
+	}
+
+	return c.JSON(http.StatusOK, ImportStatementResponse{
+		OK:             true,
+		StatementID:    statement.ID,
+		Format:         format,
+		LineCount:      len(parsed),
+		MatchedCount:   matchedCount,
+		SuggestedCount: suggestedCount,
+		TransactionID:  transactionID,
+	})
+}
+
+// ListBankStatements godoc
+// @Summary List imported bank statements
+// @Description Returns every imported statement with its match counts
+// @Tags reconciliation
+// @Produce json
+// @Security SessionAuth
+// @Success 200 {object} BankStatementListResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/reconciliation/statements [get]
+func ListBankStatements(c echo.Context) error {
+	statements, err := reconciliationRepo.GetStatements()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch statements"})
+	}
+
+	items := make([]BankStatementItem, len(statements))
+	for i, statement := range statements {
+		items[i] = BankStatementItem{
+			ID:       statement.ID,
+			Filename: statement.Filename,
+			Format:   statement.Format,
+			FileHash: statement.FileHash,
+			UploadedBy: ManagerInfo{
+				ID:   statement.UploadedBy.ID,
+				Name: statement.UploadedBy.Name,
+			},
+			LineCount:     statement.LineCount,
+			MatchedCount:  statement.MatchedCount,
+			TransactionID: statement.TransactionID,
+			CreatedAt:     statement.CreatedAt.Format(time.RFC3339),
+		}
+	}
+
+	return c.JSON(http.StatusOK, BankStatementListResponse{Statements: items})
+}
+
+// GetReconciliationWorklist godoc
+// @Summary Get reconciliation worklist
+// @Description Returns unmatched statement lines, suggested matches awaiting confirmation and deposits not yet seen on any statement
+// @Tags reconciliation
+// @Produce json
+// @Security SessionAuth
+// @Success 200 {object} ReconciliationWorklistResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/reconciliation/worklist [get]
+func GetReconciliationWorklist(c echo.Context) error {
+	lines, err := reconciliationRepo.GetLinesByStatus(repos.LineStatusUnmatched, repos.LineStatusSuggested)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch statement lines"})
+	}
+
+	deposits, err := reconciliationRepo.GetUnreconciledDeposits(nil, nil)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch deposits"})
+	}
+
+	response := ReconciliationWorklistResponse{
+		UnmatchedLines:       []StatementLineItem{},
+		SuggestedMatches:     []StatementLineItem{},
+		UnreconciledDeposits: []ReconDepositItem{},
+	}
+	for i := range lines {
+		item := toStatementLineItem(&lines[i])
+		if lines[i].Status == repos.LineStatusSuggested {
+			response.SuggestedMatches = append(response.SuggestedMatches, item)
+			continue
+		}
+		response.UnmatchedLines = append(response.UnmatchedLines, item)
+		response.UnmatchedLineTotal += lines[i].Amount
+	}
+	for i := range deposits {
+		response.UnreconciledDeposits = append(response.UnreconciledDeposits, *toReconDepositItem(&deposits[i]))
+		response.UnreconciledTotal += deposits[i].Amount
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+func parseLineID(c echo.Context) (uint, error) {
+	lineID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	return uint(lineID), err
+}
+
+// MatchStatementLine godoc
+// @Summary Confirm or set a statement line match (manager)
+// @Description Confirms a suggested match, or matches the line to the given deposit. The match is anchored as a reconciliation_match transaction.
+// @Tags reconciliation
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param id path int true "Statement line ID"
+// @Param request body MatchLineRequest false "Deposit to match; omit to confirm the suggestion"
+// @Success 200 {object} OKResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/reconciliation/lines/{id}/match [post]
+func MatchStatementLine(c echo.Context) error {
+	user := c.Get("user").(*repos.UserWithSession)
+
+	lineID, err := parseLineID(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid line ID"})
+	}
+
+	var req MatchLineRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request"})
+	}
+
+	line, err := reconciliationRepo.GetLine(lineID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Statement line not found"})
+	}
+
+	if line.Status == repos.LineStatusMatched {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Statement line is already matched"})
+	}
+
+	depositID := req.DepositID
+	if depositID == 0 {
+		if line.DepositID == nil {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "No suggested deposit to confirm; deposit_id is required"})
+		}
+		depositID = *line.DepositID
+	}
+
+	deposit, err := reconciliationRepo.GetDeposit(depositID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Deposit not found"})
+	}
+
+	if deposit.Amount != line.Amount {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Deposit amount does not match statement line"})
+	}
+
+	reconciled, err := reconciliationRepo.IsDepositReconciled(deposit.ID, line.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to check deposit"})
+	}
+	if reconciled {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Deposit is already matched to another statement line"})
+	}
+
+	transactionID := transactionGenerator()
+	transaction := &db.Transaction{
+		TransactionID: transactionID,
+		Type:          "reconciliation_match",
+		FromAccount:   fmt.Sprintf("BANKLINE-%d", line.ID),
+		ToAccount:     deposit.TransactionID,
+		Amount:        0,
+		Status:        "completed",
+		Description: fmt.Sprintf("Statement line %d (ref %s, amount %d) matched to deposit %s by manager %d",
+			line.ID, line.Reference, line.Amount, deposit.TransactionID, user.ID),
+	}
+	if err := anchorTransaction(transaction); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to record match"})
+	}
+
+	matchedAt := time.Now().Unix()
+	matchedByID := user.ID
+	line.Status = repos.LineStatusMatched
+	line.DepositID = &deposit.ID
+	line.MatchedByID = &matchedByID
+	line.MatchedAt = &matchedAt
+	line.Note = req.Note
+	line.TransactionID = transactionID
+	if err := reconciliationRepo.SaveLine(line); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update statement line"})
+	}
+	reconciliationRepo.RefreshMatchedCount(line.BankStatementID)
+
+	return c.JSON(http.StatusOK, OKResponse{OK: true, Message: "Statement line matched"})
+}
+
+// UnmatchStatementLine godoc
+// @Summary Remove a statement line match (manager)
+// @Description Returns a matched or suggested line to the worklist. The change is anchored as a reconciliation_unmatch transaction.
+// @Tags reconciliation
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param id path int true "Statement line ID"
+// @Param request body LineActionRequest true "Reason"
+// @Success 200 {object} OKResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/reconciliation/lines/{id}/unmatch [post]
+func UnmatchStatementLine(c echo.Context) error {
+	return updateLineStatus(c, repos.LineStatusUnmatched, "reconciliation_unmatch")
+}
+
+// IgnoreStatementLine godoc
+// @Summary Mark a statement line as not a deposit (manager)
+// @Description Removes a line such as a bank charge from the worklist. The change is anchored as a reconciliation_ignore transaction.
+// @Tags reconciliation
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param id path int true "Statement line ID"
+// @Param request body LineActionRequest true "Reason"
+// @Success 200 {object} OKResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/reconciliation/lines/{id}/ignore [post]
+func IgnoreStatementLine(c echo.Context) error {
+	return updateLineStatus(c, repos.LineStatusIgnored, "reconciliation_ignore")
+}
+
+func updateLineStatus(c echo.Context, status, txType string) error {
+	user := c.Get("user").(*repos.UserWithSession)
+
+	lineID, err := parseLineID(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid line ID"})
+	}
+
+	var req LineActionRequest
+	if err := c.Bind(&req); err != nil || req.Reason == "" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "A reason is required"})
+	}
+
+	line, err := reconciliationRepo.GetLine(lineID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Statement line not found"})
+	}
+
+	if line.Status == status {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("Statement line is already %s", status)})
+	}
+
+	previous := "none"
+	if line.Deposit != nil {
+		previous = line.Deposit.TransactionID
+	}
+
+	transactionID := transactionGenerator()
+	transaction := &db.Transaction{
+		TransactionID: transactionID,
+		Type:          txType,
+		FromAccount:   fmt.Sprintf("BANKLINE-%d", line.ID),
+		ToAccount:     status,
+		Amount:        0,
+		Status:        "completed",
+		Description: fmt.Sprintf("Statement line %d (amount %d) changed from %s (deposit %s) to %s by manager %d: %s",
+			line.ID, line.Amount, line.Status, previous, status, user.ID, req.Reason),
+	}
+	if err := anchorTransaction(transaction); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to record reconciliation change"})
+	}
+
+	changedByID := user.ID
+	changedAt := time.Now().Unix()
+	line.Status = status
+	line.DepositID = nil
+	line.MatchedByID = &changedByID
+	line.MatchedAt = &changedAt
+	line.Note = req.Reason
+	line.TransactionID = transactionID
+	if err := reconciliationRepo.SaveLine(line); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update statement line"})
+	}
+	reconciliationRepo.RefreshMatchedCount(line.BankStatementID)
+
+	return c.JSON(http.StatusOK, OKResponse{OK: true, Message: fmt.Sprintf("Statement line %s", status)})
+}
@@ -0,0 +1,565 @@
+package handlers
+
+import (
+	"backend/src/db"
+	"backend/src/repos"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	MaxSMSAttempts       = 5
+	MaxReminderBodyChars = 480
+	smsRetryBackoff      = 15 * time.Minute
+	smsBatchSize         = 100
+	defaultQuietHours    = "21:00-08:00"
+)
+
+var reminderRepo = repos.ReminderRepo{}
+
+var (
+	reminderPlaceholder  = regexp.MustCompile(`\{([a-z_]+)\}`)
+	reminderPlaceholders = []string{"name", "loan_id", "installment", "amount", "due_date", "days", "balance", "author", "comment"}
+)
+
+type ReminderTemplateItem struct {
+	ID        uint         `json:"id" example:"1"`
+	Event     string       `json:"event" example:"installment_due"`
+	Body      string       `json:"body" example:"Dear {name}, installment {installment} of {amount} on loan #{loan_id} is due on {due_date}."`
+	Days      int          `json:"days" example:"3"`
+	IsActive  bool         `json:"is_active" example:"true"`
+	UpdatedBy *ManagerInfo `json:"updated_by,omitempty"`
+	UpdatedAt string       `json:"updated_at" example:"2025-01-15T10:30:00Z"`
+}
+
+type ReminderTemplateListResponse struct {
+	Templates    []ReminderTemplateItem `json:"templates"`
+	Placeholders []string               `json:"placeholders" example:"name,loan_id,amount"`
+	QuietHours   string                 `json:"quiet_hours" example:"21:00-08:00"`
+}
+
+type UpdateReminderTemplateRequest struct {
+	Body     *string `json:"body" example:"Dear {name}, installment {installment} of {amount} is due on {due_date}."`
+	Days     *int    `json:"days" example:"3"`
+	IsActive *bool   `json:"is_active" example:"true"`
+}
+
+type SMSMessageItem struct {
+	ID            uint         `json:"id" example:"1"`
+	Event         string       `json:"event" example:"installment_due"`
+	Member        BorrowerInfo `json:"member"`
+	LoanID        *uint        `json:"loan_id,omitempty" example:"1"`
+	PhoneNumber   string       `json:"phone_number" example:"+1234567890"`
+	Body          string       `json:"body" example:"Dear John, installment 2 of 9333 on loan #1 is due on 2025-03-15."`
+	Status        string       `json:"status" example:"sent"`
+	Attempts      int          `json:"attempts" example:"1"`
+	NextAttemptAt string       `json:"next_attempt_at,omitempty" example:"2025-03-12T08:00:00Z"`
+	LastError     string       `json:"last_error,omitempty" example:"TWILIO_PHONE_NUMBER not configured"`
+	SentAt        string       `json:"sent_at,omitempty" example:"2025-03-12T08:00:04Z"`
+	CreatedAt     string       `json:"created_at" example:"2025-03-12T08:00:00Z"`
+}
+
+type SMSMessageListResponse struct {
+	Messages []SMSMessageItem `json:"messages"`
+}
+
+type RunRemindersResponse struct {
+	OK     bool `json:"ok" example:"true"`
+	Queued int  `json:"queued" example:"12"`
+	Sent   int  `json:"sent" example:"10"`
+	Failed int  `json:"failed" example:"2"`
+}
+
+func toReminderTemplateItem(template *db.ReminderTemplate) ReminderTemplateItem {
+	item := ReminderTemplateItem{
+		ID:        template.ID,
+		Event:     template.Event,
+		Body:      template.Body,
+		Days:      template.Days,
+		IsActive:  template.IsActive,
+		UpdatedAt: template.UpdatedAt.Format(time.RFC3339),
+	}
+	if template.UpdatedBy != nil {
+		item.UpdatedBy = &ManagerInfo{ID: template.UpdatedBy.ID, Name: template.UpdatedBy.Name}
+	}
+	return item
+}
+
+func toSMSMessageItem(message *db.SMSMessage) SMSMessageItem {
+	item := SMSMessageItem{
+		ID:          message.ID,
+		Event:       message.Event,
+		Member:      BorrowerInfo{ID: message.User.ID, Name: message.User.Name, PhoneNumber: message.User.PhoneNumber},
+		LoanID:      message.LoanID,
+		PhoneNumber: message.PhoneNumber,
+		Body:        message.Body,
+		Status:      message.Status,
+		Attempts:    message.Attempts,
+		LastError:   message.LastError,
+		CreatedAt:   message.CreatedAt.Format(time.RFC3339),
+	}
+	if message.Status == db.SMSStatusPending {
+		item.NextAttemptAt = time.Unix(message.NextAttemptAt, 0).Format(time.RFC3339)
+	}
+	if message.SentAt != nil {
+		item.SentAt = time.Unix(*message.SentAt, 0).Format(time.RFC3339)
+	}
+	return item
+}
+
+// quietHours returns the configured SMS_QUIET_HOURS window as minutes after
+// midnight, local time. The window may wrap past midnight; "off" disables it.
+func quietHours() (int, int, bool) {
+	value := os.Getenv("SMS_QUIET_HOURS")
+	if value == "" {
+		value = defaultQuietHours
+	}
+	if value == "off" {
+		return 0, 0, false
+	}
+
+	parse := func(clock string) (int, error) {
+		t, err := time.Parse("15:04", strings.TrimSpace(clock))
+		if err != nil {
+			return 0, err
+		}
+		return t.Hour()*60 + t.Minute(), nil
+	}
+	bounds := strings.Split(value, "-")
+	if len(bounds) == 2 {
+		start, err1 := parse(bounds[0])
+		end, err2 := parse(bounds[1])
+		if err1 == nil && err2 == nil {
+			return start, end, start != end
+		}
+	}
+
+	log.Printf("WARNING: Invalid SMS_QUIET_HOURS %q, using %s", value, defaultQuietHours)
+	return 21 * 60, 8 * 60, true
+}
+
+func inQuietHours(now time.Time) bool {
+	start, end, ok := quietHours()
+	if !ok {
+		return false
+	}
+	minute := now.Hour()*60 + now.Minute()
+	if start < end {
+		return minute >= start && minute < end
+	}
+	return minute >= start || minute < end
+}
+
+func renderReminder(body string, values map[string]string) string {
+	return reminderPlaceholder.ReplaceAllStringFunc(body, func(match string) string {
+		if value, ok := values[match[1:len(match)-1]]; ok {
+			return value
+		}
+		return match
+	})
+}
+
+// queueReminder renders the event's template into the delivery log under
+// the given key. Keys make queueing idempotent, so each reminder is only
+// ever created once. It returns nil when the template is inactive or the
+// reminder already exists.
+func queueReminder(event, key string, member *db.User, loanID *uint, values map[string]string, now time.Time) (*db.SMSMessage, error) {
+	template, err := reminderRepo.GetTemplate(event)
+	if err != nil || !template.IsActive {
+		return nil, err
+	}
+
+	messageKey := fmt.Sprintf("%s:%s", event, key)
+	exists, err := reminderRepo.MessageExists(messageKey)
+	if err != nil || exists {
+		return nil, err
+	}
+
+	values["name"] = member.Name
+	message := &db.SMSMessage{
+		MessageKey:    messageKey,
+		Event:         event,
+		UserID:        member.ID,
+		LoanID:        loanID,
+		PhoneNumber:   member.PhoneNumber,
+		Body:          renderReminder(template.Body, values),
+		Status:        db.SMSStatusPending,
+		NextAttemptAt: now.Unix(),
+	}
+	if err := reminderRepo.CreateMessage(message); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// queueScheduleReminders walks the schedule of every active loan, applying
+// payments to installments oldest first, and queues a due reminder for the
+// next unpaid installment inside the lead window and an overdue notice for
+// each unpaid installment past the grace period.
+func queueScheduleReminders(now time.Time) (int, error) {
+	due, err := reminderRepo.GetTemplate(db.ReminderInstallmentDue)
+	if err != nil {
+		return 0, err
+	}
+	overdue, err := reminderRepo.GetTemplate(db.ReminderInstallmentOverdue)
+	if err != nil {
+		return 0, err
+	}
+	if !due.IsActive && !overdue.IsActive {
+		return 0, nil
+	}
+
+	loans, err := loanRepoHandler.GetActive()
+	if err != nil {
+		return 0, err
+	}
+
+	queued := 0
+	for i := range loans {
+		loan := &loans[i]
+		loanID := loan.ID
+		covered := schedulePaid(loan)
+		for _, installment := range loan.Installments {
+			if covered >= installment.Payment {
+				covered -= installment.Payment
+				continue
+			}
+			owed := installment.Payment - covered
+			covered = 0
+
+			dueDate := time.Unix(installment.DueDate, 0)
+			values := map[string]string{
+				"loan_id":     strconv.FormatUint(uint64(loan.ID), 10),
+				"installment": strconv.Itoa(installment.Number),
+				"amount":      strconv.Itoa(owed),
+				"due_date":    dueDate.Format("2006-01-02"),
+				"balance":     strconv.Itoa(loan.OutstandingBalance),
+			}
+
+			// Only the next unpaid installment gets a due reminder; every
+			// unpaid installment past the grace period gets an overdue notice.
+			var event string
+			if dueDate.After(now) {
+				untilDue := int(dueDate.Sub(now).Hours() / 24)
+				if due.IsActive && untilDue <= due.Days {
+					event = db.ReminderInstallmentDue
+					values["days"] = strconv.Itoa(untilDue)
+				}
+			} else {
+				pastDue := int(now.Sub(dueDate).Hours() / 24)
+				if overdue.IsActive && pastDue >= overdue.Days {
+					event = db.ReminderInstallmentOverdue
+					values["days"] = strconv.Itoa(pastDue)
+				}
+			}
+
+			if event != "" {
+				message, err := queueReminder(event, fmt.Sprintf("INSTALLMENT-%d", installment.ID), &loan.Borrower, &loanID, values, now)
+				if err != nil {
+					log.Printf("WARNING: Failed to queue %s for loan %d: %v", event, loan.ID, err)
+				} else if message != nil {
+					queued++
+				}
+			}
+			if dueDate.After(now) {
+				break
+			}
+		}
+	}
+	return queued, nil
+}
+
+// deliverSMS makes one delivery attempt. Failures are retried with a
+// doubling backoff until MaxSMSAttempts is reached.
+func deliverSMS(message *db.SMSMessage, now time.Time) error {
+	message.Attempts++
+	err := smsService.Send(message.PhoneNumber, message.Body)
+	if err == nil {
+		sentAt := now.Unix()
+		message.Status = db.SMSStatusSent
+		message.SentAt = &sentAt
+		message.LastError = ""
+	} else {
+		message.LastError = err.Error()
+		if message.Attempts >= MaxSMSAttempts {
+			message.Status = db.SMSStatusFailed
+		} else {
+			message.NextAttemptAt = now.Add(smsRetryBackoff << (message.Attempts - 1)).Unix()
+		}
+	}
+	if saveErr := reminderRepo.SaveMessage(message); saveErr != nil {
+		return saveErr
+	}
+	return err
+}
+
+// deliverPendingSMS sends queued messages that are due, outside quiet hours.
+func deliverPendingSMS(now time.Time) (int, int, error) {
+	if inQuietHours(now) {
+		return 0, 0, nil
+	}
+
+	messages, err := reminderRepo.GetDueMessages(now.Unix(), smsBatchSize)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	sent, failed := 0, 0
+	for i := range messages {
+		if err := deliverSMS(&messages[i], now); err != nil {
+			log.Printf("WARNING: Failed to send SMS %d: %v", messages[i].ID, err)
+			failed++
+			continue
+		}
+		sent++
+	}
+	return sent, failed, nil
+}
+
+// runReminders queues reminders that have fallen due and delivers pending
+// messages, including retries of earlier failures.
+func runReminders(now time.Time) (int, int, int, error) {
+	queued, err := queueScheduleReminders(now)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	sent, failed, err := deliverPendingSMS(now)
+	return queued, sent, failed, err
+}
+
+// sendPaymentConfirmation queues a confirmation for a loan payment and
+// tries to deliver it straight away unless it is quiet hours. Anything not
+// delivered now is picked up by the reminder job.
+func sendPaymentConfirmation(loan *db.Loan, payment *db.LoanPayment, now time.Time) {
+	loanID := loan.ID
+	message, err := queueReminder(db.ReminderPaymentConfirmed, payment.TransactionID, &loan.Borrower, &loanID, map[string]string{
+		"loan_id": strconv.FormatUint(uint64(loan.ID), 10),
+		"amount":  strconv.Itoa(payment.Amount),
+		"balance": strconv.Itoa(payment.BalanceAfter),
+	}, now)
+	if err != nil {
+		log.Printf("WARNING: Failed to queue payment confirmation for loan %d: %v", loan.ID, err)
+		return
+	}
+	if message == nil || inQuietHours(now) {
+		return
+	}
+	if err := deliverSMS(message, now); err != nil {
+		log.Printf("WARNING: Failed to send payment confirmation for loan %d: %v", loan.ID, err)
+	}
+}
+
+// ListReminderTemplates godoc
+// @Summary List SMS reminder templates
+// @Description Returns the message template for each reminder event with the placeholders templates may use and the configured quiet hours
+// @Tags reminders
+// @Produce json
+// @Security SessionAuth
+// @Success 200 {object} ReminderTemplateListResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/reminders/templates [get]
+func ListReminderTemplates(c echo.Context) error {
+	templates, err := reminderRepo.GetTemplates()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch reminder templates"})
+	}
+
+	response := ReminderTemplateListResponse{
+		Templates:    []ReminderTemplateItem{},
+		Placeholders: reminderPlaceholders,
+		QuietHours:   "off",
+	}
+	for i := range templates {
+		response.Templates = append(response.Templates, toReminderTemplateItem(&templates[i]))
+	}
+	if start, end, ok := quietHours(); ok {
+		response.QuietHours = fmt.Sprintf("%02d:%02d-%02d:%02d", start/60, start%60, end/60, end%60)
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// UpdateReminderTemplate godoc
+// @Summary Edit an SMS reminder template (manager)
+// @Description Updates the message body, timing or active flag of a reminder event. Days is the lead time before the due date for installment_due and the grace period after it for installment_overdue. Omitted fields are left unchanged.
+// @Tags reminders
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param event path string true "Event (installment_due, installment_overdue, payment_confirmation, loan_comment)"
+// @Param request body UpdateReminderTemplateRequest true "Template settings"
+// @Success 200 {object} ReminderTemplateItem
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/reminders/templates/{event} [post]
+func UpdateReminderTemplate(c echo.Context) error {
+	user := c.Get("user").(*repos.UserWithSession)
+
+	var req UpdateReminderTemplateRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request"})
+	}
+
+	template, err := reminderRepo.GetTemplate(c.Param("event"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Reminder template not found"})
+	}
+
+	if req.Body != nil {
+		body := strings.TrimSpace(*req.Body)
+		if body == "" {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Body cannot be empty"})
+		}
+		if len([]rune(body)) > MaxReminderBodyChars {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("Body cannot be longer than %d characters", MaxReminderBodyChars)})
+		}
+		for _, match := range reminderPlaceholder.FindAllStringSubmatch(body, -1) {
+			if !slices.Contains(reminderPlaceholders, match[1]) {
+				return c.JSON(http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("Unknown placeholder {%s}", match[1])})
+			}
+		}
+		template.Body = body
+	}
+	if req.Days != nil {
+		if *req.Days < 0 || *req.Days > 30 {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Days must be between 0 and 30"})
+		}
+		template.Days = *req.Days
+	}
+	if req.IsActive != nil {
+		template.IsActive = *req.IsActive
+	}
+
+	updatedByID := user.ID
+	template.UpdatedByID = &updatedByID
+	if err := reminderRepo.SaveTemplate(template); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update reminder template"})
+	}
+	item := toReminderTemplateItem(template)
+	item.UpdatedBy = &ManagerInfo{ID: user.ID, Name: user.Name}
+
+	return c.JSON(http.StatusOK, item)
+}
+
+// ListSMSMessages godoc
+// @Summary Get the SMS delivery log
+// @Description Returns queued, sent and failed messages, newest first, with their delivery attempts and last error
+// @Tags reminders
+// @Produce json
+// @Security SessionAuth
+// @Param status query string false "Filter by status (pending, sent, failed)"
+// @Param user_id query int false "Filter by member"
+// @Param loan_id query int false "Filter by loan"
+// @Success 200 {object} SMSMessageListResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/reminders/messages [get]
+func ListSMSMessages(c echo.Context) error {
+	filter := repos.SMSMessageFilter{Status: c.QueryParam("status")}
+	if raw := c.QueryParam("user_id"); raw != "" {
+		userID, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid user ID"})
+		}
+		id := uint(userID)
+		filter.UserID = &id
+	}
+	if raw := c.QueryParam("loan_id"); raw != "" {
+		loanID, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid loan ID"})
+		}
+		id := uint(loanID)
+		filter.LoanID = &id
+	}
+
+	messages, err := reminderRepo.GetMessages(filter)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch messages"})
+	}
+
+	response := SMSMessageListResponse{Messages: []SMSMessageItem{}}
+	for i := range messages {
+		response.Messages = append(response.Messages, toSMSMessageItem(&messages[i]))
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// RetrySMSMessage godoc
+// @Summary Retry a failed SMS (manager)
+// @Description Puts a failed message back in the queue with a fresh set of attempts. It is sent on the next reminder run outside quiet hours.
+// @Tags reminders
+// @Produce json
+// @Security SessionAuth
+// @Param id path int true "Message ID"
+// @Success 200 {object} SMSMessageItem
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/reminders/messages/{id}/retry [post]
+func RetrySMSMessage(c echo.Context) error {
+	messageID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid message ID"})
+	}
+
+	message, err := reminderRepo.GetMessage(uint(messageID))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Message not found"})
+	}
+	if message.Status != db.SMSStatusFailed {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Only failed messages can be retried"})
+	}
+
+	message.Status = db.SMSStatusPending
+	message.Attempts = 0
+	message.NextAttemptAt = time.Now().Unix()
+	if err := reminderRepo.SaveMessage(message); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to requeue message"})
+	}
+
+	return c.JSON(http.StatusOK, toSMSMessageItem(message))
+}
+
+// RunReminders godoc
+// @Summary Run SMS reminders now (manager)
+// @Description Queues due and overdue installment reminders from the repayment schedules and sends pending messages outside quiet hours. This also runs automatically in the background; reminders are never duplicated.
+// @Tags reminders
+// @Produce json
+// @Security SessionAuth
+// @Success 200 {object} RunRemindersResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/reminders/run [post]
+func RunReminders(c echo.Context) error {
+	queued, sent, failed, err := runReminders(time.Now())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to run reminders"})
+	}
+
+	return c.JSON(http.StatusOK, RunRemindersResponse{
+		OK:     true,
+		Queued: queued,
+		Sent:   sent,
+		Failed: failed,
+	})
+}
@@ -0,0 +1,330 @@
+package handlers
+
+import (
+	"backend/src/accounting"
+	"backend/src/repos"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/xuri/excelize/v2"
+)
+
+var ledgerRepo = repos.LedgerRepo{}
+
+type TrialBalanceLineItem struct {
+	Account string `json:"account" example:"cash"`
+	Name    string `json:"name" example:"Cash at bank"`
+	Class   string `json:"class" example:"asset"`
+	Debit   int    `json:"debit" example:"500000"`
+	Credit  int    `json:"credit" example:"0"`
+}
+
+type TrialBalanceResponse struct {
+	From        string                 `json:"from,omitempty" example:"2025-01-01T00:00:00Z"`
+	To          string                 `json:"to,omitempty" example:"2026-01-01T00:00:00Z"`
+	Lines       []TrialBalanceLineItem `json:"lines"`
+	TotalDebit  int                    `json:"total_debit" example:"900000"`
+	TotalCredit int                    `json:"total_credit" example:"900000"`
+	Balanced    bool                   `json:"balanced" example:"true"`
+}
+
+type BalanceSheetAssets struct {
+	Cash              int `json:"cash" example:"400000"`
+	LoansReceivable   int `json:"loans_receivable" example:"620000"`
+	LoanLossAllowance int `json:"loan_loss_allowance" example:"20000"`
+	NetLoans          int `json:"net_loans" example:"600000"`
+	Total             int `json:"total" example:"1000000"`
+}
+
+type BalanceSheetLiabilities struct {
+	MemberSavings int `json:"member_savings" example:"800000"`
+	Total         int `json:"total" example:"800000"`
+}
+
+type BalanceSheetEquity struct {
+	ShareCapital    int `json:"share_capital" example:"100000"`
+	CurrentEarnings int `json:"current_earnings" example:"50000"`
+	Total           int `json:"total" example:"150000"`
+}
+
+type BalanceSheetResponse struct {
+	AsOf        string                  `json:"as_of" example:"2026-01-01T00:00:00Z"`
+	Assets      BalanceSheetAssets      `json:"assets"`
+	Liabilities BalanceSheetLiabilities `json:"liabilities"`
+	Equity      BalanceSheetEquity      `json:"equity"`
+	Balanced    bool                    `json:"balanced" example:"true"`
+}
+
+type IncomeStatementResponse struct {
+	From              string `json:"from,omitempty" example:"2025-01-01T00:00:00Z"`
+	To                string `json:"to,omitempty" example:"2026-01-01T00:00:00Z"`
+	InterestIncome    int    `json:"interest_income" example:"75000"`
+	FeeIncome         int    `json:"fee_income" example:"5000"`
+	Recoveries        int    `json:"recoveries" example:"2000"`
+	TotalIncome       int    `json:"total_income" example:"82000"`
+	Expenses          int    `json:"expenses" example:"30000"`
+	LoanLossProvision int    `json:"loan_loss_provision" example:"2000"`
+	NetIncome         int    `json:"net_income" example:"50000"`
+}
+
+// parseReportDate accepts RFC3339 timestamps or plain dates. A plain end date
+// is inclusive, so it is moved to the start of the following day.
+func parseReportDate(value string, isEnd bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date: %s", value)
+	}
+	if isEnd {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
+
+func parseReportRange(c echo.Context) (*time.Time, *time.Time, error) {
+	from, err := parseReportDate(c.QueryParam("start_date"), false)
+	if err != nil {
+		return nil, nil, err
+	}
+	to, err := parseReportDate(c.QueryParam("end_date"), true)
+	if err != nil {
+		return nil, nil, err
+	}
+	if from != nil && to != nil && !from.Before(*to) {
+		return nil, nil, fmt.Errorf("start_date must be before end_date")
+	}
+	return from, to, nil
+}
+
+func formatReportDate(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func writeReportExcel(c echo.Context, name string, rows [][]interface{}) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	sheetName := "Report"
+	index, _ := f.NewSheet(sheetName)
+	f.SetActiveSheet(index)
+	f.DeleteSheet("Sheet1")
+
+	for i, row := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		f.SetSheetRow(sheetName, cell, &row)
+	}
+
+	filename := fmt.Sprintf("%s_%s.xlsx", name, time.Now().Format("2006-01-02"))
+
+	c.Response().Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	c.Response().Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+
+	return f.Write(c.Response().Writer)
+}
+
+// GetTrialBalance godoc
+// @Summary Get Trial Balance
+// @Description Returns debit and credit totals per account for transactions in the date range
+// @Tags audit
+// @Produce json
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param start_date query string false "From date (ISO 8601 or YYYY-MM-DD)"
+// @Param end_date query string false "To date, inclusive (ISO 8601 or YYYY-MM-DD)"
+// @Param format query string false "Output format (json, excel)"
+// @Success 200 {object} TrialBalanceResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security SessionAuth
+// @Router /api/v1/audit/reports/trial_balance [get]
+func GetTrialBalance(c echo.Context) error {
+	from, to, err := parseReportRange(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	entries, err := ledgerRepo.GetJournal(from, to)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to build journal"})
+	}
+
+	tb := accounting.BuildTrialBalance(entries)
+
+	response := TrialBalanceResponse{
+		From:        formatReportDate(from),
+		To:          formatReportDate(to),
+		TotalDebit:  tb.TotalDebit,
+		TotalCredit: tb.TotalCredit,
+		Balanced:    tb.Balanced(),
+	}
+	for _, line := range tb.Lines {
+		response.Lines = append(response.Lines, TrialBalanceLineItem{
+			Account: line.Account,
+			Name:    line.Name,
+			Class:   string(line.Class),
+			Debit:   line.Debit,
+			Credit:  line.Credit,
+		})
+	}
+
+	if c.QueryParam("format") == "excel" {
+		rows := [][]interface{}{
+			{"Trial Balance"},
+			{"From", response.From, "To", response.To},
+			{},
+			{"Account", "Name", "Class", "Debit", "Credit"},
+		}
+		for _, line := range response.Lines {
+			rows = append(rows, []interface{}{line.Account, line.Name, line.Class, line.Debit, line.Credit})
+		}
+		rows = append(rows, []interface{}{"", "Total", "", response.TotalDebit, response.TotalCredit})
+		return writeReportExcel(c, "trial_balance", rows)
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// GetBalanceSheet godoc
+// @Summary Get Balance Sheet
+// @Description Returns assets, liabilities and equity as of end_date (defaults to now)
+// @Tags audit
+// @Produce json
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param end_date query string false "As-of date, inclusive (ISO 8601 or YYYY-MM-DD)"
+// @Param format query string false "Output format (json, excel)"
+// @Success 200 {object} BalanceSheetResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security SessionAuth
+// @Router /api/v1/audit/reports/balance_sheet [get]
+func GetBalanceSheet(c echo.Context) error {
+	asOf, err := parseReportDate(c.QueryParam("end_date"), true)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+	if asOf == nil {
+		now := time.Now()
+		asOf = &now
+	}
+
+	entries, err := ledgerRepo.GetJournal(nil, asOf)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to build journal"})
+	}
+
+	bs := accounting.BuildBalanceSheet(entries)
+
+	response := BalanceSheetResponse{
+		AsOf: formatReportDate(asOf),
+		Assets: BalanceSheetAssets{
+			Cash:              bs.Cash,
+			LoansReceivable:   bs.LoansReceivable,
+			LoanLossAllowance: bs.LoanLossAllowance,
+			NetLoans:          bs.NetLoans,
+			Total:             bs.TotalAssets,
+		},
+		Liabilities: BalanceSheetLiabilities{
+			MemberSavings: bs.MemberSavings,
+			Total:         bs.TotalLiabilities,
+		},
+		Equity: BalanceSheetEquity{
+			ShareCapital:    bs.ShareCapital,
+			CurrentEarnings: bs.CurrentEarnings,
+			Total:           bs.TotalEquity,
+		},
+		Balanced: bs.Balanced(),
+	}
+
+	if c.QueryParam("format") == "excel" {
+		rows := [][]interface{}{
+			{"Balance Sheet"},
+			{"As of", response.AsOf},
+			{},
+			{"Assets"},
+			{"Cash at bank", response.Assets.Cash},
+			{"Loans receivable", response.Assets.LoansReceivable},
+			{"Allowance for loan losses", -response.Assets.LoanLossAllowance},
+			{"Net loans", response.Assets.NetLoans},
+			{"Total assets", response.Assets.Total},
+			{},
+			{"Liabilities"},
+			{"Member savings", response.Liabilities.MemberSavings},
+			{"Total liabilities", response.Liabilities.Total},
+			{},
+			{"Equity"},
+			{"Share capital", response.Equity.ShareCapital},
+			{"Current earnings", response.Equity.CurrentEarnings},
+			{"Total equity", response.Equity.Total},
+		}
+		return writeReportExcel(c, "balance_sheet", rows)
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// GetIncomeStatement godoc
+// @Summary Get Income Statement
+// @Description Returns interest income, fees, recoveries on written-off loans, expenses, the loan loss provision and net income for the date range
+// @Tags audit
+// @Produce json
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param start_date query string false "From date (ISO 8601 or YYYY-MM-DD)"
+// @Param end_date query string false "To date, inclusive (ISO 8601 or YYYY-MM-DD)"
+// @Param format query string false "Output format (json, excel)"
+// @Success 200 {object} IncomeStatementResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security SessionAuth
+// @Router /api/v1/audit/reports/income_statement [get]
+func GetIncomeStatement(c echo.Context) error {
+	from, to, err := parseReportRange(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	entries, err := ledgerRepo.GetJournal(from, to)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to build journal"})
+	}
+
+	is := accounting.BuildIncomeStatement(entries)
+
+	response := IncomeStatementResponse{
+		From:              formatReportDate(from),
+		To:                formatReportDate(to),
+		InterestIncome:    is.InterestIncome,
+		FeeIncome:         is.FeeIncome,
+		Recoveries:        is.Recoveries,
+		TotalIncome:       is.TotalIncome,
+		Expenses:          is.Expenses,
+		LoanLossProvision: is.LoanLossProvision,
+		NetIncome:         is.NetIncome,
+	}
+
+	if c.QueryParam("format") == "excel" {
+		rows := [][]interface{}{
+			{"Income Statement"},
+			{"From", response.From, "To", response.To},
+			{},
+			{"Interest income", response.InterestIncome},
+			{"Fee income", response.FeeIncome},
+			{"Recoveries on written-off loans", response.Recoveries},
+			{"Total income", response.TotalIncome},
+			{"Expenses", response.Expenses},
+			{"Loan loss provision", response.LoanLossProvision},
+			{"Net income", response.NetIncome},
+		}
+		return writeReportExcel(c, "income_statement", rows)
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
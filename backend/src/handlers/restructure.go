@@ -0,0 +1,358 @@
+package handlers
+
+import (
+	"backend/src/amortization"
+	"backend/src/db"
+	"backend/src/lifecycle"
+	"backend/src/repos"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type RestructureLoanRequest struct {
+	Duration          int      `json:"duration" example:"18"`
+	InterestRate      *float64 `json:"interest_rate" example:"10"`
+	CapitaliseArrears bool     `json:"capitalise_arrears" example:"true"`
+	HolidayMonths     int      `json:"holiday_months" example:"2"`
+	Reason            string   `json:"reason" binding:"required" example:"Member lost income for two months"`
+}
+
+type LoanTerms struct {
+	Duration       int     `json:"duration" example:"12"`
+	InterestRate   float64 `json:"interest_rate" example:"12"`
+	MonthlyPayment int     `json:"monthly_payment" example:"9333"`
+	Outstanding    int     `json:"outstanding" example:"83333"`
+}
+
+type RestructuredInstallment struct {
+	Number    int    `json:"number" example:"1"`
+	DueDate   string `json:"due_date" example:"2025-02-15T00:00:00Z"`
+	Payment   int    `json:"payment" example:"9333"`
+	Principal int    `json:"principal" example:"8333"`
+	Interest  int    `json:"interest" example:"1000"`
+	Balance   int    `json:"balance" example:"91667"`
+}
+
+type LoanRestructureItem struct {
+	ID                  uint                      `json:"id" example:"1"`
+	RestructuredBy      *ManagerInfo              `json:"restructured_by,omitempty"`
+	Reason              string                    `json:"reason" example:"Member lost income for two months"`
+	PreviousTerms       LoanTerms                 `json:"previous_terms"`
+	PreviousSchedule    []RestructuredInstallment `json:"previous_schedule"`
+	NewTerms            LoanTerms                 `json:"new_terms"`
+	CapitalisedInterest int                       `json:"capitalised_interest" example:"2000"`
+	CapitalisedCharges  int                       `json:"capitalised_charges" example:"500"`
+	HolidayMonths       int                       `json:"holiday_months" example:"2"`
+	TransactionID       string                    `json:"transaction_id" example:"TXN-1234567890"`
+	CreatedAt           string                    `json:"created_at" example:"2025-06-01T09:00:00Z"`
+}
+
+type LoanRestructuresResponse struct {
+	LoanID           uint                  `json:"loan_id" example:"1"`
+	RestructureCount int                   `json:"restructure_count" example:"1"`
+	Restructures     []LoanRestructureItem `json:"restructures"`
+}
+
+type RestructureLoanResponse struct {
+	OK            bool                `json:"ok" example:"true"`
+	TransactionID string              `json:"transaction_id" example:"TXN-1234567890"`
+	FirstDueDate  string              `json:"first_due_date" example:"2025-09-01T10:00:00Z"`
+	Restructure   LoanRestructureItem `json:"restructure"`
+}
+
+func toLoanRestructureItem(restructure *db.LoanRestructure) LoanRestructureItem {
+	item := LoanRestructureItem{
+		ID:     restructure.ID,
+		Reason: restructure.Reason,
+		PreviousTerms: LoanTerms{
+			Duration:       restructure.PreviousDuration,
+			InterestRate:   restructure.PreviousInterestRate,
+			MonthlyPayment: restructure.PreviousMonthlyPayment,
+			Outstanding:    restructure.PreviousOutstanding,
+		},
+		PreviousSchedule: []RestructuredInstallment{},
+		NewTerms: LoanTerms{
+			Duration:       restructure.NewDuration,
+			InterestRate:   restructure.NewInterestRate,
+			MonthlyPayment: restructure.NewMonthlyPayment,
+			Outstanding:    restructure.NewOutstanding,
+		},
+		CapitalisedInterest: restructure.CapitalisedInterest,
+		CapitalisedCharges:  restructure.CapitalisedCharges,
+		HolidayMonths:       restructure.HolidayMonths,
+		TransactionID:       restructure.TransactionID,
+		CreatedAt:           restructure.CreatedAt.Format(time.RFC3339),
+	}
+	if restructure.RestructuredBy != nil {
+		item.RestructuredBy = &ManagerInfo{
+			ID:   restructure.RestructuredBy.ID,
+			Name: restructure.RestructuredBy.Name,
+		}
+	}
+	if restructure.PreviousSchedule != "" {
+		if err := json.Unmarshal([]byte(restructure.PreviousSchedule), &item.PreviousSchedule); err != nil {
+			log.Printf("WARNING: Invalid schedule snapshot on restructure %d: %v", restructure.ID, err)
+		}
+	}
+	return item
+}
+
+// scheduleSnapshot serialises a schedule so the terms a loan was restructured
+// from stay on record after its installments are replaced.
+func scheduleSnapshot(installments []db.LoanInstallment) (string, error) {
+	snapshot := make([]RestructuredInstallment, len(installments))
+	for i, installment := range installments {
+		snapshot[i] = RestructuredInstallment{
+			Number:    installment.Number,
+			DueDate:   time.Unix(installment.DueDate, 0).Format(time.RFC3339),
+			Payment:   installment.Payment,
+			Principal: installment.Principal,
+			Interest:  installment.Interest,
+			Balance:   installment.Balance,
+		}
+	}
+	data, err := json.Marshal(snapshot)
+	return string(data), err
+}
+
+// RestructureLoan godoc
+// @Summary Restructure an active loan (manager)
+// @Description Reschedules the outstanding balance of an active loan over a new duration and/or at a new rate. Overdue interest, penalties and fees may be capitalised into the balance, and a payment holiday defers the first installment by whole months without accruing interest. The duration defaults to the installments not yet due and the rate to the current one. The original terms and schedule are kept on record and the new terms are anchored as a loan_restructure transaction.
+// @Tags loans
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param id path int true "Loan ID"
+// @Param request body RestructureLoanRequest true "New terms"
+// @Success 200 {object} RestructureLoanResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/loans/{id}/restructure [post]
+func RestructureLoan(c echo.Context) error {
+	user := c.Get("user").(*repos.UserWithSession)
+
+	loanID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid loan ID"})
+	}
+
+	var req RestructureLoanRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request"})
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "A reason is required"})
+	}
+	if req.Duration < 0 {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Duration must be positive"})
+	}
+	if req.HolidayMonths < 0 {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Holiday months cannot be negative"})
+	}
+	if req.InterestRate != nil && *req.InterestRate < 0 {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Interest rate cannot be negative"})
+	}
+	if req.Duration == 0 && req.InterestRate == nil && !req.CapitaliseArrears && req.HolidayMonths == 0 {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Give a new duration, interest rate, arrears capitalisation or payment holiday"})
+	}
+
+	loan, err := loanRepoHandler.GetByID(uint(loanID))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Loan not found"})
+	}
+
+	if err := lifecycle.Check(loan, lifecycle.StatusRestructured); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	now := time.Now()
+	installments, _, _, err := loanSchedule(loan)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to build repayment schedule"})
+	}
+
+	duration := req.Duration
+	if duration == 0 {
+		for _, installment := range installments {
+			if installment.DueDate > now.Unix() {
+				duration++
+			}
+		}
+		if duration == 0 {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Every installment has fallen due; give a new duration"})
+		}
+	}
+	rate := loan.InterestRate
+	if req.InterestRate != nil {
+		rate = *req.InterestRate
+	}
+
+	// Overdue principal is already part of the outstanding balance, so
+	// capitalising arrears adds the unpaid interest of past installments and
+	// the penalties and fees charged to the loan.
+	capitalisedInterest, capitalisedCharges := 0, 0
+	var dues amortization.Dues
+	var charges []db.FeeCharge
+	if req.CapitaliseArrears {
+		_, paidInterest, err := scheduleRepo.GetPaidSplit(loan.ID, scheduleStart(loan))
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch loan payments"})
+		}
+		overdueInterest := 0
+		for _, installment := range installments {
+			if installment.DueDate <= now.Unix() {
+				overdueInterest += installment.Interest
+			}
+		}
+		capitalisedInterest = max(0, overdueInterest-paidInterest)
+
+		dues, charges, err = loanDues(loan, now)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch loan charges"})
+		}
+		capitalisedCharges = dues.Penalties + dues.Fees
+	}
+
+	outstanding := loan.OutstandingBalance + capitalisedInterest + capitalisedCharges
+	schedule, err := amortization.Generate(loanRepaymentMethod(loan), outstanding, rate, duration, amortization.AddMonths(now, req.HolidayMonths))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	previousSchedule, err := scheduleSnapshot(installments)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to record the original schedule"})
+	}
+
+	restructuredByID := user.ID
+	restructure := &db.LoanRestructure{
+		LoanID:                 loan.ID,
+		RestructuredByID:       &restructuredByID,
+		Reason:                 req.Reason,
+		PreviousDuration:       loan.Duration,
+		PreviousInterestRate:   loan.InterestRate,
+		PreviousMonthlyPayment: loan.MonthlyPayment,
+		PreviousOutstanding:    loan.OutstandingBalance,
+		PreviousSchedule:       previousSchedule,
+		NewDuration:            duration,
+		NewInterestRate:        rate,
+		NewMonthlyPayment:      schedule[0].Payment,
+		NewOutstanding:         outstanding,
+		CapitalisedInterest:    capitalisedInterest,
+		CapitalisedCharges:     capitalisedCharges,
+		HolidayMonths:          req.HolidayMonths,
+	}
+
+	description := fmt.Sprintf("Loan #%d restructured: %d at %.2f%% over %d months, %d monthly",
+		loan.ID, outstanding, rate, duration, schedule[0].Payment)
+	if capitalisedInterest+capitalisedCharges > 0 {
+		description += fmt.Sprintf(", %d arrears capitalised", capitalisedInterest+capitalisedCharges)
+	}
+	if req.HolidayMonths > 0 {
+		description += fmt.Sprintf(", %d month payment holiday", req.HolidayMonths)
+	}
+	transaction := &db.Transaction{
+		TransactionID: transactionGenerator(),
+		Type:          "loan_restructure",
+		FromAccount:   fmt.Sprintf("LOAN-%d", loan.ID),
+		ToAccount:     lifecycle.StatusRestructured,
+		Amount:        capitalisedInterest + capitalisedCharges,
+		Status:        "completed",
+		Description:   description,
+	}
+
+	transactionID, err := changeLoanStatus(loan, lifecycle.StatusRestructured, &restructuredByID, req.Reason, transaction, map[string]interface{}{
+		"outstanding_balance": outstanding,
+		"duration":            duration,
+		"interest_rate":       rate,
+		"monthly_payment":     schedule[0].Payment,
+		"restructured_at":     now.Unix(),
+		"restructure_count":   loan.RestructureCount + 1,
+		"days_past_due":       0,
+		"arrears_amount":      0,
+	})
+	if err != nil {
+		return statusChangeError(c, err)
+	}
+
+	restructure.TransactionID = transactionID
+	if err := loanRepoHandler.CreateRestructure(restructure); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to record restructure"})
+	}
+	if err := saveLoanSchedule(loan.ID, schedule); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to save repayment schedule"})
+	}
+	if capitalisedCharges > 0 {
+		if err := settleLoanCharges(charges, dues.Penalties, dues.Fees); err != nil {
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to settle capitalised charges"})
+		}
+	}
+
+	item := toLoanRestructureItem(restructure)
+	item.RestructuredBy = &ManagerInfo{ID: user.ID, Name: user.Name}
+	return c.JSON(http.StatusOK, RestructureLoanResponse{
+		OK:            true,
+		TransactionID: transactionID,
+		FirstDueDate:  schedule[0].DueDate.Format(time.RFC3339),
+		Restructure:   item,
+	})
+}
+
+// GetLoanRestructures godoc
+// @Summary Get loan restructure history
+// @Description Returns every restructure of the loan with the terms and schedule it replaced, the new terms and the anchored transaction. Members may only view their own loans.
+// @Tags loans
+// @Produce json
+// @Security SessionAuth
+// @Param id path int true "Loan ID"
+// @Success 200 {object} LoanRestructuresResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/loans/{id}/restructures [get]
+func GetLoanRestructures(c echo.Context) error {
+	user := c.Get("user").(*repos.UserWithSession)
+
+	loanID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid loan ID"})
+	}
+
+	loan, err := loanRepoHandler.GetByID(uint(loanID))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Loan not found"})
+	}
+
+	if user.Role == "member" && loan.BorrowerID != user.ID {
+		return c.JSON(http.StatusForbidden, ErrorResponse{Error: "Not authorized to view this loan"})
+	}
+
+	restructures, err := loanRepoHandler.GetRestructures(loan.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch loan restructures"})
+	}
+
+	response := LoanRestructuresResponse{
+		LoanID:           loan.ID,
+		RestructureCount: loan.RestructureCount,
+		Restructures:     []LoanRestructureItem{},
+	}
+	for i := range restructures {
+		response.Restructures = append(response.Restructures, toLoanRestructureItem(&restructures[i]))
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"backend/src/amortization"
+	"backend/src/db"
+	"backend/src/lifecycle"
+	"backend/src/repos"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+var scheduleRepo = repos.ScheduleRepo{}
+
+type ScheduleInstallmentItem struct {
+	Number     int    `json:"number" example:"1"`
+	DueDate    string `json:"due_date" example:"2025-02-15T00:00:00Z"`
+	Payment    int    `json:"payment" example:"9333"`
+	Principal  int    `json:"principal" example:"8333"`
+	Interest   int    `json:"interest" example:"1000"`
+	Balance    int    `json:"balance" example:"91667"`
+	AmountPaid int    `json:"amount_paid" example:"9333"`
+	Status     string `json:"status" example:"paid"`
+}
+
+type LoanScheduleResponse struct {
+	LoanID          uint                      `json:"loan_id" example:"1"`
+	RepaymentMethod string                    `json:"repayment_method" example:"flat"`
+	InterestRate    float64                   `json:"interest_rate" example:"12"`
+	Principal       int                       `json:"principal" example:"100000"`
+	TotalInterest   int                       `json:"total_interest" example:"12000"`
+	TotalPayable    int                       `json:"total_payable" example:"112000"`
+	TotalPaid       int                       `json:"total_paid" example:"9333"`
+	Preview         bool                      `json:"preview" example:"false"`
+	NextInstallment *ScheduleInstallmentItem  `json:"next_installment,omitempty"`
+	Installments    []ScheduleInstallmentItem `json:"installments"`
+}
+
+func loanRepaymentMethod(loan *db.Loan) string {
+	if loan.RepaymentMethod == "" {
+		return amortization.MethodFlat
+	}
+	return loan.RepaymentMethod
+}
+
+func generateLoanSchedule(loan *db.Loan, rate float64, start time.Time) ([]amortization.Installment, error) {
+	return amortization.Generate(loanRepaymentMethod(loan), loan.Principal, rate, loan.Duration, start)
+}
+
+// scheduleStart is when the loan's current schedule took effect. Only
+// payments made since then count against its installments.
+func scheduleStart(loan *db.Loan) int64 {
+	if loan.RestructuredAt != nil {
+		return *loan.RestructuredAt
+	}
+	return 0
+}
+
+// schedulePaid sums the principal and interest of the loan's preloaded
+// payments that count against its current schedule.
+func schedulePaid(loan *db.Loan) int {
+	paid := 0
+	start := scheduleStart(loan)
+	for _, payment := range loan.Payments {
+		if payment.PaymentDate >= start {
+			paid += payment.PrincipalAmount + payment.InterestAmount
+		}
+	}
+	return paid
+}
+
+func saveLoanSchedule(loanID uint, schedule []amortization.Installment) error {
+	installments := make([]db.LoanInstallment, len(schedule))
+	for i, installment := range schedule {
+		installments[i] = db.LoanInstallment{
+			LoanID:    loanID,
+			Number:    installment.Number,
+			DueDate:   installment.DueDate.Unix(),
+			Payment:   installment.Payment,
+			Principal: installment.Principal,
+			Interest:  installment.Interest,
+			Balance:   installment.Balance,
+		}
+	}
+	return scheduleRepo.ReplaceSchedule(loanID, installments)
+}
+
+// loanSchedule returns the stored schedule of a loan. Loans without one,
+// such as pending requests, get a preview computed from the current terms.
+func loanSchedule(loan *db.Loan) ([]db.LoanInstallment, float64, bool, error) {
+	stored, err := scheduleRepo.GetSchedule(loan.ID)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	if len(stored) > 0 {
+		return stored, loan.InterestRate, false, nil
+	}
+
+	rate := loan.InterestRate
+	start := loanStartedAt(loan)
+	if lifecycle.IsInitial(loan.Status) && loan.ApprovedByID == nil {
+		if loan.ProductID != nil {
+			product, err := productRepo.GetByID(*loan.ProductID)
+			if err != nil {
+				return nil, 0, false, err
+			}
+			rate = product.InterestRate
+		} else {
+			interestRate, err := interestRateRepo.GetByDuration(loan.Duration, time.Now().Unix())
+			if err != nil {
+				return nil, 0, false, err
+			}
+			rate = interestRate.Rate
+		}
+		start = time.Now()
+	}
+
+	schedule, err := generateLoanSchedule(loan, rate, start)
+	if err != nil {
+		return nil, 0, false, err
+	}
+
+	preview := make([]db.LoanInstallment, len(schedule))
+	for i, installment := range schedule {
+		preview[i] = db.LoanInstallment{
+			LoanID:    loan.ID,
+			Number:    installment.Number,
+			DueDate:   installment.DueDate.Unix(),
+			Payment:   installment.Payment,
+			Principal: installment.Principal,
+			Interest:  installment.Interest,
+			Balance:   installment.Balance,
+		}
+	}
+	return preview, rate, true, nil
+}
+
+// GetLoanSchedule godoc
+// @Summary Get loan repayment schedule
+// @Description Returns each installment's due date, principal, interest and remaining balance, with how much has been paid against it. Pending requests return a preview at the current rate. Members may only view their own loans.
+// @Tags loans
+// @Produce json
+// @Security SessionAuth
+// @Param id path int true "Loan ID"
+// @Success 200 {object} LoanScheduleResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/loans/{id}/schedule [get]
+func GetLoanSchedule(c echo.Context) error {
+	user := c.Get("user").(*repos.UserWithSession)
+
+	loanID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid loan ID"})
+	}
+
+	loan, err := loanRepoHandler.GetByID(uint(loanID))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Loan not found"})
+	}
+
+	if user.Role == "member" && loan.BorrowerID != user.ID {
+		return c.JSON(http.StatusForbidden, ErrorResponse{Error: "Not authorized to view this loan"})
+	}
+
+	installments, rate, preview, err := loanSchedule(loan)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to build repayment schedule"})
+	}
+
+	totalPaid, err := scheduleRepo.GetTotalPaid(loan.ID, scheduleStart(loan))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch loan payments"})
+	}
+
+	response := LoanScheduleResponse{
+		LoanID:          loan.ID,
+		RepaymentMethod: loanRepaymentMethod(loan),
+		InterestRate:    rate,
+		Principal:       loan.Principal,
+		TotalPaid:       totalPaid,
+		Preview:         preview,
+		Installments:    []ScheduleInstallmentItem{},
+	}
+
+	// Payments are applied to installments in order, oldest first.
+	now := time.Now()
+	remaining := totalPaid
+	for _, installment := range installments {
+		item := ScheduleInstallmentItem{
+			Number:    installment.Number,
+			DueDate:   time.Unix(installment.DueDate, 0).Format(time.RFC3339),
+			Payment:   installment.Payment,
+			Principal: installment.Principal,
+			Interest:  installment.Interest,
+			Balance:   installment.Balance,
+		}
+
+		item.AmountPaid = installment.Payment
+		if remaining < installment.Payment {
+			item.AmountPaid = remaining
+		}
+		remaining -= item.AmountPaid
+
+		switch {
+		case item.AmountPaid == installment.Payment:
+			item.Status = "paid"
+		case installment.DueDate < now.Unix():
+			item.Status = "overdue"
+		case item.AmountPaid > 0:
+			item.Status = "partially_paid"
+		default:
+			item.Status = "upcoming"
+		}
+
+		response.TotalInterest += installment.Interest
+		response.TotalPayable += installment.Payment
+		response.Installments = append(response.Installments, item)
+	}
+
+	for i := range response.Installments {
+		if response.Installments[i].Status != "paid" {
+			next := response.Installments[i]
+			response.NextInstallment = &next
+			break
+		}
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"backend/src/db"
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+)
+
+// postShareCapital brings the ledger in line with the members' share
+// registers. Each member's shares balance not yet posted is recorded as a
+// share_capital transaction from the bank to SHARES-<id>, or back when
+// shares were redeemed, so the balance sheet carries share capital. It
+// returns how many transactions were posted.
+func postShareCapital() (int, error) {
+	posted, err := ledgerRepo.GetPostedShareCapital()
+	if err != nil {
+		return 0, err
+	}
+	users, err := ledgerRepo.GetSharesBalances()
+	if err != nil {
+		return 0, err
+	}
+
+	registers := map[uint]int{}
+	for _, user := range users {
+		registers[user.ID] = user.SharesBalance
+	}
+	for userID := range posted {
+		if _, ok := registers[userID]; !ok {
+			registers[userID] = 0
+		}
+	}
+
+	count := 0
+	for _, userID := range slices.Sorted(maps.Keys(registers)) {
+		shares := registers[userID]
+		difference := shares - posted[userID]
+		if difference == 0 {
+			continue
+		}
+
+		account := fmt.Sprintf("SHARES-%d", userID)
+		transaction := &db.Transaction{
+			TransactionID: transactionGenerator(),
+			Type:          "share_capital",
+			FromAccount:   "BANK",
+			ToAccount:     account,
+			Amount:        difference,
+			Status:        "completed",
+			Description:   fmt.Sprintf("Share capital of member %d brought to %d", userID, shares),
+		}
+		if difference < 0 {
+			transaction.FromAccount, transaction.ToAccount = account, "BANK"
+			transaction.Amount = -difference
+		}
+		if err := anchorTransaction(transaction); err != nil {
+			log.Printf("WARNING: Failed to post share capital for user %d: %v", userID, err)
+			continue
+		}
+		count++
+	}
+	return count, nil
+}
@@ -0,0 +1,440 @@
+package handlers
+
+import (
+	"backend/src/accounting"
+	"backend/src/db"
+	"backend/src/pdf"
+	"backend/src/repos"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+var statementRepo = repos.StatementRepo{}
+
+type StatementMember struct {
+	ID          uint   `json:"id" example:"1"`
+	Name        string `json:"name" example:"John Doe"`
+	PhoneNumber string `json:"phone_number" example:"+1234567890"`
+}
+
+type StatementEntry struct {
+	Date             string `json:"date" example:"2025-11-14T09:30:00Z"`
+	TransactionID    string `json:"transaction_id" example:"TXN-1234567890"`
+	Type             string `json:"type" example:"deposit"`
+	Description      string `json:"description" example:"Deposit: BANK-TX-12345"`
+	Debit            int    `json:"debit" example:"0"`
+	Credit           int    `json:"credit" example:"10000"`
+	Balance          int    `json:"balance" example:"60000"`
+	LoanID           *uint  `json:"loan_id,omitempty" example:"3"`
+	PrincipalAmount  int    `json:"principal_amount,omitempty" example:"8000"`
+	InterestAmount   int    `json:"interest_amount,omitempty" example:"1000"`
+	LoanBalanceAfter *int   `json:"loan_balance_after,omitempty" example:"86000"`
+	BlockNumber      *uint  `json:"block_number,omitempty" example:"1042"`
+	EthereumTxHash   string `json:"ethereum_tx_hash,omitempty" example:"0xabc123..."`
+}
+
+type MemberStatementResponse struct {
+	Member         StatementMember  `json:"member"`
+	From           string           `json:"from,omitempty" example:"2025-11-01T00:00:00Z"`
+	To             string           `json:"to" example:"2025-12-01T00:00:00Z"`
+	OpeningBalance int              `json:"opening_balance" example:"50000"`
+	TotalCredits   int              `json:"total_credits" example:"10000"`
+	TotalDebits    int              `json:"total_debits" example:"0"`
+	ClosingBalance int              `json:"closing_balance" example:"60000"`
+	Entries        []StatementEntry `json:"entries"`
+}
+
+// savingsEffect returns the signed change a transaction makes to the member's
+// savings balance. Loan repayments are paid in from outside savings, so they
+// are listed on the statement and only move the balance by any overpayment
+// credited back to the member.
+func savingsEffect(tx *db.Transaction, account string, data *accounting.LedgerData) int {
+	switch tx.Type {
+	case "loan_payment":
+		return data.Payments[tx.TransactionID].CreditAmount
+	case "loan_guarantee":
+		// Guarantees lock savings without moving them.
+		return 0
+	}
+	effect := 0
+	if tx.ToAccount == account {
+		effect += tx.Amount
+	}
+	if tx.FromAccount == account {
+		effect -= tx.Amount
+	}
+	return effect
+}
+
+func buildMemberStatement(user *db.User, from, to *time.Time) (*MemberStatementResponse, error) {
+	if to == nil {
+		now := time.Now()
+		to = &now
+	}
+
+	transactions, err := statementRepo.GetMemberTransactions(user.ID, to)
+	if err != nil {
+		return nil, err
+	}
+
+	account := fmt.Sprintf("USER-%d", user.ID)
+	response := &MemberStatementResponse{
+		Member: StatementMember{
+			ID:          user.ID,
+			Name:        user.Name,
+			PhoneNumber: user.PhoneNumber,
+		},
+		From:    formatReportDate(from),
+		To:      formatReportDate(to),
+		Entries: []StatementEntry{},
+	}
+
+	ledgerData, err := ledgerRepo.GetLedgerData(transactions)
+	if err != nil {
+		return nil, err
+	}
+
+	var inRange []db.Transaction
+	for _, tx := range transactions {
+		if from != nil && tx.CreatedAt.Before(*from) {
+			response.OpeningBalance += savingsEffect(&tx, account, ledgerData)
+			continue
+		}
+		inRange = append(inRange, tx)
+	}
+
+	txIDs := make([]string, len(inRange))
+	for i, tx := range inRange {
+		txIDs[i] = tx.TransactionID
+	}
+	blocks, err := statementRepo.GetBlocksByTransactionIDs(txIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	balance := response.OpeningBalance
+	for i := range inRange {
+		tx := &inRange[i]
+		effect := savingsEffect(tx, account, ledgerData)
+		balance += effect
+
+		entry := StatementEntry{
+			Date:          tx.CreatedAt.Format(time.RFC3339),
+			TransactionID: tx.TransactionID,
+			Type:          tx.Type,
+			Description:   tx.Description,
+			Balance:       balance,
+		}
+		if effect > 0 {
+			entry.Credit = effect
+			response.TotalCredits += effect
+		} else if effect < 0 {
+			entry.Debit = -effect
+			response.TotalDebits -= effect
+		}
+
+		if payment, ok := ledgerData.Payments[tx.TransactionID]; ok {
+			loanID := payment.LoanID
+			balanceAfter := payment.BalanceAfter
+			entry.LoanID = &loanID
+			entry.PrincipalAmount = payment.PrincipalAmount
+			entry.InterestAmount = payment.InterestAmount
+			entry.LoanBalanceAfter = &balanceAfter
+		}
+
+		if block, ok := blocks[tx.TransactionID]; ok {
+			blockNumber := block.BlockNumber
+			entry.BlockNumber = &blockNumber
+			entry.EthereumTxHash = block.EthereumTxHash
+		}
+
+		response.Entries = append(response.Entries, entry)
+	}
+	response.ClosingBalance = balance
+
+	return response, nil
+}
+
+func writeStatement(c echo.Context, statement *MemberStatementResponse) error {
+	switch c.QueryParam("format") {
+	case "csv":
+		return writeStatementCSV(c, statement)
+	case "pdf":
+		return writeStatementPDF(c, statement)
+	}
+	return c.JSON(http.StatusOK, statement)
+}
+
+func statementFilename(statement *MemberStatementResponse, ext string) string {
+	return fmt.Sprintf("statement_%d_%s.%s", statement.Member.ID, time.Now().Format("2006-01-02"), ext)
+}
+
+func writeStatementCSV(c echo.Context, statement *MemberStatementResponse) error {
+	c.Response().Header().Set("Content-Type", "text/csv")
+	c.Response().Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", statementFilename(statement, "csv")))
+
+	w := csv.NewWriter(c.Response().Writer)
+	w.Write([]string{"Date", "Transaction ID", "Type", "Description", "Debit", "Credit", "Balance",
+		"Loan ID", "Principal", "Interest", "Loan Balance After", "Block Number", "Sepolia Tx Hash"})
+	w.Write([]string{statement.From, "", "opening_balance", "Opening balance", "", "", strconv.Itoa(statement.OpeningBalance)})
+
+	for _, entry := range statement.Entries {
+		loanID, principal, interest, loanBalance, block := "", "", "", "", ""
+		if entry.LoanID != nil {
+			loanID = strconv.Itoa(int(*entry.LoanID))
+			principal = strconv.Itoa(entry.PrincipalAmount)
+			interest = strconv.Itoa(entry.InterestAmount)
+		}
+		if entry.LoanBalanceAfter != nil {
+			loanBalance = strconv.Itoa(*entry.LoanBalanceAfter)
+		}
+		if entry.BlockNumber != nil {
+			block = strconv.Itoa(int(*entry.BlockNumber))
+		}
+		w.Write([]string{
+			entry.Date, entry.TransactionID, entry.Type, entry.Description,
+			strconv.Itoa(entry.Debit), strconv.Itoa(entry.Credit), strconv.Itoa(entry.Balance),
+			loanID, principal, interest, loanBalance, block, entry.EthereumTxHash,
+		})
+	}
+
+	w.Write([]string{statement.To, "", "closing_balance", "Closing balance", strconv.Itoa(statement.TotalDebits),
+		strconv.Itoa(statement.TotalCredits), strconv.Itoa(statement.ClosingBalance)})
+	w.Flush()
+	return w.Error()
+}
+
+func cooperativeName() string {
+	if name := os.Getenv("COOPERATIVE_NAME"); name != "" {
+		return name
+	}
+	return "8MH Cooperative"
+}
+
+func displayDate(value string) string {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.Format("02 Jan 2006")
+	}
+	return value
+}
+
+func writeStatementPDF(c echo.Context, statement *MemberStatementResponse) error {
+	brand := pdf.Color{R: 0.09, G: 0.33, B: 0.55}
+	const margin = 40.0
+	right := pdf.PageWidth - margin
+
+	doc := pdf.New()
+
+	columns := []struct {
+		title string
+		x     float64
+		align string
+	}{
+		{"Date", margin, "left"},
+		{"Description", margin + 58, "left"},
+		{"Debit", margin + 262, "right"},
+		{"Credit", margin + 314, "right"},
+		{"Balance", margin + 372, "right"},
+		{"Block", margin + 404, "right"},
+		{"Sepolia Tx", margin + 412, "left"},
+	}
+
+	drawHeader := func() float64 {
+		doc.Rect(0, 0, pdf.PageWidth, 70, brand)
+		doc.Text(margin, 32, 18, true, pdf.White, cooperativeName())
+		doc.Text(margin, 52, 10, false, pdf.White, "Member Account Statement")
+		doc.TextRight(right, 52, 9, false, pdf.White, "Blockchain-anchored ledger")
+		return 90
+	}
+
+	drawTableHeader := func(y float64) float64 {
+		doc.Rect(margin-4, y-10, right-margin+8, 14, pdf.Color{R: 0.92, G: 0.94, B: 0.97})
+		for _, col := range columns {
+			if col.align == "right" {
+				doc.TextRight(col.x, y, 8, true, brand, col.title)
+			} else {
+				doc.Text(col.x, y, 8, true, brand, col.title)
+			}
+		}
+		return y + 16
+	}
+
+	drawFooter := func() {
+		doc.Line(margin, pdf.PageHeight-40, right, pdf.PageHeight-40, 0.5, pdf.Grey)
+		doc.Text(margin, pdf.PageHeight-28, 7, false, pdf.Grey,
+			"Each entry is anchored in the cooperative ledger; Sepolia hashes can be verified at sepolia.etherscan.io.")
+		doc.TextRight(right, pdf.PageHeight-28, 7, false, pdf.Grey, fmt.Sprintf("Page %d", doc.PageCount()))
+	}
+
+	y := drawHeader()
+	doc.Text(margin, y, 11, true, pdf.Black, statement.Member.Name)
+	doc.Text(margin, y+14, 9, false, pdf.Grey, fmt.Sprintf("Member #%d  |  %s", statement.Member.ID, statement.Member.PhoneNumber))
+	period := "From first transaction"
+	if statement.From != "" {
+		period = "From " + displayDate(statement.From)
+	}
+	doc.TextRight(right, y, 9, false, pdf.Black, period)
+	doc.TextRight(right, y+14, 9, false, pdf.Black, "To "+displayDate(statement.To))
+	y += 36
+
+	summary := []struct {
+		label string
+		value int
+	}{
+		{"Opening balance", statement.OpeningBalance},
+		{"Total credits", statement.TotalCredits},
+		{"Total debits", statement.TotalDebits},
+		{"Closing balance", statement.ClosingBalance},
+	}
+	boxWidth := (right - margin) / float64(len(summary))
+	for i, item := range summary {
+		x := margin + float64(i)*boxWidth
+		doc.Rect(x, y, boxWidth-6, 38, pdf.Color{R: 0.96, G: 0.97, B: 0.98})
+		doc.Text(x+8, y+14, 8, false, pdf.Grey, item.label)
+		doc.Text(x+8, y+30, 12, true, pdf.Black, strconv.Itoa(item.value))
+	}
+	y += 58
+
+	y = drawTableHeader(y)
+	for _, entry := range statement.Entries {
+		if y > pdf.PageHeight-60 {
+			drawFooter()
+			doc.AddPage()
+			y = drawTableHeader(drawHeader())
+		}
+
+		description := entry.Description
+		if entry.LoanID != nil {
+			description = fmt.Sprintf("Loan #%d repayment: P %d / I %d", *entry.LoanID, entry.PrincipalAmount, entry.InterestAmount)
+		}
+		debit, credit, block := "", "", ""
+		if entry.Debit > 0 {
+			debit = strconv.Itoa(entry.Debit)
+		}
+		if entry.Credit > 0 {
+			credit = strconv.Itoa(entry.Credit)
+		}
+		if entry.BlockNumber != nil {
+			block = strconv.Itoa(int(*entry.BlockNumber))
+		}
+		txHash := entry.EthereumTxHash
+		if txHash == "" {
+			txHash = "pending"
+		}
+
+		doc.Text(columns[0].x, y, 7.5, false, pdf.Black, displayDate(entry.Date))
+		doc.Text(columns[1].x, y, 7.5, false, pdf.Black, pdf.Truncate(description, 150, 7.5, false))
+		doc.TextRight(columns[2].x, y, 7.5, false, pdf.Black, debit)
+		doc.TextRight(columns[3].x, y, 7.5, false, pdf.Black, credit)
+		doc.TextRight(columns[4].x, y, 7.5, true, pdf.Black, strconv.Itoa(entry.Balance))
+		doc.TextRight(columns[5].x, y, 7.5, false, pdf.Grey, block)
+		doc.Text(columns[6].x, y, 6.5, false, pdf.Grey, pdf.Truncate(txHash, right-columns[6].x, 6.5, false))
+		doc.Line(margin-4, y+4, right+4, y+4, 0.25, pdf.Color{R: 0.85, G: 0.85, B: 0.85})
+		y += 14
+	}
+
+	if len(statement.Entries) == 0 {
+		doc.Text(margin, y, 9, false, pdf.Grey, "No transactions in this period.")
+	}
+	drawFooter()
+
+	c.Response().Header().Set("Content-Type", "application/pdf")
+	c.Response().Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", statementFilename(statement, "pdf")))
+
+	return doc.Write(c.Response().Writer)
+}
+
+// GetMyStatement godoc
+// @Summary Get own account statement (member)
+// @Description Returns opening balance, each transaction with running savings balance, block number and Sepolia hash, and closing balance. Loan repayments are listed with their principal and interest split. Any statement fee is charged on the first CSV or PDF download of the calendar month.
+// @Tags statements
+// @Produce json
+// @Produce text/csv
+// @Produce application/pdf
+// @Security SessionAuth
+// @Param from query string false "From date (ISO 8601 or YYYY-MM-DD)"
+// @Param to query string false "To date, inclusive (ISO 8601 or YYYY-MM-DD)"
+// @Param format query string false "Output format (json, csv, pdf)"
+// @Success 200 {object} MemberStatementResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/statements [get]
+func GetMyStatement(c echo.Context) error {
+	user := c.Get("user").(*repos.UserWithSession)
+
+	member, err := userRepo.GetByID(user.ID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "User not found"})
+	}
+
+	if format := c.QueryParam("format"); format == "csv" || format == "pdf" {
+		chargeStatementFees(member.ID, time.Now())
+		if member, err = userRepo.GetByID(user.ID); err != nil {
+			return c.JSON(http.StatusNotFound, ErrorResponse{Error: "User not found"})
+		}
+	}
+
+	return respondWithStatement(c, member)
+}
+
+// GetMemberStatement godoc
+// @Summary Get a member's account statement (manager)
+// @Description Same as the member statement, for any member
+// @Tags statements
+// @Produce json
+// @Produce text/csv
+// @Produce application/pdf
+// @Security SessionAuth
+// @Param id path int true "Member ID"
+// @Param from query string false "From date (ISO 8601 or YYYY-MM-DD)"
+// @Param to query string false "To date, inclusive (ISO 8601 or YYYY-MM-DD)"
+// @Param format query string false "Output format (json, csv, pdf)"
+// @Success 200 {object} MemberStatementResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/statements/members/{id} [get]
+func GetMemberStatement(c echo.Context) error {
+	memberID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid member ID"})
+	}
+
+	member, err := userRepo.GetByID(uint(memberID))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "User not found"})
+	}
+
+	return respondWithStatement(c, member)
+}
+
+func respondWithStatement(c echo.Context, member *db.User) error {
+	from, err := parseReportDate(c.QueryParam("from"), false)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+	to, err := parseReportDate(c.QueryParam("to"), true)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+	if from != nil && to != nil && !from.Before(*to) {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "from must be before to"})
+	}
+
+	statement, err := buildMemberStatement(member, from, to)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to build statement"})
+	}
+
+	return writeStatement(c, statement)
+}
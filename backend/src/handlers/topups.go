@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"backend/src/amortization"
+	"backend/src/db"
+	"backend/src/lifecycle"
+	"fmt"
+	"time"
+)
+
+// checkTopUp reports why a member cannot refinance a loan into a new one of
+// the given amount. The new loan has to cover the loan's payoff today, and a
+// loan can only have one top-up open at a time; exceptID names the top-up
+// being amended, which does not count against itself.
+func checkTopUp(previous *db.Loan, borrowerID uint, amount int, exceptID uint, now time.Time) (string, error) {
+	if previous.BorrowerID != borrowerID {
+		return "You can only top up your own loans", nil
+	}
+	if !lifecycle.IsActive(previous.Status) || previous.OutstandingBalance <= 0 {
+		return fmt.Sprintf("Loan #%d is not active", previous.ID), nil
+	}
+
+	topUps, err := loanRepoHandler.GetTopUps(previous.ID)
+	if err != nil {
+		return "", err
+	}
+	for _, topUp := range topUps {
+		if topUp.ID == exceptID {
+			continue
+		}
+		if lifecycle.IsPending(topUp.Status) || (topUp.Status == lifecycle.StatusApproved && topUp.DisbursedAt == nil) {
+			return fmt.Sprintf("Loan #%d already has a top-up request (loan #%d)", previous.ID, topUp.ID), nil
+		}
+	}
+
+	quote, err := payoffQuote(previous, now, now)
+	if err != nil {
+		return "", err
+	}
+	if amount <= quote.Total {
+		return fmt.Sprintf("A top-up of loan #%d must be more than its payoff amount of %d", previous.ID, quote.Total), nil
+	}
+	return "", nil
+}
+
+// settleTopUp closes the earlier loan with its payoff quote out of the
+// top-up loan's principal. The settlement is posted as a loan_top_up
+// transaction from the new loan to the old one, the old loan's payment and
+// quote record it, and the old loan is marked PaidOff.
+func settleTopUp(previous, loan *db.Loan, quote *db.PayoffQuote, actorID uint, now time.Time) (string, error) {
+	if err := chargePayoffFees(previous); err != nil {
+		return "", err
+	}
+	charges, err := feeRepo.GetOutstandingLoanCharges(previous.ID)
+	if err != nil {
+		return "", err
+	}
+	allocation := amortization.Allocate(quote.Total, quoteDues(quote), paymentWaterfall(), prepayOverpayments())
+
+	transaction := &db.Transaction{
+		TransactionID: transactionGenerator(),
+		Type:          "loan_top_up",
+		FromAccount:   fmt.Sprintf("LOAN-%d", loan.ID),
+		ToAccount:     fmt.Sprintf("LOAN-%d", previous.ID),
+		Amount:        quote.Total,
+		Status:        "completed",
+		Description:   fmt.Sprintf("Loan #%d settled with %d from top-up loan #%d", previous.ID, quote.Total, loan.ID),
+	}
+
+	previous.OutstandingBalance = 0
+	if _, err := changeLoanStatus(previous, lifecycle.StatusPaidOff, &actorID, fmt.Sprintf("Refinanced into loan #%d", loan.ID), transaction, map[string]interface{}{
+		"outstanding_balance": 0,
+		"paid_off_at":         now.Unix(),
+	}); err != nil {
+		return "", err
+	}
+
+	payment := &db.LoanPayment{
+		LoanID:           previous.ID,
+		TransactionID:    transaction.TransactionID,
+		Amount:           quote.Total,
+		PrincipalAmount:  allocation.TotalPrincipal(),
+		InterestAmount:   allocation.Interest,
+		PenaltyAmount:    allocation.Penalties,
+		FeeAmount:        allocation.Fees,
+		PrepaymentAmount: allocation.Prepayment,
+		CreditAmount:     allocation.Credit,
+		BalanceAfter:     0,
+		Status:           "completed",
+		PaymentDate:      now.Unix(),
+	}
+	if err := db.DB.Create(payment).Error; err != nil {
+		return "", err
+	}
+
+	if err := settleLoanCharges(charges, allocation.Penalties, allocation.Fees); err != nil {
+		return "", err
+	}
+
+	quote.RequestedByID = actorID
+	if err := payoffRepo.CreateQuote(quote); err != nil {
+		return "", err
+	}
+	if err := payoffRepo.MarkSettled(quote.ID, transaction.TransactionID); err != nil {
+		return "", err
+	}
+
+	return transaction.TransactionID, nil
+}
+
+// refinancedInto returns the top-up loan that settled the given loan, if
+// any.
+func refinancedInto(loanID uint) (*uint, error) {
+	topUps, err := loanRepoHandler.GetTopUps(loanID)
+	if err != nil {
+		return nil, err
+	}
+	for _, topUp := range topUps {
+		if topUp.TopUpAmount > 0 {
+			id := topUp.ID
+			return &id, nil
+		}
+	}
+	return nil, nil
+}
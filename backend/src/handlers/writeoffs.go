@@ -0,0 +1,678 @@
+package handlers
+
+import (
+	"backend/src/accounting"
+	"backend/src/db"
+	"backend/src/lifecycle"
+	"backend/src/repos"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+var writeOffRepo = repos.WriteOffRepo{}
+
+type WriteOffLoanRequest struct {
+	Reason string `json:"reason" example:"Borrower deceased, no estate"`
+}
+
+type WriteOffLoanResponse struct {
+	OK         bool `json:"ok" example:"true"`
+	WriteOffID uint `json:"write_off_id" example:"1"`
+	Amount     int  `json:"amount" example:"83333"`
+}
+
+type DecideWriteOffRequest struct {
+	Approve bool   `json:"approve" example:"true"`
+	Comment string `json:"comment" example:"Collection efforts documented"`
+}
+
+type WriteOffItem struct {
+	ID            uint         `json:"id" example:"1"`
+	LoanID        uint         `json:"loan_id" example:"1"`
+	Borrower      BorrowerInfo `json:"borrower"`
+	Amount        int          `json:"amount" example:"83333"`
+	AllowanceUsed int          `json:"allowance_used" example:"83333"`
+	Reason        string       `json:"reason" example:"Borrower deceased, no estate"`
+	Status        string       `json:"status" example:"pending"`
+	RequestedBy   ManagerInfo  `json:"requested_by"`
+	DecidedBy     *ManagerInfo `json:"decided_by,omitempty"`
+	DecidedAt     string       `json:"decided_at,omitempty" example:"2025-06-30T10:00:00Z"`
+	Comment       string       `json:"comment,omitempty" example:"Collection efforts documented"`
+	TransactionID string       `json:"transaction_id,omitempty" example:"TXN-1234567890"`
+	CreatedAt     string       `json:"created_at" example:"2025-06-28T10:00:00Z"`
+}
+
+type WriteOffListResponse struct {
+	WriteOffs []WriteOffItem `json:"write_offs"`
+}
+
+type RecordRecoveryRequest struct {
+	Amount    int    `json:"amount" example:"10000"`
+	Reference string `json:"reference" example:"Estate settlement"`
+}
+
+type RecordRecoveryResponse struct {
+	OK              bool   `json:"ok" example:"true"`
+	TransactionID   string `json:"transaction_id" example:"TXN-1234567890"`
+	RecoveredAmount int    `json:"recovered_amount" example:"10000"`
+	Unrecovered     int    `json:"unrecovered" example:"73333"`
+}
+
+type RecoveryItem struct {
+	ID            uint        `json:"id" example:"1"`
+	Amount        int         `json:"amount" example:"10000"`
+	Reference     string      `json:"reference,omitempty" example:"Estate settlement"`
+	RecordedBy    ManagerInfo `json:"recorded_by"`
+	TransactionID string      `json:"transaction_id" example:"TXN-1234567890"`
+	CreatedAt     string      `json:"created_at" example:"2025-08-01T10:00:00Z"`
+}
+
+type LoanRecoveriesResponse struct {
+	LoanID           uint           `json:"loan_id" example:"1"`
+	WrittenOffAmount int            `json:"written_off_amount" example:"83333"`
+	RecoveredAmount  int            `json:"recovered_amount" example:"10000"`
+	Recoveries       []RecoveryItem `json:"recoveries"`
+}
+
+type ProvisionBucketItem struct {
+	Bucket      string  `json:"bucket" example:"31-60"`
+	Loans       int     `json:"loans" example:"3"`
+	Outstanding int     `json:"outstanding" example:"250000"`
+	Rate        float64 `json:"rate" example:"25"`
+	Required    int     `json:"required" example:"62500"`
+}
+
+type ProvisioningResponse struct {
+	AsOf              string                `json:"as_of" example:"2025-06-30T00:00:00Z"`
+	Buckets           []ProvisionBucketItem `json:"buckets"`
+	TotalOutstanding  int                   `json:"total_outstanding" example:"2000000"`
+	RequiredAllowance int                   `json:"required_allowance" example:"120000"`
+	CurrentAllowance  int                   `json:"current_allowance" example:"100000"`
+	Adjustment        int                   `json:"adjustment" example:"20000"`
+}
+
+type RunProvisioningResponse struct {
+	OK            bool   `json:"ok" example:"true"`
+	TransactionID string `json:"transaction_id,omitempty" example:"TXN-1234567890"`
+	Adjustment    int    `json:"adjustment" example:"20000"`
+	Allowance     int    `json:"allowance" example:"120000"`
+}
+
+type UpdateProvisionRateRequest struct {
+	Rate float64 `json:"rate" example:"25"`
+}
+
+type ProvisionRateItem struct {
+	Bucket string  `json:"bucket" example:"31-60"`
+	Rate   float64 `json:"rate" example:"25"`
+}
+
+func toWriteOffItem(writeOff *db.LoanWriteOff) WriteOffItem {
+	item := WriteOffItem{
+		ID:     writeOff.ID,
+		LoanID: writeOff.LoanID,
+		Borrower: BorrowerInfo{
+			ID:          writeOff.Loan.Borrower.ID,
+			Name:        writeOff.Loan.Borrower.Name,
+			PhoneNumber: writeOff.Loan.Borrower.PhoneNumber,
+		},
+		Amount:        writeOff.Amount,
+		AllowanceUsed: writeOff.AllowanceUsed,
+		Reason:        writeOff.Reason,
+		Status:        writeOff.Status,
+		RequestedBy:   ManagerInfo{ID: writeOff.RequestedBy.ID, Name: writeOff.RequestedBy.Name},
+		Comment:       writeOff.Comment,
+		TransactionID: writeOff.TransactionID,
+		CreatedAt:     writeOff.CreatedAt.Format(time.RFC3339),
+	}
+	if writeOff.DecidedBy != nil {
+		item.DecidedBy = &ManagerInfo{ID: writeOff.DecidedBy.ID, Name: writeOff.DecidedBy.Name}
+	}
+	if writeOff.DecidedAt != nil {
+		item.DecidedAt = time.Unix(*writeOff.DecidedAt, 0).Format(time.RFC3339)
+	}
+	return item
+}
+
+// writeOffAmount is what writing the loan off removes from loans
+// receivable: the outstanding balance and the unpaid charges on the loan.
+func writeOffAmount(loan *db.Loan) (int, error) {
+	charges, err := feeRepo.GetOutstandingLoanCharges(loan.ID)
+	if err != nil {
+		return 0, err
+	}
+	amount := loan.OutstandingBalance
+	for _, charge := range charges {
+		amount += charge.Amount - charge.PaidAmount - charge.WaivedAmount
+	}
+	return amount, nil
+}
+
+// loanLossAllowance returns the allowance currently held against loan losses.
+func loanLossAllowance() (int, error) {
+	entries, err := ledgerRepo.GetJournal(nil, nil)
+	if err != nil {
+		return 0, err
+	}
+	return -accounting.Balances(entries)[accounting.AccountLoanLossAllowance], nil
+}
+
+// loanProvisioning applies the provision rate of each aging bucket to the
+// outstanding balances of the active loans in it, and compares the required
+// allowance with the one held.
+func loanProvisioning(now time.Time) (*ProvisioningResponse, error) {
+	loans, err := loanRepoHandler.GetActive()
+	if err != nil {
+		return nil, err
+	}
+	rates, err := writeOffRepo.GetProvisionRates()
+	if err != nil {
+		return nil, err
+	}
+	rateOf := map[string]float64{}
+	for _, rate := range rates {
+		rateOf[rate.Bucket] = rate.Rate
+	}
+
+	response := &ProvisioningResponse{
+		AsOf:    now.Format(time.RFC3339),
+		Buckets: make([]ProvisionBucketItem, len(agingBuckets)),
+	}
+	index := map[string]int{}
+	for i, bucket := range agingBuckets {
+		response.Buckets[i] = ProvisionBucketItem{Bucket: bucket.Name, Rate: rateOf[bucket.Name]}
+		index[bucket.Name] = i
+	}
+
+	for i := range loans {
+		_, daysPastDue, _ := loanArrears(&loans[i], now)
+		bucket := &response.Buckets[index[agingBucketFor(daysPastDue)]]
+		bucket.Loans++
+		bucket.Outstanding += loans[i].OutstandingBalance
+		response.TotalOutstanding += loans[i].OutstandingBalance
+	}
+
+	for i := range response.Buckets {
+		bucket := &response.Buckets[i]
+		bucket.Required = int(math.Round(float64(bucket.Outstanding) * bucket.Rate / 100))
+		response.RequiredAllowance += bucket.Required
+	}
+
+	response.CurrentAllowance, err = loanLossAllowance()
+	if err != nil {
+		return nil, err
+	}
+	response.Adjustment = response.RequiredAllowance - response.CurrentAllowance
+	return response, nil
+}
+
+// runProvisioning posts the adjustment that brings the loan loss allowance
+// to the required level. It returns the anchoring transaction ID, or "" when
+// the allowance is already right.
+func runProvisioning(now time.Time) (string, *ProvisioningResponse, error) {
+	provisioning, err := loanProvisioning(now)
+	if err != nil {
+		return "", nil, err
+	}
+	if provisioning.Adjustment == 0 {
+		return "", provisioning, nil
+	}
+
+	direction := "PROVISION"
+	if provisioning.Adjustment < 0 {
+		direction = "RELEASE"
+	}
+	transaction := &db.Transaction{
+		TransactionID: transactionGenerator(),
+		Type:          "loan_provision",
+		FromAccount:   "LOAN_PORTFOLIO",
+		ToAccount:     direction,
+		Amount:        max(provisioning.Adjustment, -provisioning.Adjustment),
+		Status:        "completed",
+		Description: fmt.Sprintf("Loan loss allowance moved from %d to %d on %d outstanding",
+			provisioning.CurrentAllowance, provisioning.RequiredAllowance, provisioning.TotalOutstanding),
+	}
+	if err := anchorTransaction(transaction); err != nil {
+		return "", nil, err
+	}
+	return transaction.TransactionID, provisioning, nil
+}
+
+// RequestLoanWriteOff godoc
+// @Summary Request a loan write-off (manager)
+// @Description Files a request to write off a loan that will not be repaid. It takes effect only once an auditor approves it, which closes the loan as WrittenOff.
+// @Tags loans
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param id path int true "Loan ID"
+// @Param request body WriteOffLoanRequest true "Write-off reason"
+// @Success 200 {object} WriteOffLoanResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/loans/{id}/write_off [post]
+func RequestLoanWriteOff(c echo.Context) error {
+	user := c.Get("user").(*repos.UserWithSession)
+
+	loanID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid loan ID"})
+	}
+
+	var req WriteOffLoanRequest
+	if err := c.Bind(&req); err != nil || strings.TrimSpace(req.Reason) == "" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "A reason is required"})
+	}
+
+	loan, err := loanRepoHandler.GetByID(uint(loanID))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Loan not found"})
+	}
+
+	if err := lifecycle.Check(loan, lifecycle.StatusWrittenOff); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	pending, err := writeOffRepo.HasPending(loan.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to check write-off requests"})
+	}
+	if pending {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "A write-off request is already pending for this loan"})
+	}
+
+	amount, err := writeOffAmount(loan)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch loan charges"})
+	}
+
+	writeOff := &db.LoanWriteOff{
+		LoanID:        loan.ID,
+		RequestedByID: user.ID,
+		Reason:        strings.TrimSpace(req.Reason),
+		Amount:        amount,
+		Status:        db.WriteOffPending,
+	}
+	if err := writeOffRepo.Create(writeOff); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to create write-off request"})
+	}
+
+	return c.JSON(http.StatusOK, WriteOffLoanResponse{OK: true, WriteOffID: writeOff.ID, Amount: amount})
+}
+
+// ListLoanWriteOffs godoc
+// @Summary List loan write-off requests
+// @Description Returns write-off requests, newest first, optionally filtered by status (pending, approved, rejected)
+// @Tags loans
+// @Produce json
+// @Security SessionAuth
+// @Param status query string false "Request status"
+// @Success 200 {object} WriteOffListResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/loans/write_offs [get]
+func ListLoanWriteOffs(c echo.Context) error {
+	writeOffs, err := writeOffRepo.GetAll(c.QueryParam("status"))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch write-off requests"})
+	}
+
+	items := make([]WriteOffItem, len(writeOffs))
+	for i := range writeOffs {
+		items[i] = toWriteOffItem(&writeOffs[i])
+	}
+
+	return c.JSON(http.StatusOK, WriteOffListResponse{WriteOffs: items})
+}
+
+// DecideLoanWriteOff godoc
+// @Summary Approve or reject a loan write-off (auditor)
+// @Description Auditor decides a pending write-off request. Approval closes the loan as WrittenOff, removes its outstanding balance and unpaid charges from loans receivable against the loan loss allowance, expensing any shortfall, and anchors the write-off. A rejection is anchored as well.
+// @Tags loans
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param id path int true "Write-off request ID"
+// @Param request body DecideWriteOffRequest true "Decision"
+// @Success 200 {object} WriteOffItem
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/loans/write_offs/{id}/decide [post]
+func DecideLoanWriteOff(c echo.Context) error {
+	user := c.Get("user").(*repos.UserWithSession)
+
+	writeOffID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid write-off ID"})
+	}
+
+	var req DecideWriteOffRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request"})
+	}
+
+	writeOff, err := writeOffRepo.GetByID(uint(writeOffID))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Write-off request not found"})
+	}
+	if writeOff.Status != db.WriteOffPending {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Write-off request has already been decided"})
+	}
+
+	decidedByID := user.ID
+	decidedAt := time.Now().Unix()
+	writeOff.DecidedByID = &decidedByID
+	writeOff.DecidedAt = &decidedAt
+	writeOff.Comment = req.Comment
+
+	if req.Approve {
+		loan, err := loanRepoHandler.GetByID(writeOff.LoanID)
+		if err != nil {
+			return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Loan not found"})
+		}
+		if err := lifecycle.Check(loan, lifecycle.StatusWrittenOff); err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		}
+
+		// Repayments may have come in since the request was filed.
+		amount, err := writeOffAmount(loan)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch loan charges"})
+		}
+		allowance, err := loanLossAllowance()
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to build journal"})
+		}
+		writeOff.Amount = amount
+		writeOff.AllowanceUsed = max(0, min(allowance, amount))
+		writeOff.Status = db.WriteOffApproved
+
+		transaction := &db.Transaction{
+			TransactionID: transactionGenerator(),
+			Type:          "loan_write_off",
+			FromAccount:   fmt.Sprintf("LOAN-%d", loan.ID),
+			ToAccount:     lifecycle.StatusWrittenOff,
+			Amount:        amount,
+			Status:        "completed",
+			Description: fmt.Sprintf("Loan #%d written off at %d, requested by manager %d and approved by auditor %d: %s",
+				loan.ID, amount, writeOff.RequestedByID, user.ID, writeOff.Reason),
+		}
+		// The write-off is saved first so the journal finds it once the
+		// transaction is posted.
+		writeOff.TransactionID = transaction.TransactionID
+		if err := writeOffRepo.Save(writeOff); err != nil {
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update write-off request"})
+		}
+
+		updates := map[string]interface{}{
+			"outstanding_balance": 0,
+			"written_off_at":      decidedAt,
+			"written_off_amount":  amount,
+			"days_past_due":       0,
+			"arrears_amount":      0,
+		}
+		if _, err := changeLoanStatus(loan, lifecycle.StatusWrittenOff, &decidedByID, writeOff.Reason, transaction, updates); err != nil {
+			writeOff.Status, writeOff.TransactionID = db.WriteOffPending, ""
+			writeOff.DecidedByID, writeOff.DecidedAt = nil, nil
+			if saveErr := writeOffRepo.Save(writeOff); saveErr != nil {
+				log.Printf("WARNING: Failed to return write-off %d to pending: %v", writeOff.ID, saveErr)
+			}
+			return statusChangeError(c, err)
+		}
+		if err := feeRepo.MarkLoanChargesWrittenOff(loan.ID); err != nil {
+			log.Printf("WARNING: Failed to close charges on written-off loan %d: %v", loan.ID, err)
+		}
+	} else {
+		writeOff.Status = db.WriteOffRejected
+		transaction := &db.Transaction{
+			TransactionID: transactionGenerator(),
+			Type:          "loan_write_off",
+			FromAccount:   fmt.Sprintf("LOAN-%d", writeOff.LoanID),
+			ToAccount:     db.WriteOffRejected,
+			Amount:        0,
+			Status:        "completed",
+			Description: fmt.Sprintf("Write-off of loan #%d requested by manager %d rejected by auditor %d: %s",
+				writeOff.LoanID, writeOff.RequestedByID, user.ID, writeOff.Reason),
+		}
+		if err := anchorTransaction(transaction); err != nil {
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to record write-off decision"})
+		}
+		writeOff.TransactionID = transaction.TransactionID
+		if err := writeOffRepo.Save(writeOff); err != nil {
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update write-off request"})
+		}
+	}
+
+	item := toWriteOffItem(writeOff)
+	item.DecidedBy = &ManagerInfo{ID: user.ID, Name: user.Name}
+	return c.JSON(http.StatusOK, item)
+}
+
+// RecordLoanRecovery godoc
+// @Summary Record a recovery on a written-off loan (manager)
+// @Description Posts money collected on a written-off loan as recovery income, anchored as a loan_recovery transaction. Recoveries cannot exceed the amount written off; the recovery that covers it in full releases the loan's guarantors.
+// @Tags loans
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param id path int true "Loan ID"
+// @Param request body RecordRecoveryRequest true "Recovery"
+// @Success 200 {object} RecordRecoveryResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/loans/{id}/recoveries [post]
+func RecordLoanRecovery(c echo.Context) error {
+	user := c.Get("user").(*repos.UserWithSession)
+
+	loanID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid loan ID"})
+	}
+
+	var req RecordRecoveryRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request"})
+	}
+	if req.Amount <= 0 {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Amount must be positive"})
+	}
+
+	loan, err := loanRepoHandler.GetByID(uint(loanID))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Loan not found"})
+	}
+	if loan.Status != lifecycle.StatusWrittenOff {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Recoveries can only be recorded on written-off loans"})
+	}
+	unrecovered := loan.WrittenOffAmount - loan.RecoveredAmount
+	if req.Amount > unrecovered {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: fmt.Sprintf("Only %d of the %d written off remains unrecovered", unrecovered, loan.WrittenOffAmount),
+		})
+	}
+
+	transaction := &db.Transaction{
+		TransactionID: transactionGenerator(),
+		Type:          "loan_recovery",
+		FromAccount:   "BANK",
+		ToAccount:     fmt.Sprintf("LOAN-%d", loan.ID),
+		Amount:        req.Amount,
+		Status:        "completed",
+		Description:   fmt.Sprintf("Recovery on written-off loan #%d: %s", loan.ID, req.Reference),
+	}
+	if err := anchorTransaction(transaction); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to record recovery"})
+	}
+
+	recovery := &db.LoanRecovery{
+		LoanID:        loan.ID,
+		Amount:        req.Amount,
+		Reference:     req.Reference,
+		RecordedByID:  user.ID,
+		TransactionID: transaction.TransactionID,
+	}
+	if err := writeOffRepo.CreateRecovery(recovery); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to save recovery"})
+	}
+
+	// Guarantors stay bound until the written-off amount is recovered.
+	if unrecovered == req.Amount {
+		releaseGuarantees(loan)
+	}
+
+	return c.JSON(http.StatusOK, RecordRecoveryResponse{
+		OK:              true,
+		TransactionID:   transaction.TransactionID,
+		RecoveredAmount: loan.RecoveredAmount + req.Amount,
+		Unrecovered:     unrecovered - req.Amount,
+	})
+}
+
+// GetLoanRecoveries godoc
+// @Summary List recoveries on a written-off loan
+// @Description Returns the amount written off and every recovery posted against it
+// @Tags loans
+// @Produce json
+// @Security SessionAuth
+// @Param id path int true "Loan ID"
+// @Success 200 {object} LoanRecoveriesResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/loans/{id}/recoveries [get]
+func GetLoanRecoveries(c echo.Context) error {
+	loanID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid loan ID"})
+	}
+
+	loan, err := loanRepoHandler.GetByID(uint(loanID))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Loan not found"})
+	}
+
+	recoveries, err := writeOffRepo.GetRecoveries(loan.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch recoveries"})
+	}
+
+	response := LoanRecoveriesResponse{
+		LoanID:           loan.ID,
+		WrittenOffAmount: loan.WrittenOffAmount,
+		RecoveredAmount:  loan.RecoveredAmount,
+		Recoveries:       []RecoveryItem{},
+	}
+	for _, recovery := range recoveries {
+		response.Recoveries = append(response.Recoveries, RecoveryItem{
+			ID:            recovery.ID,
+			Amount:        recovery.Amount,
+			Reference:     recovery.Reference,
+			RecordedBy:    ManagerInfo{ID: recovery.RecordedBy.ID, Name: recovery.RecordedBy.Name},
+			TransactionID: recovery.TransactionID,
+			CreatedAt:     recovery.CreatedAt.Format(time.RFC3339),
+		})
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// GetLoanProvisioning godoc
+// @Summary Get the loan loss provisioning calculation
+// @Description Applies each aging bucket's provision rate to the outstanding balance of the active loans in it and compares the required allowance with the allowance held
+// @Tags provisioning
+// @Produce json
+// @Security SessionAuth
+// @Success 200 {object} ProvisioningResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/provisioning [get]
+func GetLoanProvisioning(c echo.Context) error {
+	provisioning, err := loanProvisioning(time.Now())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to calculate provisioning"})
+	}
+	return c.JSON(http.StatusOK, provisioning)
+}
+
+// RunLoanProvisioning godoc
+// @Summary Post the loan loss provision (manager)
+// @Description Brings the loan loss allowance to the required level, expensing an increase or releasing an excess, and anchors the adjustment as a loan_provision transaction. Nothing is posted when the allowance is already right. Also runs daily in the background.
+// @Tags provisioning
+// @Produce json
+// @Security SessionAuth
+// @Success 200 {object} RunProvisioningResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/provisioning/run [post]
+func RunLoanProvisioning(c echo.Context) error {
+	transactionID, provisioning, err := runProvisioning(time.Now())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to post provisioning"})
+	}
+
+	return c.JSON(http.StatusOK, RunProvisioningResponse{
+		OK:            true,
+		TransactionID: transactionID,
+		Adjustment:    provisioning.Adjustment,
+		Allowance:     provisioning.RequiredAllowance,
+	})
+}
+
+// UpdateProvisionRate godoc
+// @Summary Set the provision rate for an aging bucket (manager)
+// @Description Sets the percentage of outstanding balance provided for on loans in the bucket (current, 1-30, 31-60, 61-90 or 90+). Takes effect at the next provisioning run.
+// @Tags provisioning
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param bucket path string true "Aging bucket"
+// @Param request body UpdateProvisionRateRequest true "Provision rate"
+// @Success 200 {object} ProvisionRateItem
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/provisioning/rates/{bucket} [post]
+func UpdateProvisionRate(c echo.Context) error {
+	var req UpdateProvisionRateRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request"})
+	}
+	if req.Rate < 0 || req.Rate > 100 {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Rate must be between 0 and 100"})
+	}
+
+	rate, err := writeOffRepo.GetProvisionRate(c.Param("bucket"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Aging bucket not found"})
+	}
+
+	rate.Rate = req.Rate
+	if err := writeOffRepo.SaveProvisionRate(rate); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update provision rate"})
+	}
+
+	return c.JSON(http.StatusOK, ProvisionRateItem{Bucket: rate.Bucket, Rate: rate.Rate})
+}
@@ -0,0 +1,230 @@
+package lifecycle
+
+import (
+	"backend/src/db"
+	"errors"
+	"fmt"
+)
+
+const (
+	StatusRequested    = "Requested"
+	StatusUnderReview  = "UnderReview"
+	StatusApproved     = "Approved"
+	StatusRejected     = "Rejected"
+	StatusDisbursed    = "Disbursed"
+	StatusDelinquent   = "Delinquent"
+	StatusRestructured = "Restructured"
+	StatusPaidOff      = "PaidOff"
+	StatusWrittenOff   = "WrittenOff"
+	StatusCancelled    = "Cancelled"
+)
+
+// ErrInvalidTransition is wrapped by every error Check returns.
+var ErrInvalidTransition = errors.New("invalid loan status transition")
+
+// Guard rejects a transition the loan is not ready for.
+type Guard func(loan *db.Loan) error
+
+type Transition struct {
+	To    string
+	Guard Guard
+}
+
+func requireTerms(loan *db.Loan) error {
+	if loan.Principal <= 0 || loan.Duration <= 0 {
+		return errors.New("loan must have a principal and duration")
+	}
+	return nil
+}
+
+func requireApprover(loan *db.Loan) error {
+	if loan.ApprovedByID == nil {
+		return errors.New("loan has not been approved by a manager")
+	}
+	return nil
+}
+
+func requireNotDisbursed(loan *db.Loan) error {
+	if loan.DisbursedAt != nil || loan.PaidOutOnApproval {
+		return errors.New("loan has already been disbursed")
+	}
+	return nil
+}
+
+// requirePayable allows paying out an approved loan once, and never one
+// that was already paid out on approval.
+func requirePayable(loan *db.Loan) error {
+	if err := requireApprover(loan); err != nil {
+		return err
+	}
+	return requireNotDisbursed(loan)
+}
+
+// requirePaidOut allows a loan to go on to repayment states only once the
+// principal has reached the member.
+func requirePaidOut(loan *db.Loan) error {
+	if loan.DisbursedAt == nil && !loan.PaidOutOnApproval {
+		return errors.New("loan has not been paid out")
+	}
+	return nil
+}
+
+func requireSettled(loan *db.Loan) error {
+	if loan.OutstandingBalance > 0 {
+		return errors.New("loan still has an outstanding balance")
+	}
+	return nil
+}
+
+func requireOutstanding(loan *db.Loan) error {
+	if loan.OutstandingBalance <= 0 {
+		return errors.New("loan has no outstanding balance")
+	}
+	return nil
+}
+
+// all combines guards, stopping at the first that rejects the loan.
+func all(guards ...Guard) Guard {
+	return func(loan *db.Loan) error {
+		for _, guard := range guards {
+			if err := guard(loan); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// transitions lists, for every state, the states a loan may move to next.
+// States without an entry are terminal. Loans approved before disbursements
+// were recorded were paid out on approval, so an Approved loan that was paid
+// out may also go delinquent, be restructured, be paid off or be written off
+// directly. A delinquent loan returns to Disbursed when its arrears are
+// cleared; paying a loan out goes through CheckPayout. A restructured loan
+// may be restructured again.
+var transitions = map[string][]Transition{
+	StatusRequested: {
+		{StatusUnderReview, nil},
+		{StatusApproved, requireTerms},
+		{StatusRejected, nil},
+		{StatusCancelled, nil},
+	},
+	StatusUnderReview: {
+		{StatusApproved, requireTerms},
+		{StatusRejected, nil},
+		{StatusCancelled, nil},
+	},
+	StatusApproved: {
+		{StatusDisbursed, requirePayable},
+		{StatusCancelled, requireNotDisbursed},
+		{StatusDelinquent, all(requirePaidOut, requireOutstanding)},
+		{StatusRestructured, all(requirePaidOut, requireOutstanding)},
+		{StatusPaidOff, all(requirePaidOut, requireSettled)},
+		{StatusWrittenOff, all(requirePaidOut, requireOutstanding)},
+	},
+	StatusDisbursed: {
+		{StatusDelinquent, requireOutstanding},
+		{StatusRestructured, requireOutstanding},
+		{StatusPaidOff, requireSettled},
+		{StatusWrittenOff, requireOutstanding},
+	},
+	StatusDelinquent: {
+		{StatusDisbursed, requirePaidOut},
+		{StatusRestructured, requireOutstanding},
+		{StatusPaidOff, requireSettled},
+		{StatusWrittenOff, requireOutstanding},
+	},
+	StatusRestructured: {
+		{StatusDelinquent, requireOutstanding},
+		{StatusRestructured, requireOutstanding},
+		{StatusPaidOff, requireSettled},
+		{StatusWrittenOff, requireOutstanding},
+	},
+}
+
+// AllStatuses lists every loan state.
+var AllStatuses = []string{
+	StatusRequested, StatusUnderReview, StatusApproved, StatusRejected, StatusDisbursed,
+	StatusDelinquent, StatusRestructured, StatusPaidOff, StatusWrittenOff, StatusCancelled,
+}
+
+// ActiveStatuses are the states in which a loan is out with the member and
+// being repaid.
+var ActiveStatuses = []string{StatusApproved, StatusDisbursed, StatusDelinquent, StatusRestructured}
+
+// OpenStatuses are the states of loans that are pending or still being
+// repaid.
+var OpenStatuses = []string{StatusRequested, StatusUnderReview, StatusApproved, StatusDisbursed, StatusDelinquent, StatusRestructured}
+
+// PendingStatuses are the states of a loan request awaiting a decision.
+var PendingStatuses = []string{StatusRequested, StatusUnderReview}
+
+// InitialStatuses are the states a loan may be created in.
+var InitialStatuses = []string{StatusRequested, StatusUnderReview, StatusApproved}
+
+func contains(list []string, status string) bool {
+	for _, s := range list {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+func IsValid(status string) bool {
+	return contains(AllStatuses, status)
+}
+
+func IsActive(status string) bool {
+	return contains(ActiveStatuses, status)
+}
+
+func IsPending(status string) bool {
+	return contains(PendingStatuses, status)
+}
+
+func IsInitial(status string) bool {
+	return contains(InitialStatuses, status)
+}
+
+func IsTerminal(status string) bool {
+	return len(transitions[status]) == 0
+}
+
+// Allowed returns the states reachable from the given state.
+func Allowed(from string) []string {
+	var states []string
+	for _, t := range transitions[from] {
+		states = append(states, t.To)
+	}
+	return states
+}
+
+// Check reports whether the loan may move to the target state.
+func Check(loan *db.Loan, to string) error {
+	if !IsValid(to) {
+		return fmt.Errorf("%w: unknown loan status %s", ErrInvalidTransition, to)
+	}
+	for _, t := range transitions[loan.Status] {
+		if t.To != to {
+			continue
+		}
+		if t.Guard != nil {
+			if err := t.Guard(loan); err != nil {
+				return fmt.Errorf("%w: cannot move loan from %s to %s: %v", ErrInvalidTransition, loan.Status, to, err)
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: cannot move loan from %s to %s", ErrInvalidTransition, loan.Status, to)
+}
+
+// CheckPayout reports whether the loan may be paid out. Only an approved
+// loan that has not been paid out qualifies; Check alone would also let a
+// delinquent loan back to Disbursed.
+func CheckPayout(loan *db.Loan) error {
+	if loan.Status != StatusApproved {
+		return fmt.Errorf("%w: cannot pay out a loan that is %s", ErrInvalidTransition, loan.Status)
+	}
+	return Check(loan, StatusDisbursed)
+}
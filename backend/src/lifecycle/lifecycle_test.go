@@ -0,0 +1,85 @@
+package lifecycle
+
+import (
+	"backend/src/db"
+	"errors"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	approver := uint(1)
+	disbursedAt := int64(1700000000)
+
+	requested := db.Loan{Status: StatusRequested, Principal: 1000, Duration: 12}
+	approved := db.Loan{Status: StatusApproved, Principal: 1000, Duration: 12, OutstandingBalance: 1000, ApprovedByID: &approver}
+	disbursed := approved
+	disbursed.Status = StatusDisbursed
+	disbursed.DisbursedAt = &disbursedAt
+	paidOut := approved
+	paidOut.PaidOutOnApproval = true
+	settled := disbursed
+	settled.OutstandingBalance = 0
+	delinquent := disbursed
+	delinquent.Status = StatusDelinquent
+	unpaidDelinquent := delinquent
+	unpaidDelinquent.DisbursedAt = nil
+
+	tests := []struct {
+		name string
+		loan db.Loan
+		to   string
+		ok   bool
+	}{
+		{"request approved", requested, StatusApproved, true},
+		{"request without terms", db.Loan{Status: StatusRequested}, StatusApproved, false},
+		{"request disbursed", requested, StatusDisbursed, false},
+		{"approved paid out", approved, StatusDisbursed, true},
+		{"approved without approver", db.Loan{Status: StatusApproved, Principal: 1000, Duration: 12}, StatusDisbursed, false},
+		{"paid on approval paid out again", paidOut, StatusDisbursed, false},
+		{"approved cancelled", approved, StatusCancelled, true},
+		{"paid on approval cancelled", paidOut, StatusCancelled, false},
+		{"unpaid approved delinquent", approved, StatusDelinquent, false},
+		{"unpaid approved restructured", approved, StatusRestructured, false},
+		{"unpaid approved written off", approved, StatusWrittenOff, false},
+		{"paid on approval delinquent", paidOut, StatusDelinquent, true},
+		{"paid on approval written off", paidOut, StatusWrittenOff, true},
+		{"disbursed delinquent", disbursed, StatusDelinquent, true},
+		{"disbursed paid off with balance", disbursed, StatusPaidOff, false},
+		{"disbursed settled paid off", settled, StatusPaidOff, true},
+		{"delinquent cured", delinquent, StatusDisbursed, true},
+		{"unpaid delinquent cured", unpaidDelinquent, StatusDisbursed, false},
+		{"paid off reopened", db.Loan{Status: StatusPaidOff}, StatusDisbursed, false},
+		{"unknown status", requested, "Lost", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Check(&tt.loan, tt.to)
+			if tt.ok && err != nil {
+				t.Errorf("Check(%s -> %s) returned error: %v", tt.loan.Status, tt.to, err)
+			}
+			if !tt.ok && !errors.Is(err, ErrInvalidTransition) {
+				t.Errorf("Check(%s -> %s) = %v, want ErrInvalidTransition", tt.loan.Status, tt.to, err)
+			}
+		})
+	}
+}
+
+func TestCheckPayout(t *testing.T) {
+	approver := uint(1)
+	disbursedAt := int64(1700000000)
+
+	approved := db.Loan{Status: StatusApproved, Principal: 1000, Duration: 12, OutstandingBalance: 1000, ApprovedByID: &approver}
+	delinquent := approved
+	delinquent.Status = StatusDelinquent
+	delinquent.DisbursedAt = &disbursedAt
+
+	if err := CheckPayout(&approved); err != nil {
+		t.Errorf("CheckPayout(approved) returned error: %v", err)
+	}
+	if err := Check(&delinquent, StatusDisbursed); err != nil {
+		t.Fatalf("Check(delinquent -> Disbursed) returned error: %v", err)
+	}
+	if err := CheckPayout(&delinquent); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("CheckPayout(delinquent) = %v, want ErrInvalidTransition", err)
+	}
+}
@@ -50,6 +50,10 @@ func main() {
 	}
 
 	handlers.InitAuthHandlers()
+	if err := handlers.InitDocumentStorage(); err != nil {
+		log.Fatal("Failed to initialize document storage:", err)
+	}
+	handlers.StartBackgroundJobs()
 
 	e := echo.New()
 	e.Use(middleware.Logger())
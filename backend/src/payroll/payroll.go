@@ -0,0 +1,149 @@
+package payroll
+
+import (
+	"backend/src/tabular"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	FormatCSV  = tabular.FormatCSV
+	FormatXLSX = tabular.FormatXLSX
+)
+
+// Row is one member's line of an employer remittance. Amounts are in the
+// same units as db.Deposit.Amount. A row that cannot be read carries the
+// reason in Error instead of failing the whole file.
+type Row struct {
+	Line      int
+	MemberID  uint
+	Phone     string
+	LoanID    uint
+	Repayment int
+	Savings   int
+	Reference string
+	Error     string
+}
+
+// DetectFormat guesses the file format from its name, falling back to
+// sniffing the content.
+func DetectFormat(filename string, data []byte) string {
+	return tabular.DetectFormat(filename, data)
+}
+
+// Parse reads a remittance in the given format. decimal is the decimal mark
+// of its amounts; when empty it is worked out from the file.
+func Parse(format string, data []byte, decimal string) ([]Row, error) {
+	switch format {
+	case FormatCSV:
+		return ParseCSV(data, decimal)
+	case FormatXLSX:
+		return ParseXLSX(data, decimal)
+	}
+	return nil, fmt.Errorf("unsupported payroll format: %s", format)
+}
+
+var columns = tabular.Columns{
+	"member_id": {"member_id", "member id", "member no", "member number", "user_id"},
+	"phone":     {"phone", "phone number", "phone_number", "mobile"},
+	"loan_id":   {"loan_id", "loan id", "loan no", "loan number"},
+	"repayment": {"repayment", "loan repayment", "loan_repayment", "loan deduction"},
+	"savings":   {"savings", "savings deposit", "savings_deposit", "savings deduction", "deposit"},
+	"reference": {"reference", "ref", "employee no", "employee number", "payroll no", "staff no"},
+}
+
+// ParseCSV reads a header-row CSV remittance.
+func ParseCSV(data []byte, decimal string) ([]Row, error) {
+	records, err := tabular.ReadCSV(data)
+	if err != nil {
+		return nil, err
+	}
+	return parseRecords(records, decimal)
+}
+
+// ParseXLSX reads the first sheet of a workbook laid out like the CSV.
+func ParseXLSX(data []byte, decimal string) ([]Row, error) {
+	records, err := tabular.ReadXLSX(data)
+	if err != nil {
+		return nil, err
+	}
+	return parseRecords(records, decimal)
+}
+
+// parseRecords maps the header row to known columns, matched
+// case-insensitively, and reads every following non-blank row. Members are
+// identified by member ID or phone number; repayment and savings are each
+// optional but a file must have at least one of them. Amounts are read with
+// the given decimal mark, or the one the file's amounts show when it is
+// empty; an amount that could be read either way is reported on its row.
+func parseRecords(records [][]string, decimal string) ([]Row, error) {
+	if len(records) == 0 {
+		return nil, fmt.Errorf("file is missing a header row")
+	}
+
+	header := columns.Match(records[0])
+	if !header.Has("member_id") && !header.Has("phone") {
+		return nil, fmt.Errorf("file is missing a member_id or phone column")
+	}
+	if !header.Has("repayment") && !header.Has("savings") {
+		return nil, fmt.Errorf("file is missing a repayment or savings column")
+	}
+
+	if decimal == "" {
+		var amounts []string
+		for _, record := range records[1:] {
+			amounts = append(amounts, header.Field(record, "repayment"), header.Field(record, "savings"))
+		}
+		var err error
+		if decimal, err = tabular.DetectDecimal(amounts); err != nil {
+			return nil, err
+		}
+	}
+
+	var rows []Row
+	for i, record := range records[1:] {
+		if tabular.Blank(record) {
+			continue
+		}
+
+		row := Row{
+			Line:      i + 2,
+			Phone:     header.Field(record, "phone"),
+			Reference: header.Field(record, "reference"),
+		}
+		var problems []string
+		if raw := header.Field(record, "member_id"); raw != "" {
+			id, err := strconv.ParseUint(raw, 10, 32)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("unrecognised member ID: %q", raw))
+			}
+			row.MemberID = uint(id)
+		}
+		if raw := header.Field(record, "loan_id"); raw != "" {
+			id, err := strconv.ParseUint(strings.TrimPrefix(raw, "#"), 10, 32)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("unrecognised loan ID: %q", raw))
+			}
+			row.LoanID = uint(id)
+		}
+		if raw := header.Field(record, "repayment"); raw != "" {
+			amount, err := tabular.ParseAmount(raw, decimal)
+			if err != nil {
+				problems = append(problems, err.Error())
+			}
+			row.Repayment = amount
+		}
+		if raw := header.Field(record, "savings"); raw != "" {
+			amount, err := tabular.ParseAmount(raw, decimal)
+			if err != nil {
+				problems = append(problems, err.Error())
+			}
+			row.Savings = amount
+		}
+		row.Error = strings.Join(problems, "; ")
+
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
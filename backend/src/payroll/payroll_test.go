@@ -0,0 +1,141 @@
+package payroll
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestParseCSV(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []Row
+	}{
+		{
+			name: "member IDs",
+			data: "Member ID,Loan No,Loan Repayment,Savings Deposit,Staff No\n" +
+				"7,#12,\"4,500.00\",1000,E-01\n" +
+				"8,,,250,E-02\n",
+			want: []Row{
+				{Line: 2, MemberID: 7, LoanID: 12, Repayment: 4500, Savings: 1000, Reference: "E-01"},
+				{Line: 3, MemberID: 8, Savings: 250, Reference: "E-02"},
+			},
+		},
+		{
+			name: "phones and decimal commas",
+			data: "\ufeffMOBILE,Repayment\n" +
+				"0700000001,\"1.234,56\"\n" +
+				",\n" +
+				"0700000002,\"12,50\"\n",
+			want: []Row{
+				{Line: 2, Phone: "0700000001", Repayment: 1235},
+				{Line: 4, Phone: "0700000002", Repayment: 13},
+			},
+		},
+		{
+			name: "unreadable rows",
+			data: "member_id,loan_id,repayment,savings\n" +
+				"x,1,100,\n" +
+				"3,loan,abc,1 000\n",
+			want: []Row{
+				{Line: 2, LoanID: 1, Repayment: 100, Error: `unrecognised member ID: "x"`},
+				{Line: 3, MemberID: 3, Savings: 1000,
+					Error: `unrecognised loan ID: "loan"; unrecognised amount: "abc"`},
+			},
+		},
+		{
+			name: "ambiguous amounts",
+			data: "member_id,savings\n" +
+				"4,1.500\n" +
+				"5,500\n",
+			want: []Row{
+				{Line: 2, MemberID: 4, Error: `ambiguous amount "1.500": set the decimal mark`},
+				{Line: 3, MemberID: 5, Savings: 500},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := ParseCSV([]byte(tt.data), "")
+			if err != nil {
+				t.Fatalf("ParseCSV returned error: %v", err)
+			}
+			if len(rows) != len(tt.want) {
+				t.Fatalf("ParseCSV returned %d rows, want %d", len(rows), len(tt.want))
+			}
+			for i, row := range rows {
+				if row != tt.want[i] {
+					t.Errorf("row %d = %+v, want %+v", i+1, row, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseCSVRejectsMissingColumns(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"empty file", "", "missing a header row"},
+		{"no member column", "loan_id,repayment\n1,100\n", "missing a member_id or phone column"},
+		{"no amount column", "member_id,reference\n1,E-01\n", "missing a repayment or savings column"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseCSV([]byte(tt.data), "")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseCSV error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseXLSX(t *testing.T) {
+	f := excelize.NewFile()
+	defer f.Close()
+	sheet := f.GetSheetName(0)
+	records := [][]interface{}{
+		{"Phone Number", "Loan Deduction", "Savings Deduction"},
+		{"0700000001", 4500, "1,000"},
+		{},
+		{"0700000002", "", 250.4},
+	}
+	for i, record := range records {
+		cell, err := excelize.CoordinatesToCellName(1, i+1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := f.SetSheetRow(sheet, cell, &record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if format := DetectFormat("remittance", buf.Bytes()); format != FormatXLSX {
+		t.Fatalf("DetectFormat = %s, want %s", format, FormatXLSX)
+	}
+	rows, err := Parse(FormatXLSX, buf.Bytes(), "")
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	want := []Row{
+		{Line: 2, Phone: "0700000001", Repayment: 4500, Savings: 1000},
+		{Line: 4, Phone: "0700000002", Savings: 250},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("Parse returned %d rows, want %d", len(rows), len(want))
+	}
+	for i, row := range rows {
+		if row != want[i] {
+			t.Errorf("row %d = %+v, want %+v", i+1, row, want[i])
+		}
+	}
+}
@@ -0,0 +1,197 @@
+// Package pdf writes simple single-font PDF documents: text, lines and
+// filled rectangles on A4 pages using the standard Helvetica fonts, so no
+// font files need to be embedded.
+//
+// The standard fonts only cover the Windows-1252 (WinAnsi) character set.
+// Text is encoded to it; letters outside it are written without their
+// accents where possible and as "?" otherwise.
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	PageWidth  = 595.28
+	PageHeight = 841.89
+)
+
+type Color struct{ R, G, B float64 }
+
+var (
+	Black = Color{0, 0, 0}
+	White = Color{1, 1, 1}
+	Grey  = Color{0.45, 0.45, 0.45}
+)
+
+type Document struct {
+	pages []*bytes.Buffer
+	page  *bytes.Buffer
+}
+
+func New() *Document {
+	d := &Document{}
+	d.AddPage()
+	return d
+}
+
+func (d *Document) AddPage() {
+	d.page = &bytes.Buffer{}
+	d.pages = append(d.pages, d.page)
+}
+
+func (d *Document) PageCount() int {
+	return len(d.pages)
+}
+
+// Text draws s with its baseline at (x, y), measured in points from the top
+// left corner of the page.
+func (d *Document) Text(x, y float64, size float64, bold bool, color Color, s string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(d.page, "BT %.3f %.3f %.3f rg /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n",
+		color.R, color.G, color.B, font, size, x, PageHeight-y, escape(s))
+}
+
+// TextRight draws s so that it ends at x.
+func (d *Document) TextRight(x, y float64, size float64, bold bool, color Color, s string) {
+	d.Text(x-TextWidth(s, size, bold), y, size, bold, color, s)
+}
+
+func (d *Document) Rect(x, y, w, h float64, fill Color) {
+	fmt.Fprintf(d.page, "%.3f %.3f %.3f rg %.2f %.2f %.2f %.2f re f\n",
+		fill.R, fill.G, fill.B, x, PageHeight-y-h, w, h)
+}
+
+func (d *Document) Line(x1, y1, x2, y2 float64, width float64, color Color) {
+	fmt.Fprintf(d.page, "%.3f %.3f %.3f RG %.2f w %.2f %.2f m %.2f %.2f l S\n",
+		color.R, color.G, color.B, width, x1, PageHeight-y1, x2, PageHeight-y2)
+}
+
+// TextWidth approximates the rendered width of s. Helvetica averages about
+// half an em per glyph; bold runs slightly wider.
+func TextWidth(s string, size float64, bold bool) float64 {
+	factor := 0.52
+	if bold {
+		factor = 0.56
+	}
+	return float64(utf8.RuneCountInString(s)) * size * factor
+}
+
+// Truncate shortens s so it fits within width, marking the cut with "..".
+func Truncate(s string, width, size float64, bold bool) string {
+	if TextWidth(s, size, bold) <= width {
+		return s
+	}
+	for len(s) > 0 && TextWidth(s+"..", size, bold) > width {
+		_, last := utf8.DecodeLastRuneInString(s)
+		s = s[:len(s)-last]
+	}
+	return s + ".."
+}
+
+// winAnsi holds the WinAnsi codes of the characters in 0x80-0x9F. The rest
+// of Latin-1 keeps its code.
+var winAnsi = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87,
+	'ˆ': 0x88, '‰': 0x89, 'Š': 0x8A, '‹': 0x8B, 'Œ': 0x8C, 'Ž': 0x8E,
+	'‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97,
+	'˜': 0x98, '™': 0x99, 'š': 0x9A, '›': 0x9B, 'œ': 0x9C, 'ž': 0x9E, 'Ÿ': 0x9F,
+}
+
+// transliterations spells Latin letters missing from WinAnsi without their
+// accents.
+var transliterations = map[rune]string{
+	'Ā': "A", 'ā': "a", 'Ă': "A", 'ă': "a", 'Ą': "A", 'ą': "a",
+	'Ć': "C", 'ć': "c", 'Ĉ': "C", 'ĉ': "c", 'Ċ': "C", 'ċ': "c", 'Č': "C", 'č': "c",
+	'Ď': "D", 'ď': "d", 'Đ': "D", 'đ': "d",
+	'Ē': "E", 'ē': "e", 'Ĕ': "E", 'ĕ': "e", 'Ė': "E", 'ė': "e", 'Ę': "E", 'ę': "e", 'Ě': "E", 'ě': "e",
+	'Ĝ': "G", 'ĝ': "g", 'Ğ': "G", 'ğ': "g", 'Ġ': "G", 'ġ': "g", 'Ģ': "G", 'ģ': "g",
+	'Ĥ': "H", 'ĥ': "h", 'Ħ': "H", 'ħ': "h",
+	'Ĩ': "I", 'ĩ': "i", 'Ī': "I", 'ī': "i", 'Ĭ': "I", 'ĭ': "i", 'Į': "I", 'į': "i", 'İ': "I", 'ı': "i",
+	'Ĵ': "J", 'ĵ': "j", 'Ķ': "K", 'ķ': "k",
+	'Ĺ': "L", 'ĺ': "l", 'Ļ': "L", 'ļ': "l", 'Ľ': "L", 'ľ': "l", 'Ŀ': "L", 'ŀ': "l", 'Ł': "L", 'ł': "l",
+	'Ń': "N", 'ń': "n", 'Ņ': "N", 'ņ': "n", 'Ň': "N", 'ň': "n",
+	'Ō': "O", 'ō': "o", 'Ŏ': "O", 'ŏ': "o", 'Ő': "O", 'ő': "o",
+	'Ŕ': "R", 'ŕ': "r", 'Ŗ': "R", 'ŗ': "r", 'Ř': "R", 'ř': "r",
+	'Ś': "S", 'ś': "s", 'Ŝ': "S", 'ŝ': "s", 'Ş': "S", 'ş': "s", 'Ș': "S", 'ș': "s",
+	'Ţ': "T", 'ţ': "t", 'Ť': "T", 'ť': "t", 'Ț': "T", 'ț': "t", 'Ŧ': "T", 'ŧ': "t",
+	'Ũ': "U", 'ũ': "u", 'Ū': "U", 'ū': "u", 'Ŭ': "U", 'ŭ': "u", 'Ů': "U", 'ů': "u", 'Ű': "U", 'ű': "u", 'Ų': "U", 'ų': "u",
+	'Ŵ': "W", 'ŵ': "w", 'Ŷ': "Y", 'ŷ': "y",
+	'Ź': "Z", 'ź': "z", 'Ż': "Z", 'ż': "z",
+}
+
+// escape encodes s as a WinAnsi PDF string body. Bytes outside printable
+// ASCII are written as octal escapes so the content stream stays ASCII.
+func escape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 32 && r <= 126:
+			b.WriteRune(r)
+		case r >= 0xA0 && r <= 0xFF:
+			fmt.Fprintf(&b, "\\%03o", r)
+		case winAnsi[r] != 0:
+			fmt.Fprintf(&b, "\\%03o", winAnsi[r])
+		case transliterations[r] != "":
+			b.WriteString(transliterations[r])
+		case unicode.Is(unicode.Mn, r):
+			// A combining accent has nothing to sit on once its letter
+			// is replaced, so it is dropped.
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// Write serialises the document.
+func (d *Document) Write(w io.Writer) error {
+	var out bytes.Buffer
+	var offsets []int
+
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n")
+
+	// Objects 1-4 are fixed: catalog, page tree, regular and bold fonts.
+	// Each page then contributes a page object followed by its content stream.
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+i*2)
+	}
+
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	for i, page := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			PageWidth, PageHeight, 6+i*2))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.Len(), page.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	_, err := w.Write(out.Bytes())
+	return err
+}
@@ -0,0 +1,52 @@
+package pdf
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Plain (text) \\ here", `Plain \(text\) \\ here`},
+		{"José Müller", `Jos\351 M\374ller`},
+		{"Fee – €5 “due”", `Fee \226 \2005 \223due\224`},
+		{"Łukasz Wałęsa", "Lukasz Walesa"},
+		{"Ngozi Ọ̀kọ́", "Ngozi ?k?"},
+		{"王伟", "??"},
+		{"tab\there", "tab?here"},
+	}
+	for _, tt := range tests {
+		if got := escape(tt.in); got != tt.want {
+			t.Errorf("escape(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestTextWritesWinAnsi(t *testing.T) {
+	doc := New()
+	doc.Text(10, 10, 9, false, Black, "Zoë Ñúñez")
+
+	var out bytes.Buffer
+	if err := doc.Write(&out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `(Zo\353 \321\372\361ez) Tj`) {
+		t.Errorf("content stream does not carry the WinAnsi text:\n%s", out.String())
+	}
+	for _, c := range out.Bytes() {
+		if c > 126 {
+			t.Fatalf("document contains non-ASCII byte %#x", c)
+		}
+	}
+}
+
+func TestTruncateKeepsWholeCharacters(t *testing.T) {
+	got := Truncate("Amélie Öztürk-Smith", TextWidth("Amélie Ö..", 8, false), 8, false)
+	if got != "Amélie Ö.." {
+		t.Errorf("Truncate = %q, want %q", got, "Amélie Ö..")
+	}
+}
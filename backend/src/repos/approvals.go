@@ -0,0 +1,71 @@
+package repos
+
+import (
+	"backend/src/db"
+)
+
+type ApprovalRepo struct{}
+
+func (ApprovalRepo) GetPolicies() ([]db.ApprovalPolicy, error) {
+	var policies []db.ApprovalPolicy
+	err := db.DB.Preload("Committee").Preload("UpdatedBy").Order("min_amount ASC").Find(&policies).Error
+	return policies, err
+}
+
+func (ApprovalRepo) GetPolicy(policyID uint) (*db.ApprovalPolicy, error) {
+	var policy db.ApprovalPolicy
+	err := db.DB.Preload("Committee").Preload("UpdatedBy").First(&policy, policyID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// GetPolicyForAmount returns the active band with the highest minimum at or
+// below the amount.
+func (ApprovalRepo) GetPolicyForAmount(amount int) (*db.ApprovalPolicy, error) {
+	var policy db.ApprovalPolicy
+	err := db.DB.Preload("Committee").Where("is_active = ? AND min_amount <= ?", true, amount).
+		Order("min_amount DESC").First(&policy).Error
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func (ApprovalRepo) MinAmountTaken(minAmount int, exceptID uint) (bool, error) {
+	var count int64
+	err := db.DB.Model(&db.ApprovalPolicy{}).Where("min_amount = ? AND id <> ?", minAmount, exceptID).Count(&count).Error
+	return count > 0, err
+}
+
+// Save creates or updates the policy and replaces its committee.
+func (ApprovalRepo) Save(policy *db.ApprovalPolicy) error {
+	tx := db.DB.Begin()
+	if err := tx.Omit("Committee", "UpdatedBy").Save(policy).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Model(policy).Association("Committee").Replace(policy.Committee); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+func (ApprovalRepo) CreateVote(vote *db.LoanApprovalVote) error {
+	return db.DB.Create(vote).Error
+}
+
+// GetVotes returns the votes on a loan oldest first, limited to one request
+// version when given.
+func (ApprovalRepo) GetVotes(loanID uint, requestVersion *int) ([]db.LoanApprovalVote, error) {
+	query := db.DB.Preload("Voter").Where("loan_id = ?", loanID).Order("id ASC")
+	if requestVersion != nil {
+		query = query.Where("request_version = ?", *requestVersion)
+	}
+
+	var votes []db.LoanApprovalVote
+	err := query.Find(&votes).Error
+	return votes, err
+}
@@ -0,0 +1,47 @@
+package repos
+
+import (
+	"backend/src/db"
+)
+
+type CollateralRepo struct{}
+
+func (CollateralRepo) Create(collateral *db.Collateral) error {
+	return db.DB.Create(collateral).Error
+}
+
+func (CollateralRepo) GetByID(collateralID uint) (*db.Collateral, error) {
+	var collateral db.Collateral
+	err := db.DB.Preload("Loan").First(&collateral, collateralID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &collateral, nil
+}
+
+func (CollateralRepo) GetByLoan(loanID uint) ([]db.Collateral, error) {
+	var collateral []db.Collateral
+	err := db.DB.Where("loan_id = ?", loanID).Order("id ASC").Find(&collateral).Error
+	return collateral, err
+}
+
+// GetPledgedValue sums the valuations of collateral still under lien for a loan.
+func (CollateralRepo) GetPledgedValue(loanID uint) (int, error) {
+	var total int
+	err := db.DB.Model(&db.Collateral{}).Select("COALESCE(SUM(valuation), 0)").
+		Where("loan_id = ? AND lien_status = ?", loanID, db.LienStatusActive).Scan(&total).Error
+	return total, err
+}
+
+func (CollateralRepo) Save(collateral *db.Collateral) error {
+	return db.DB.Omit("Loan", "RegisteredBy").Save(collateral).Error
+}
+
+func (CollateralRepo) GetTransaction(transactionID string) (*db.Transaction, error) {
+	var transaction db.Transaction
+	err := db.DB.Where("transaction_id = ?", transactionID).First(&transaction).Error
+	if err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
@@ -0,0 +1,32 @@
+package repos
+
+import (
+	"backend/src/db"
+)
+
+type CommentRepo struct{}
+
+func (CommentRepo) Create(comment *db.LoanComment) error {
+	return db.DB.Create(comment).Error
+}
+
+// GetByLoan returns the comments on a loan oldest first, leaving out
+// internal notes unless asked for them.
+func (CommentRepo) GetByLoan(loanID uint, includeInternal bool) ([]db.LoanComment, error) {
+	query := db.DB.Preload("Author").Where("loan_id = ?", loanID).Order("id ASC")
+	if !includeInternal {
+		query = query.Where("internal = ?", false)
+	}
+
+	var comments []db.LoanComment
+	err := query.Find(&comments).Error
+	return comments, err
+}
+
+// GetAuthors returns everyone who has commented on a loan.
+func (CommentRepo) GetAuthors(loanID uint) ([]db.User, error) {
+	var users []db.User
+	err := db.DB.Where("id IN (?)", db.DB.Model(&db.LoanComment{}).Select("author_id").Where("loan_id = ?", loanID)).
+		Order("id ASC").Find(&users).Error
+	return users, err
+}
@@ -0,0 +1,63 @@
+package repos
+
+import (
+	"backend/src/db"
+)
+
+type DocumentRepo struct{}
+
+type DocumentFilter struct {
+	LoanID   *uint
+	MemberID *uint
+	// OwnerID limits the results to documents on the member themselves or
+	// on loans they borrowed.
+	OwnerID *uint
+}
+
+func (DocumentRepo) Create(document *db.Document) error {
+	return db.DB.Create(document).Error
+}
+
+func (DocumentRepo) GetByID(documentID uint) (*db.Document, error) {
+	var document db.Document
+	err := db.DB.Preload("Loan").Preload("UploadedBy").First(&document, documentID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &document, nil
+}
+
+func (DocumentRepo) GetAll(filter DocumentFilter) ([]db.Document, error) {
+	query := db.DB.Preload("Loan").Preload("UploadedBy").Order("id DESC")
+	if filter.LoanID != nil {
+		query = query.Where("loan_id = ?", *filter.LoanID)
+	}
+	if filter.MemberID != nil {
+		query = query.Where("member_id = ?", *filter.MemberID)
+	}
+	if filter.OwnerID != nil {
+		query = query.Where("member_id = ? OR loan_id IN (?)", *filter.OwnerID,
+			db.DB.Model(&db.Loan{}).Select("id").Where("borrower_id = ?", *filter.OwnerID))
+	}
+
+	var documents []db.Document
+	err := query.Find(&documents).Error
+	return documents, err
+}
+
+func (DocumentRepo) UpdateTransaction(documentID uint, transactionID string) error {
+	return db.DB.Model(&db.Document{}).Where("id = ?", documentID).Update("transaction_id", transactionID).Error
+}
+
+func (DocumentRepo) Delete(documentID uint) error {
+	return db.DB.Unscoped().Delete(&db.Document{}, documentID).Error
+}
+
+func (DocumentRepo) GetTransaction(transactionID string) (*db.Transaction, error) {
+	var transaction db.Transaction
+	err := db.DB.Where("transaction_id = ?", transactionID).First(&transaction).Error
+	if err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
@@ -0,0 +1,26 @@
+package repos
+
+import (
+	"backend/src/db"
+)
+
+type EligibilityRepo struct{}
+
+func (EligibilityRepo) GetRules() ([]db.EligibilityRule, error) {
+	var rules []db.EligibilityRule
+	err := db.DB.Order("id ASC").Find(&rules).Error
+	return rules, err
+}
+
+func (EligibilityRepo) GetRuleByCode(code string) (*db.EligibilityRule, error) {
+	var rule db.EligibilityRule
+	err := db.DB.Where("code = ?", code).First(&rule).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (EligibilityRepo) SaveRule(rule *db.EligibilityRule) error {
+	return db.DB.Save(rule).Error
+}
@@ -0,0 +1,141 @@
+package repos
+
+import (
+	"backend/src/db"
+)
+
+type FeeRepo struct{}
+
+func (FeeRepo) GetTypes() ([]db.FeeType, error) {
+	var feeTypes []db.FeeType
+	err := db.DB.Order("id ASC").Find(&feeTypes).Error
+	return feeTypes, err
+}
+
+func (FeeRepo) GetTypeByCode(code string) (*db.FeeType, error) {
+	var feeType db.FeeType
+	if err := db.DB.Where("code = ?", code).First(&feeType).Error; err != nil {
+		return nil, err
+	}
+	return &feeType, nil
+}
+
+func (FeeRepo) GetActiveTypesByTrigger(trigger string) ([]db.FeeType, error) {
+	var feeTypes []db.FeeType
+	err := db.DB.Where("trigger_rule = ? AND is_active = ?", trigger, true).Order("id ASC").Find(&feeTypes).Error
+	return feeTypes, err
+}
+
+func (FeeRepo) SaveType(feeType *db.FeeType) error {
+	return db.DB.Save(feeType).Error
+}
+
+func (FeeRepo) ChargeExists(chargeKey string) (bool, error) {
+	var count int64
+	err := db.DB.Model(&db.FeeCharge{}).Where("charge_key = ?", chargeKey).Count(&count).Error
+	return count > 0, err
+}
+
+func (FeeRepo) CreateCharge(charge *db.FeeCharge) error {
+	return db.DB.Create(charge).Error
+}
+
+func (FeeRepo) GetCharge(chargeID uint) (*db.FeeCharge, error) {
+	var charge db.FeeCharge
+	err := db.DB.Preload("FeeType").Preload("User").Preload("WaivedBy").First(&charge, chargeID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &charge, nil
+}
+
+// GetCharges lists charges newest first. A zero userID or empty status
+// leaves that filter off.
+func (FeeRepo) GetCharges(userID uint, status string) ([]db.FeeCharge, error) {
+	query := db.DB.Preload("FeeType").Preload("User").Preload("WaivedBy")
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var charges []db.FeeCharge
+	err := query.Order("created_at DESC, id DESC").Find(&charges).Error
+	return charges, err
+}
+
+func (FeeRepo) MarkWaived(charge *db.FeeCharge) error {
+	return db.DB.Model(charge).Updates(map[string]interface{}{
+		"status":                db.FeeStatusWaived,
+		"waived_amount":         charge.WaivedAmount,
+		"waived_by_id":          charge.WaivedByID,
+		"waived_at":             charge.WaivedAt,
+		"waiver_reason":         charge.WaiverReason,
+		"waiver_transaction_id": charge.WaiverTransactionID,
+	}).Error
+}
+
+// GetTotalCollected returns the fees actually collected and kept. Charged
+// and paid fees were collected in full. Outstanding and written-off fees
+// count only what was paid towards them, as do waived loan fees. A waived
+// savings fee was refunded, so none of it counts.
+func (FeeRepo) GetTotalCollected() (int64, error) {
+	var total int64
+	err := db.DB.Model(&db.FeeCharge{}).
+		Select(`COALESCE(SUM(CASE
+			WHEN status IN ? THEN amount
+			WHEN status = ? AND loan_id IS NULL THEN 0
+			ELSE paid_amount END), 0)`,
+			[]string{db.FeeStatusCharged, db.FeeStatusPaid}, db.FeeStatusWaived).
+		Scan(&total).Error
+	return total, err
+}
+
+// GetOutstandingLoanCharges returns the unpaid fees on a loan, oldest first.
+func (FeeRepo) GetOutstandingLoanCharges(loanID uint) ([]db.FeeCharge, error) {
+	var charges []db.FeeCharge
+	err := db.DB.Preload("FeeType").
+		Where("loan_id = ? AND status = ?", loanID, db.FeeStatusOutstanding).
+		Order("created_at ASC, id ASC").Find(&charges).Error
+	return charges, err
+}
+
+// GetOutstandingSavingsCharges returns the member's fees that savings could
+// not yet cover, oldest first.
+func (FeeRepo) GetOutstandingSavingsCharges(userID uint) ([]db.FeeCharge, error) {
+	var charges []db.FeeCharge
+	err := db.DB.Preload("FeeType").
+		Where("user_id = ? AND loan_id IS NULL AND status = ?", userID, db.FeeStatusOutstanding).
+		Order("created_at ASC, id ASC").Find(&charges).Error
+	return charges, err
+}
+
+func (FeeRepo) GetUsersWithOutstandingSavingsCharges() ([]uint, error) {
+	var userIDs []uint
+	err := db.DB.Model(&db.FeeCharge{}).
+		Where("loan_id IS NULL AND status = ?", db.FeeStatusOutstanding).
+		Distinct().Pluck("user_id", &userIDs).Error
+	return userIDs, err
+}
+
+// MarkLoanChargesWrittenOff closes the unpaid fees on a loan being written
+// off.
+func (FeeRepo) MarkLoanChargesWrittenOff(loanID uint) error {
+	return db.DB.Model(&db.FeeCharge{}).
+		Where("loan_id = ? AND status = ?", loanID, db.FeeStatusOutstanding).
+		Update("status", db.FeeStatusWrittenOff).Error
+}
+
+func (FeeRepo) SaveChargePayment(charge *db.FeeCharge) error {
+	return db.DB.Model(charge).Updates(map[string]interface{}{
+		"paid_amount": charge.PaidAmount,
+		"status":      charge.Status,
+	}).Error
+}
+
+func (FeeRepo) GetActiveMembers() ([]db.User, error) {
+	var users []db.User
+	err := db.DB.Where("role = ? AND is_active = ?", "member", true).Order("id ASC").Find(&users).Error
+	return users, err
+}
@@ -0,0 +1,63 @@
+package repos
+
+import (
+	"backend/src/db"
+	"backend/src/lifecycle"
+	"slices"
+)
+
+type GuaranteeRepo struct{}
+
+func (GuaranteeRepo) Create(guarantee *db.LoanGuarantee) error {
+	return db.DB.Create(guarantee).Error
+}
+
+func (GuaranteeRepo) GetByID(guaranteeID uint) (*db.LoanGuarantee, error) {
+	var guarantee db.LoanGuarantee
+	err := db.DB.Preload("Loan").Preload("Loan.Borrower").Preload("Guarantor").First(&guarantee, guaranteeID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &guarantee, nil
+}
+
+func (GuaranteeRepo) GetByLoan(loanID uint) ([]db.LoanGuarantee, error) {
+	var guarantees []db.LoanGuarantee
+	err := db.DB.Where("loan_id = ?", loanID).Preload("Guarantor").Order("id ASC").Find(&guarantees).Error
+	return guarantees, err
+}
+
+func (GuaranteeRepo) GetByGuarantor(guarantorID uint) ([]db.LoanGuarantee, error) {
+	var guarantees []db.LoanGuarantee
+	err := db.DB.Where("guarantor_id = ?", guarantorID).Preload("Loan").Preload("Loan.Borrower").
+		Order("id DESC").Find(&guarantees).Error
+	return guarantees, err
+}
+
+// GetAccepted returns accepted guarantees on loans that are still open or
+// have been written off, optionally for a single guarantor. Guarantees on a
+// written-off loan stay accepted until they are released.
+func (GuaranteeRepo) GetAccepted(guarantorID uint) ([]db.LoanGuarantee, error) {
+	var guarantees []db.LoanGuarantee
+	statuses := append(slices.Clone(lifecycle.OpenStatuses), lifecycle.StatusWrittenOff)
+	query := db.DB.Joins("JOIN loans ON loans.id = loan_guarantees.loan_id").
+		Where("loan_guarantees.status = ? AND loans.status IN ?", db.GuaranteeStatusAccepted, statuses)
+	if guarantorID != 0 {
+		query = query.Where("loan_guarantees.guarantor_id = ?", guarantorID)
+	}
+	err := query.Preload("Loan").Preload("Guarantor").Order("loan_guarantees.guarantor_id ASC, loan_guarantees.id ASC").
+		Find(&guarantees).Error
+	return guarantees, err
+}
+
+// CountAccepted returns how many guarantors have accepted on the loan.
+func (GuaranteeRepo) CountAccepted(loanID uint) (int, error) {
+	var count int64
+	err := db.DB.Model(&db.LoanGuarantee{}).
+		Where("loan_id = ? AND status = ?", loanID, db.GuaranteeStatusAccepted).Count(&count).Error
+	return int(count), err
+}
+
+func (GuaranteeRepo) Save(guarantee *db.LoanGuarantee) error {
+	return db.DB.Omit("Loan", "Guarantor").Save(guarantee).Error
+}
@@ -0,0 +1,128 @@
+package repos
+
+import (
+	"backend/src/accounting"
+	"backend/src/db"
+	"fmt"
+	"time"
+)
+
+type LedgerRepo struct{}
+
+// GetTransactions returns completed transactions posted in [from, to). Either
+// bound may be nil.
+func (LedgerRepo) GetTransactions(from, to *time.Time) ([]db.Transaction, error) {
+	query := db.DB.Model(&db.Transaction{}).Where("status = ?", "completed")
+	if from != nil {
+		query = query.Where("created_at >= ?", *from)
+	}
+	if to != nil {
+		query = query.Where("created_at < ?", *to)
+	}
+
+	var transactions []db.Transaction
+	err := query.Order("created_at ASC, id ASC").Find(&transactions).Error
+	return transactions, err
+}
+
+// GetLedgerData loads the loan payments, loans, restructures and write-offs
+// referenced by the given transactions so they can be mapped to journal
+// entries.
+func (LedgerRepo) GetLedgerData(transactions []db.Transaction) (*accounting.LedgerData, error) {
+	data := &accounting.LedgerData{
+		Payments:     map[string]db.LoanPayment{},
+		Loans:        map[uint]db.Loan{},
+		Restructures: map[string]db.LoanRestructure{},
+		WriteOffs:    map[string]db.LoanWriteOff{},
+	}
+
+	var txIDs []string
+	var loanIDs []uint
+	for _, tx := range transactions {
+		txIDs = append(txIDs, tx.TransactionID)
+		var loanID uint
+		if _, err := fmt.Sscanf(tx.FromAccount, "LOAN-%d", &loanID); err == nil {
+			loanIDs = append(loanIDs, loanID)
+		}
+	}
+
+	if len(txIDs) > 0 {
+		var payments []db.LoanPayment
+		if err := db.DB.Where("transaction_id IN ?", txIDs).Find(&payments).Error; err != nil {
+			return nil, err
+		}
+		for _, payment := range payments {
+			data.Payments[payment.TransactionID] = payment
+		}
+
+		var restructures []db.LoanRestructure
+		if err := db.DB.Where("transaction_id IN ?", txIDs).Find(&restructures).Error; err != nil {
+			return nil, err
+		}
+		for _, restructure := range restructures {
+			data.Restructures[restructure.TransactionID] = restructure
+		}
+
+		var writeOffs []db.LoanWriteOff
+		if err := db.DB.Where("transaction_id IN ?", txIDs).Find(&writeOffs).Error; err != nil {
+			return nil, err
+		}
+		for _, writeOff := range writeOffs {
+			data.WriteOffs[writeOff.TransactionID] = writeOff
+		}
+	}
+
+	if len(loanIDs) > 0 {
+		var loans []db.Loan
+		if err := db.DB.Where("id IN ?", loanIDs).Find(&loans).Error; err != nil {
+			return nil, err
+		}
+		for _, loan := range loans {
+			data.Loans[loan.ID] = loan
+		}
+	}
+
+	return data, nil
+}
+
+// GetJournal builds the journal for transactions posted in [from, to).
+func (r LedgerRepo) GetJournal(from, to *time.Time) ([]accounting.Entry, error) {
+	transactions, err := r.GetTransactions(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := r.GetLedgerData(transactions)
+	if err != nil {
+		return nil, err
+	}
+
+	return accounting.BuildJournal(transactions, data), nil
+}
+
+// GetPostedShareCapital returns the share capital the ledger holds for each
+// member: subscriptions posted to SHARES-<id> less redemptions from it.
+func (LedgerRepo) GetPostedShareCapital() (map[uint]int, error) {
+	var transactions []db.Transaction
+	if err := db.DB.Where("type = ? AND status = ?", "share_capital", "completed").Find(&transactions).Error; err != nil {
+		return nil, err
+	}
+
+	posted := map[uint]int{}
+	for _, tx := range transactions {
+		var userID uint
+		if _, err := fmt.Sscanf(tx.ToAccount, "SHARES-%d", &userID); err == nil {
+			posted[userID] += tx.Amount
+		} else if _, err := fmt.Sscanf(tx.FromAccount, "SHARES-%d", &userID); err == nil {
+			posted[userID] -= tx.Amount
+		}
+	}
+	return posted, nil
+}
+
+// GetSharesBalances returns every user whose share register is not empty.
+func (LedgerRepo) GetSharesBalances() ([]db.User, error) {
+	var users []db.User
+	err := db.DB.Where("shares_balance <> 0").Order("id ASC").Find(&users).Error
+	return users, err
+}
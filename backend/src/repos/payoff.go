@@ -0,0 +1,26 @@
+package repos
+
+import (
+	"backend/src/db"
+)
+
+type PayoffRepo struct{}
+
+func (PayoffRepo) CreateQuote(quote *db.PayoffQuote) error {
+	return db.DB.Create(quote).Error
+}
+
+func (PayoffRepo) GetQuote(quoteID uint) (*db.PayoffQuote, error) {
+	var quote db.PayoffQuote
+	err := db.DB.First(&quote, quoteID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &quote, nil
+}
+
+// MarkSettled records the payment that settled the loan with the quote.
+func (PayoffRepo) MarkSettled(quoteID uint, transactionID string) error {
+	return db.DB.Model(&db.PayoffQuote{}).Where("id = ?", quoteID).
+		Updates(map[string]interface{}{"status": db.PayoffQuoteSettled, "transaction_id": transactionID}).Error
+}
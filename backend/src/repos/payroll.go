@@ -0,0 +1,71 @@
+package repos
+
+import (
+	"backend/src/db"
+
+	"gorm.io/gorm"
+)
+
+const (
+	PayrollBatchPreviewed = "previewed"
+	PayrollBatchPosted    = "posted"
+
+	PayrollRowValid   = "valid"
+	PayrollRowInvalid = "invalid"
+	PayrollRowPosted  = "posted"
+	PayrollRowFailed  = "failed"
+)
+
+type PayrollRepo struct{}
+
+func (PayrollRepo) FindBatchByHash(fileHash string) (*db.PayrollBatch, error) {
+	var batch db.PayrollBatch
+	err := db.DB.Where("file_hash = ?", fileHash).First(&batch).Error
+	if err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+func (PayrollRepo) CreateBatch(batch *db.PayrollBatch) error {
+	return db.DB.Create(batch).Error
+}
+
+func (PayrollRepo) GetBatches() ([]db.PayrollBatch, error) {
+	var batches []db.PayrollBatch
+	err := db.DB.Preload("UploadedBy").Preload("PostedBy").Order("created_at DESC").Find(&batches).Error
+	return batches, err
+}
+
+func (PayrollRepo) GetBatch(batchID uint) (*db.PayrollBatch, error) {
+	var batch db.PayrollBatch
+	err := db.DB.Preload("UploadedBy").Preload("PostedBy").
+		Preload("Rows", func(tx *gorm.DB) *gorm.DB { return tx.Order("line_number ASC") }).
+		Preload("Rows.Member").
+		First(&batch, batchID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// ClaimBatch moves a previewed batch to posted and reports whether this
+// call did so, so a batch is only ever posted once.
+func (PayrollRepo) ClaimBatch(batchID, postedByID uint, postedAt int64) (bool, error) {
+	result := db.DB.Model(&db.PayrollBatch{}).
+		Where("id = ? AND status = ?", batchID, PayrollBatchPreviewed).
+		Updates(map[string]interface{}{
+			"status":       PayrollBatchPosted,
+			"posted_by_id": postedByID,
+			"posted_at":    postedAt,
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+func (PayrollRepo) UpdateBatch(batchID uint, updates map[string]interface{}) error {
+	return db.DB.Model(&db.PayrollBatch{}).Where("id = ?", batchID).Updates(updates).Error
+}
+
+func (PayrollRepo) UpdateRow(rowID uint, updates map[string]interface{}) error {
+	return db.DB.Model(&db.PayrollBatchRow{}).Where("id = ?", rowID).Updates(updates).Error
+}
@@ -0,0 +1,141 @@
+package repos
+
+import (
+	"backend/src/accounting"
+	"backend/src/db"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+type FiscalPeriodRepo struct{}
+
+func (FiscalPeriodRepo) GetAll() ([]db.FiscalPeriod, error) {
+	var periods []db.FiscalPeriod
+	err := db.DB.Preload("ClosedBy").Order("year DESC, month DESC").Find(&periods).Error
+	return periods, err
+}
+
+func (FiscalPeriodRepo) GetByID(periodID uint) (*db.FiscalPeriod, error) {
+	var period db.FiscalPeriod
+	err := db.DB.Preload("ClosedBy").Preload("ClosingBalances").Preload("ReopenRequests.RequestedBy").First(&period, periodID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &period, nil
+}
+
+// FindOrCreate returns the period for year/month, creating it as open if it
+// has never been referenced before.
+func (FiscalPeriodRepo) FindOrCreate(year, month int) (*db.FiscalPeriod, error) {
+	startsAt, endsAt, err := db.PeriodBounds(year, month)
+	if err != nil {
+		return nil, err
+	}
+
+	period := db.FiscalPeriod{
+		Year:     year,
+		Month:    month,
+		StartsAt: startsAt,
+		EndsAt:   endsAt,
+		Status:   db.PeriodStatusOpen,
+	}
+	err = db.DB.Where("year = ? AND month = ?", year, month).FirstOrCreate(&period).Error
+	if err != nil {
+		return nil, err
+	}
+	return &period, nil
+}
+
+func (FiscalPeriodRepo) GetTransactionsInRange(startsAt, endsAt int64) ([]db.Transaction, error) {
+	var transactions []db.Transaction
+	err := db.DB.Where("created_at >= ? AND created_at < ?", time.Unix(startsAt, 0), time.Unix(endsAt, 0)).
+		Order("id ASC").Find(&transactions).Error
+	return transactions, err
+}
+
+type AccountBalance struct {
+	Account string
+	Balance int
+}
+
+// GetAccountBalancesAt returns the balance of every chart account, on its
+// normal side, from the journal of all transactions posted before the given
+// time. Records that move no money have no journal entries and so no effect.
+func (FiscalPeriodRepo) GetAccountBalancesAt(before int64) ([]AccountBalance, error) {
+	to := time.Unix(before, 0)
+	entries, err := LedgerRepo{}.GetJournal(nil, &to)
+	if err != nil {
+		return nil, err
+	}
+
+	net := accounting.Balances(entries)
+	var balances []AccountBalance
+	for _, account := range accounting.ChartOfAccounts {
+		if net[account.Code] == 0 {
+			continue
+		}
+		balances = append(balances, AccountBalance{Account: account.Code, Balance: net[account.Code]})
+	}
+	return balances, nil
+}
+
+// MarkClosed stores the closing snapshot, records the period_close
+// transaction and flips the period to closed, all or nothing.
+func (FiscalPeriodRepo) MarkClosed(period *db.FiscalPeriod, balances []db.PeriodClosingBalance, closing *db.Transaction) error {
+	tx := db.DB.Begin()
+	if err := tx.Create(closing).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Where("fiscal_period_id = ?", period.ID).Delete(&db.PeriodClosingBalance{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if len(balances) > 0 {
+		if err := tx.Create(&balances).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := tx.Model(period).Updates(map[string]interface{}{
+		"status":                 db.PeriodStatusClosed,
+		"closed_by_id":           period.ClosedByID,
+		"closed_at":              period.ClosedAt,
+		"closing_transaction_id": period.ClosingTransactionID,
+		"period_hash":            period.PeriodHash,
+		"transaction_count":      period.TransactionCount,
+	}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+func (FiscalPeriodRepo) Reopen(periodID uint) error {
+	return db.DB.Model(&db.FiscalPeriod{}).Where("id = ?", periodID).Update("status", db.PeriodStatusOpen).Error
+}
+
+func (FiscalPeriodRepo) CreateReopenRequest(request *db.PeriodReopenRequest) error {
+	return db.DB.Create(request).Error
+}
+
+func (FiscalPeriodRepo) GetReopenRequest(requestID uint) (*db.PeriodReopenRequest, error) {
+	var request db.PeriodReopenRequest
+	err := db.DB.Preload("FiscalPeriod").Preload("RequestedBy").First(&request, requestID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+func (FiscalPeriodRepo) GetPendingReopenRequests() ([]db.PeriodReopenRequest, error) {
+	var requests []db.PeriodReopenRequest
+	err := db.DB.Where("status = ?", "pending").Preload("FiscalPeriod").Preload("RequestedBy").
+		Order("created_at ASC").Find(&requests).Error
+	return requests, err
+}
+
+func (FiscalPeriodRepo) SaveReopenRequest(request *db.PeriodReopenRequest) error {
+	return db.DB.Omit(clause.Associations).Save(request).Error
+}
@@ -0,0 +1,46 @@
+package repos
+
+import (
+	"backend/src/db"
+)
+
+type ProductRepo struct{}
+
+func (ProductRepo) Create(product *db.LoanProduct) error {
+	return db.DB.Create(product).Error
+}
+
+func (ProductRepo) GetByID(productID uint) (*db.LoanProduct, error) {
+	var product db.LoanProduct
+	err := db.DB.Preload("Fees").First(&product, productID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+// GetAll lists products by name, leaving out inactive ones when activeOnly
+// is set.
+func (ProductRepo) GetAll(activeOnly bool) ([]db.LoanProduct, error) {
+	query := db.DB.Preload("Fees")
+	if activeOnly {
+		query = query.Where("is_active = ?", true)
+	}
+	var products []db.LoanProduct
+	err := query.Order("name ASC").Find(&products).Error
+	return products, err
+}
+
+// Save updates the product and replaces its fee list.
+func (ProductRepo) Save(product *db.LoanProduct) error {
+	tx := db.DB.Begin()
+	if err := tx.Omit("Fees").Save(product).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Model(product).Association("Fees").Replace(product.Fees); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
@@ -0,0 +1,113 @@
+package repos
+
+import (
+	"backend/src/db"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+const (
+	LineStatusUnmatched = "unmatched"
+	LineStatusSuggested = "suggested"
+	LineStatusMatched   = "matched"
+	LineStatusIgnored   = "ignored"
+)
+
+type ReconciliationRepo struct{}
+
+func (ReconciliationRepo) FindStatementByHash(fileHash string) (*db.BankStatement, error) {
+	var statement db.BankStatement
+	err := db.DB.Where("file_hash = ?", fileHash).First(&statement).Error
+	if err != nil {
+		return nil, err
+	}
+	return &statement, nil
+}
+
+func (ReconciliationRepo) CreateStatement(statement *db.BankStatement) error {
+	return db.DB.Create(statement).Error
+}
+
+func (ReconciliationRepo) UpdateStatementTransaction(statementID uint, transactionID string) error {
+	if err := db.DB.Model(&db.BankStatement{}).Where("id = ?", statementID).
+		Update("transaction_id", transactionID).Error; err != nil {
+		return err
+	}
+	return db.DB.Model(&db.BankStatementLine{}).
+		Where("bank_statement_id = ? AND status = ?", statementID, LineStatusMatched).
+		Update("transaction_id", transactionID).Error
+}
+
+func (ReconciliationRepo) GetStatements() ([]db.BankStatement, error) {
+	var statements []db.BankStatement
+	err := db.DB.Preload("UploadedBy").Order("created_at DESC").Find(&statements).Error
+	return statements, err
+}
+
+func (ReconciliationRepo) GetLine(lineID uint) (*db.BankStatementLine, error) {
+	var line db.BankStatementLine
+	err := db.DB.Preload("Deposit").Preload("Deposit.User").First(&line, lineID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &line, nil
+}
+
+func (ReconciliationRepo) GetLinesByStatus(statuses ...string) ([]db.BankStatementLine, error) {
+	var lines []db.BankStatementLine
+	err := db.DB.Where("status IN ?", statuses).Preload("Deposit").Preload("Deposit.User").
+		Order("date ASC, id ASC").Find(&lines).Error
+	return lines, err
+}
+
+func (ReconciliationRepo) SaveLine(line *db.BankStatementLine) error {
+	return db.DB.Omit(clause.Associations).Save(line).Error
+}
+
+// RefreshMatchedCount recomputes the number of confirmed matches on a statement.
+func (ReconciliationRepo) RefreshMatchedCount(statementID uint) error {
+	var count int64
+	if err := db.DB.Model(&db.BankStatementLine{}).
+		Where("bank_statement_id = ? AND status = ?", statementID, LineStatusMatched).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	return db.DB.Model(&db.BankStatement{}).Where("id = ?", statementID).Update("matched_count", count).Error
+}
+
+// GetUnreconciledDeposits returns completed deposits that no statement line
+// has been matched or suggested against, optionally limited to a date range.
+func (ReconciliationRepo) GetUnreconciledDeposits(from, to *time.Time) ([]db.Deposit, error) {
+	query := db.DB.Model(&db.Deposit{}).Preload("User").
+		Where("status = ?", "completed").
+		Where("id NOT IN (?)", db.DB.Model(&db.BankStatementLine{}).Select("deposit_id").
+			Where("deposit_id IS NOT NULL AND status IN ?", []string{LineStatusMatched, LineStatusSuggested}))
+	if from != nil {
+		query = query.Where("created_at >= ?", *from)
+	}
+	if to != nil {
+		query = query.Where("created_at <= ?", *to)
+	}
+
+	var deposits []db.Deposit
+	err := query.Order("created_at ASC").Find(&deposits).Error
+	return deposits, err
+}
+
+func (ReconciliationRepo) IsDepositReconciled(depositID uint, excludeLineID uint) (bool, error) {
+	var count int64
+	err := db.DB.Model(&db.BankStatementLine{}).
+		Where("deposit_id = ? AND id <> ? AND status IN ?", depositID, excludeLineID, []string{LineStatusMatched, LineStatusSuggested}).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (ReconciliationRepo) GetDeposit(depositID uint) (*db.Deposit, error) {
+	var deposit db.Deposit
+	err := db.DB.Preload("User").First(&deposit, depositID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &deposit, nil
+}
@@ -0,0 +1,81 @@
+package repos
+
+import (
+	"backend/src/db"
+)
+
+type ReminderRepo struct{}
+
+type SMSMessageFilter struct {
+	Status string
+	UserID *uint
+	LoanID *uint
+}
+
+func (ReminderRepo) GetTemplates() ([]db.ReminderTemplate, error) {
+	var templates []db.ReminderTemplate
+	err := db.DB.Preload("UpdatedBy").Order("id ASC").Find(&templates).Error
+	return templates, err
+}
+
+func (ReminderRepo) GetTemplate(event string) (*db.ReminderTemplate, error) {
+	var template db.ReminderTemplate
+	err := db.DB.Preload("UpdatedBy").Where("event = ?", event).First(&template).Error
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+func (ReminderRepo) SaveTemplate(template *db.ReminderTemplate) error {
+	return db.DB.Omit("UpdatedBy").Save(template).Error
+}
+
+func (ReminderRepo) MessageExists(messageKey string) (bool, error) {
+	var count int64
+	err := db.DB.Model(&db.SMSMessage{}).Where("message_key = ?", messageKey).Count(&count).Error
+	return count > 0, err
+}
+
+func (ReminderRepo) CreateMessage(message *db.SMSMessage) error {
+	return db.DB.Create(message).Error
+}
+
+func (ReminderRepo) GetMessage(messageID uint) (*db.SMSMessage, error) {
+	var message db.SMSMessage
+	err := db.DB.Preload("User").First(&message, messageID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+func (ReminderRepo) GetMessages(filter SMSMessageFilter) ([]db.SMSMessage, error) {
+	query := db.DB.Preload("User").Order("id DESC")
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.UserID != nil {
+		query = query.Where("user_id = ?", *filter.UserID)
+	}
+	if filter.LoanID != nil {
+		query = query.Where("loan_id = ?", *filter.LoanID)
+	}
+
+	var messages []db.SMSMessage
+	err := query.Limit(500).Find(&messages).Error
+	return messages, err
+}
+
+// GetDueMessages returns pending messages whose next attempt has come,
+// oldest first.
+func (ReminderRepo) GetDueMessages(now int64, limit int) ([]db.SMSMessage, error) {
+	var messages []db.SMSMessage
+	err := db.DB.Where("status = ? AND next_attempt_at <= ?", db.SMSStatusPending, now).
+		Order("next_attempt_at ASC, id ASC").Limit(limit).Find(&messages).Error
+	return messages, err
+}
+
+func (ReminderRepo) SaveMessage(message *db.SMSMessage) error {
+	return db.DB.Omit("User").Save(message).Error
+}
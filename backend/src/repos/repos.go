@@ -2,8 +2,11 @@ package repos
 
 import (
 	"backend/src/db"
+	"backend/src/lifecycle"
 	"fmt"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type User struct{}
@@ -152,7 +155,7 @@ func (LoanRepo) Create(loan *db.Loan) error {
 
 func (LoanRepo) GetByID(loanID uint) (*db.Loan, error) {
 	var loan db.Loan
-	err := db.DB.Preload("Borrower").Preload("ApprovedBy").First(&loan, loanID).Error
+	err := db.DB.Preload("Borrower").Preload("ApprovedBy").Preload("Product").First(&loan, loanID).Error
 	if err != nil {
 		return nil, err
 	}
@@ -171,34 +174,134 @@ func (LoanRepo) GetByStatus(status string) ([]db.Loan, error) {
 	return loans, err
 }
 
+// GetActive returns loans that are being repaid, with their borrower,
+// completed payments and repayment schedule. Approved loans still waiting
+// to be paid out owe nothing yet and are left out.
+func (LoanRepo) GetActive() ([]db.Loan, error) {
+	var loans []db.Loan
+	err := db.DB.Where("status IN ?", lifecycle.ActiveStatuses).
+		Where("disbursed_at IS NOT NULL OR paid_out_on_approval = ?", true).
+		Preload("Borrower").
+		Preload("Payments", "status = ?", "completed").
+		Preload("Installments", func(tx *gorm.DB) *gorm.DB { return tx.Order("number ASC") }).
+		Order("id ASC").Find(&loans).Error
+	return loans, err
+}
+
+// GetOpenByBorrower returns the member's pending and active loans, with
+// what is needed to work out their arrears.
+func (LoanRepo) GetOpenByBorrower(borrowerID uint) ([]db.Loan, error) {
+	var loans []db.Loan
+	err := db.DB.Where("borrower_id = ? AND status IN ?", borrowerID, lifecycle.OpenStatuses).
+		Preload("Payments", "status = ?", "completed").
+		Preload("Installments", func(tx *gorm.DB) *gorm.DB { return tx.Order("number ASC") }).
+		Order("id ASC").Find(&loans).Error
+	return loans, err
+}
+
+// GetTopUps returns the loans requested to refinance the given loan.
+func (LoanRepo) GetTopUps(loanID uint) ([]db.Loan, error) {
+	var loans []db.Loan
+	err := db.DB.Where("top_up_of_id = ?", loanID).Order("id ASC").Find(&loans).Error
+	return loans, err
+}
+
+func (LoanRepo) UpdateDelinquency(loanID uint, daysPastDue, arrears int, checkedAt int64) error {
+	return db.DB.Model(&db.Loan{}).Where("id = ?", loanID).Updates(map[string]interface{}{
+		"days_past_due":          daysPastDue,
+		"arrears_amount":         arrears,
+		"delinquency_checked_at": checkedAt,
+	}).Error
+}
+
 func (LoanRepo) GetAll() ([]db.Loan, error) {
 	var loans []db.Loan
 	err := db.DB.Preload("Borrower").Preload("ApprovedBy").Find(&loans).Error
 	return loans, err
 }
 
-func (LoanRepo) UpdateStatus(loanID uint, status string, approvedByID uint, interestRate float64, monthlyPayment int) error {
-	updates := map[string]interface{}{
-		"status": status,
+// ApplyStatusChange updates the loan's status together with any other
+// columns that change with it, and records the change in its history.
+func (LoanRepo) ApplyStatusChange(loanID uint, updates map[string]interface{}, history *db.LoanStatusHistory) error {
+	tx := db.DB.Begin()
+	updates["status"] = history.ToStatus
+	if err := tx.Model(&db.Loan{}).Where("id = ?", loanID).Updates(updates).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Create(history).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+func (LoanRepo) CreateStatusHistory(history *db.LoanStatusHistory) error {
+	return db.DB.Create(history).Error
+}
+
+func (LoanRepo) GetStatusHistory(loanID uint) ([]db.LoanStatusHistory, error) {
+	var history []db.LoanStatusHistory
+	err := db.DB.Where("loan_id = ?", loanID).Preload("ChangedBy").Order("created_at ASC, id ASC").Find(&history).Error
+	return history, err
+}
+
+func (LoanRepo) CreateRequestVersion(version *db.LoanRequestVersion) error {
+	return db.DB.Create(version).Error
+}
+
+func (LoanRepo) GetRequestVersions(loanID uint) ([]db.LoanRequestVersion, error) {
+	var versions []db.LoanRequestVersion
+	err := db.DB.Where("loan_id = ?", loanID).Preload("ChangedBy").Order("version ASC").Find(&versions).Error
+	return versions, err
+}
+
+// AmendRequest applies an amendment to a loan still in the given status and
+// request version and records the new version. It reports false, changing
+// nothing, when the loan has moved on in the meantime.
+func (LoanRepo) AmendRequest(loanID uint, status string, fromVersion int, updates map[string]interface{}, version *db.LoanRequestVersion) (bool, error) {
+	tx := db.DB.Begin()
+	updates["request_version"] = version.Version
+	result := tx.Model(&db.Loan{}).Where("id = ? AND status = ? AND request_version = ?", loanID, status, fromVersion).Updates(updates)
+	if result.Error != nil {
+		tx.Rollback()
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		return false, nil
 	}
-	if status == "Approved" {
-		updates["approved_by_id"] = approvedByID
-		updates["interest_rate"] = interestRate
-		updates["monthly_payment"] = monthlyPayment
+	if err := tx.Create(version).Error; err != nil {
+		tx.Rollback()
+		return false, err
 	}
-	return db.DB.Model(&db.Loan{}).Where("id = ?", loanID).Updates(updates).Error
+	return true, tx.Commit().Error
+}
+
+func (LoanRepo) CreateRestructure(restructure *db.LoanRestructure) error {
+	return db.DB.Create(restructure).Error
+}
+
+func (LoanRepo) GetRestructures(loanID uint) ([]db.LoanRestructure, error) {
+	var restructures []db.LoanRestructure
+	err := db.DB.Where("loan_id = ?", loanID).Preload("RestructuredBy").Order("created_at ASC, id ASC").Find(&restructures).Error
+	return restructures, err
 }
 
 func (LoanRepo) GetTotalLoansAmount() (int64, error) {
 	var total int64
-	err := db.DB.Model(&db.Loan{}).Select("COALESCE(SUM(amount), 0)").Where("status IN ?", []string{"Approved", "Disbursed"}).Scan(&total).Error
+	err := db.DB.Model(&db.Loan{}).Select("COALESCE(SUM(amount), 0)").Where("status IN ?", lifecycle.ActiveStatuses).Scan(&total).Error
 	return total, err
 }
 
+// GetTotalProfit returns interest and fees collected.
 func (LoanRepo) GetTotalProfit() (int64, error) {
 	var totalInterest int64
-	err := db.DB.Model(&db.LoanPayment{}).Select("COALESCE(SUM(interest_amount), 0)").Scan(&totalInterest).Error
-	return totalInterest, err
+	if err := db.DB.Model(&db.LoanPayment{}).Select("COALESCE(SUM(interest_amount), 0)").Scan(&totalInterest).Error; err != nil {
+		return 0, err
+	}
+	totalFees, err := FeeRepo{}.GetTotalCollected()
+	return totalInterest + totalFees, err
 }
 
 type DepositRepo struct{}
@@ -217,28 +320,47 @@ func (InterestRateRepo) Create(rate *db.InterestRate) error {
 	return db.DB.Create(rate).Error
 }
 
-func (InterestRateRepo) GetAll() ([]db.InterestRate, error) {
+// GetCurrent returns the rate version in force for each duration at the
+// given time. Durations whose first rate is still scheduled are left out.
+func (InterestRateRepo) GetCurrent(at int64) ([]db.InterestRate, error) {
+	var versions []db.InterestRate
+	err := db.DB.Where("effective_from <= ?", at).
+		Order("duration_months ASC, effective_from DESC, id DESC").Find(&versions).Error
+	if err != nil {
+		return nil, err
+	}
+
 	var rates []db.InterestRate
-	err := db.DB.Order("duration_months ASC").Find(&rates).Error
-	return rates, err
+	for _, version := range versions {
+		if len(rates) == 0 || rates[len(rates)-1].DurationMonths != version.DurationMonths {
+			rates = append(rates, version)
+		}
+	}
+	return rates, nil
 }
 
-func (InterestRateRepo) GetByDuration(durationMonths int) (*db.InterestRate, error) {
+// GetByDuration returns the rate version in force for the duration at the
+// given time.
+func (InterestRateRepo) GetByDuration(durationMonths int, at int64) (*db.InterestRate, error) {
 	var rate db.InterestRate
-	err := db.DB.Where("duration_months = ?", durationMonths).Order("effective_from DESC").First(&rate).Error
+	err := db.DB.Where("duration_months = ? AND effective_from <= ?", durationMonths, at).
+		Order("effective_from DESC, id DESC").First(&rate).Error
 	if err != nil {
 		return nil, err
 	}
 	return &rate, nil
 }
 
-func (InterestRateRepo) Upsert(rate *db.InterestRate) error {
-	var existing db.InterestRate
-	err := db.DB.Where("duration_months = ?", rate.DurationMonths).First(&existing).Error
-	if err != nil {
-		return db.DB.Create(rate).Error
+// GetHistory returns every version of the rate for the duration, or for all
+// durations when it is 0, newest first.
+func (InterestRateRepo) GetHistory(durationMonths int) ([]db.InterestRate, error) {
+	query := db.DB.Preload("SetBy")
+	if durationMonths != 0 {
+		query = query.Where("duration_months = ?", durationMonths)
 	}
-	return db.DB.Model(&existing).Updates(rate).Error
+	var rates []db.InterestRate
+	err := query.Order("duration_months ASC, effective_from DESC, id DESC").Find(&rates).Error
+	return rates, err
 }
 
 type StatsRepo struct{}
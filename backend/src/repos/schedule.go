@@ -0,0 +1,51 @@
+package repos
+
+import (
+	"backend/src/db"
+)
+
+type ScheduleRepo struct{}
+
+// ReplaceSchedule swaps the stored installments of a loan for a new set.
+func (ScheduleRepo) ReplaceSchedule(loanID uint, installments []db.LoanInstallment) error {
+	tx := db.DB.Begin()
+	if err := tx.Unscoped().Where("loan_id = ?", loanID).Delete(&db.LoanInstallment{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if len(installments) > 0 {
+		if err := tx.Create(&installments).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit().Error
+}
+
+// GetPaidSplit returns the principal and interest repaid on a loan since the
+// given time.
+func (ScheduleRepo) GetPaidSplit(loanID uint, since int64) (int, int, error) {
+	var row struct {
+		Principal int
+		Interest  int
+	}
+	err := db.DB.Model(&db.LoanPayment{}).
+		Select("COALESCE(SUM(principal_amount), 0) AS principal, COALESCE(SUM(interest_amount), 0) AS interest").
+		Where("loan_id = ? AND status = ? AND payment_date >= ?", loanID, "completed", since).Scan(&row).Error
+	return row.Principal, row.Interest, err
+}
+
+func (ScheduleRepo) GetSchedule(loanID uint) ([]db.LoanInstallment, error) {
+	var installments []db.LoanInstallment
+	err := db.DB.Where("loan_id = ?", loanID).Order("number ASC").Find(&installments).Error
+	return installments, err
+}
+
+// GetTotalPaid returns the principal and interest repaid on a loan since the
+// given time, leaving out penalties, fees and credits.
+func (ScheduleRepo) GetTotalPaid(loanID uint, since int64) (int, error) {
+	var total int
+	err := db.DB.Model(&db.LoanPayment{}).Select("COALESCE(SUM(principal_amount + interest_amount), 0)").
+		Where("loan_id = ? AND status = ? AND payment_date >= ?", loanID, "completed", since).Scan(&total).Error
+	return total, err
+}
@@ -21,13 +21,15 @@ func NewSMS() *SMS {
 }
 
 func (s *SMS) SendOTP(phoneNumber, otpCode string) error {
+	return s.Send(phoneNumber, fmt.Sprintf("Your verification code is: %s. Valid for 5 minutes.", otpCode))
+}
+
+func (s *SMS) Send(phoneNumber, message string) error {
 	from := os.Getenv("TWILIO_PHONE_NUMBER")
 	if from == "" {
 		return fmt.Errorf("TWILIO_PHONE_NUMBER not configured")
 	}
 
-	message := fmt.Sprintf("Your verification code is: %s. Valid for 5 minutes.", otpCode)
-
 	params := &twilioApi.CreateMessageParams{}
 	params.SetTo(phoneNumber)
 	params.SetFrom(from)
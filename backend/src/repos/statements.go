@@ -0,0 +1,39 @@
+package repos
+
+import (
+	"backend/src/db"
+	"fmt"
+	"time"
+)
+
+type StatementRepo struct{}
+
+// GetMemberTransactions returns completed transactions that credit or debit
+// the member's ledger account, posted before the given time if set.
+func (StatementRepo) GetMemberTransactions(userID uint, before *time.Time) ([]db.Transaction, error) {
+	account := fmt.Sprintf("USER-%d", userID)
+	query := db.DB.Where("status = ? AND (from_account = ? OR to_account = ?)", "completed", account, account)
+	if before != nil {
+		query = query.Where("created_at < ?", *before)
+	}
+
+	var transactions []db.Transaction
+	err := query.Order("created_at ASC, id ASC").Find(&transactions).Error
+	return transactions, err
+}
+
+func (StatementRepo) GetBlocksByTransactionIDs(transactionIDs []string) (map[string]db.Block, error) {
+	blocks := map[string]db.Block{}
+	if len(transactionIDs) == 0 {
+		return blocks, nil
+	}
+
+	var rows []db.Block
+	if err := db.DB.Where("transaction_id IN ?", transactionIDs).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, block := range rows {
+		blocks[block.TransactionID] = block
+	}
+	return blocks, nil
+}
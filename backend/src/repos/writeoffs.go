@@ -0,0 +1,81 @@
+package repos
+
+import (
+	"backend/src/db"
+
+	"gorm.io/gorm"
+)
+
+type WriteOffRepo struct{}
+
+func (WriteOffRepo) Create(writeOff *db.LoanWriteOff) error {
+	return db.DB.Create(writeOff).Error
+}
+
+func (WriteOffRepo) GetByID(writeOffID uint) (*db.LoanWriteOff, error) {
+	var writeOff db.LoanWriteOff
+	err := db.DB.Preload("Loan").Preload("Loan.Borrower").Preload("RequestedBy").Preload("DecidedBy").
+		First(&writeOff, writeOffID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &writeOff, nil
+}
+
+// GetAll returns write-off requests, newest first, optionally only those
+// with the given status.
+func (WriteOffRepo) GetAll(status string) ([]db.LoanWriteOff, error) {
+	query := db.DB.Preload("Loan").Preload("Loan.Borrower").Preload("RequestedBy").Preload("DecidedBy")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var writeOffs []db.LoanWriteOff
+	err := query.Order("id DESC").Find(&writeOffs).Error
+	return writeOffs, err
+}
+
+func (WriteOffRepo) HasPending(loanID uint) (bool, error) {
+	var count int64
+	err := db.DB.Model(&db.LoanWriteOff{}).Where("loan_id = ? AND status = ?", loanID, db.WriteOffPending).Count(&count).Error
+	return count > 0, err
+}
+
+func (WriteOffRepo) Save(writeOff *db.LoanWriteOff) error {
+	return db.DB.Omit("Loan", "RequestedBy", "DecidedBy").Save(writeOff).Error
+}
+
+// CreateRecovery records the recovery and adds it to the loan's recovered
+// amount.
+func (WriteOffRepo) CreateRecovery(recovery *db.LoanRecovery) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(recovery).Error; err != nil {
+			return err
+		}
+		return tx.Model(&db.Loan{}).Where("id = ?", recovery.LoanID).
+			UpdateColumn("recovered_amount", gorm.Expr("recovered_amount + ?", recovery.Amount)).Error
+	})
+}
+
+func (WriteOffRepo) GetRecoveries(loanID uint) ([]db.LoanRecovery, error) {
+	var recoveries []db.LoanRecovery
+	err := db.DB.Where("loan_id = ?", loanID).Preload("RecordedBy").Order("id ASC").Find(&recoveries).Error
+	return recoveries, err
+}
+
+func (WriteOffRepo) GetProvisionRates() ([]db.ProvisionRate, error) {
+	var rates []db.ProvisionRate
+	err := db.DB.Order("id ASC").Find(&rates).Error
+	return rates, err
+}
+
+func (WriteOffRepo) GetProvisionRate(bucket string) (*db.ProvisionRate, error) {
+	var rate db.ProvisionRate
+	if err := db.DB.Where("bucket = ?", bucket).First(&rate).Error; err != nil {
+		return nil, err
+	}
+	return &rate, nil
+}
+
+func (WriteOffRepo) SaveProvisionRate(rate *db.ProvisionRate) error {
+	return db.DB.Save(rate).Error
+}
@@ -61,18 +61,111 @@ func RegisterRoutes(e *echo.Echo) {
 	api.GET("/home", handlers.Home, middleware.Auth)
 
 	api.GET("/interest_rates", handlers.GetInterestRates)
+	api.GET("/interest_rates/history", handlers.GetInterestRateHistory, middleware.Auth)
 	api.POST("/interest_rates/set", handlers.SetInterestRate, middleware.Auth, middleware.RequireManager)
 
 	loans := api.Group("/loans", middleware.Auth)
 	loans.GET("/member", handlers.GetMemberLoans, middleware.RequireMember)
 	loans.GET("/manager", handlers.GetManagerLoans, middleware.RequireManager)
 	loans.GET("/:id", handlers.GetLoanByID, middleware.RequireManager)
+	loans.GET("/rules", handlers.ListEligibilityRules, middleware.RequireRole("manager", "auditor"))
+	loans.POST("/rules/:code", handlers.UpdateEligibilityRule, middleware.RequireManager)
+	loans.GET("/eligibility", handlers.CheckLoanEligibility, middleware.RequireMember)
+	loans.GET("/:id/schedule", handlers.GetLoanSchedule, middleware.RequireRole("member", "manager"))
+	loans.GET("/:id/payoff_quote", handlers.GetPayoffQuote, middleware.RequireRole("member", "manager"))
+	loans.GET("/:id/collateral", handlers.GetLoanCollateral, middleware.RequireRole("member", "manager", "auditor"))
+	loans.POST("/:id/collateral", handlers.RegisterCollateral, middleware.RequireManager)
+	loans.GET("/:id/history", handlers.GetLoanHistory, middleware.RequireRole("member", "manager", "auditor"))
 	loans.POST("/:id/update_status", handlers.UpdateLoanStatus, middleware.RequireManager)
+	loans.GET("/:id/approvals", handlers.GetLoanApprovals, middleware.RequireRole("manager", "auditor"))
+	loans.GET("/approval_policies", handlers.ListApprovalPolicies, middleware.RequireRole("manager", "auditor"))
+	loans.POST("/approval_policies", handlers.CreateApprovalPolicy, middleware.RequireManager)
+	loans.POST("/approval_policies/:id", handlers.UpdateApprovalPolicy, middleware.RequireManager)
+	loans.POST("/:id/disburse", handlers.DisburseLoan, middleware.RequireManager)
+	loans.GET("/:id/restructures", handlers.GetLoanRestructures, middleware.RequireRole("member", "manager", "auditor"))
+	loans.POST("/:id/restructure", handlers.RestructureLoan, middleware.RequireManager)
+	loans.POST("/:id/write_off", handlers.RequestLoanWriteOff, middleware.RequireManager)
+	loans.GET("/write_offs", handlers.ListLoanWriteOffs, middleware.RequireRole("manager", "auditor"))
+	loans.POST("/write_offs/:id/decide", handlers.DecideLoanWriteOff, middleware.RequireAuditor)
+	loans.GET("/:id/recoveries", handlers.GetLoanRecoveries, middleware.RequireRole("manager", "auditor"))
+	loans.POST("/:id/recoveries", handlers.RecordLoanRecovery, middleware.RequireManager)
 	loans.POST("/request", handlers.RequestLoan, middleware.RequireMember)
+	loans.POST("/:id/cancel", handlers.CancelLoan, middleware.RequireMember)
+	loans.POST("/:id/amend", handlers.AmendLoan, middleware.RequireMember)
+	loans.GET("/:id/versions", handlers.GetLoanRequestVersions, middleware.RequireRole("member", "manager", "auditor"))
+	loans.GET("/:id/comments", handlers.GetLoanComments, middleware.RequireRole("member", "manager", "auditor"))
+	loans.POST("/:id/comments", handlers.CreateLoanComment, middleware.RequireRole("member", "manager"))
 	loans.POST("/add", handlers.AddLoan, middleware.RequireManager)
 	loans.POST("/payment", handlers.MakePayment, middleware.RequireMember)
 
 	api.POST("/deposit", handlers.AddDeposit, middleware.Auth, middleware.RequireManager)
+	api.POST("/withdrawal", handlers.AddWithdrawal, middleware.Auth, middleware.RequireManager)
+
+	collateral := api.Group("/collateral", middleware.Auth)
+	collateral.POST("/:id/revalue", handlers.RevalueCollateral, middleware.RequireManager)
+
+	documents := api.Group("/documents", middleware.Auth)
+	documents.POST("", handlers.UploadDocument, middleware.RequireRole("member", "manager"))
+	documents.GET("", handlers.ListDocuments, middleware.RequireRole("member", "manager", "auditor"))
+	documents.GET("/:id", handlers.DownloadDocument, middleware.RequireRole("member", "manager", "auditor"))
+	documents.GET("/:id/verify", handlers.VerifyDocument, middleware.RequireRole("manager", "auditor"))
+
+	guarantees := api.Group("/guarantees", middleware.Auth)
+	guarantees.GET("", handlers.GetMyGuarantees, middleware.RequireMember)
+	guarantees.GET("/exposure", handlers.GetGuarantorExposure, middleware.RequireRole("manager", "auditor"))
+	guarantees.POST("/:id/respond", handlers.RespondToGuarantee, middleware.RequireMember)
+	guarantees.POST("/:id/release", handlers.ReleaseGuarantee, middleware.RequireManager)
+
+	products := api.Group("/loan_products", middleware.Auth)
+	products.GET("", handlers.ListLoanProducts)
+	products.POST("", handlers.CreateLoanProduct, middleware.RequireManager)
+	products.POST("/:id", handlers.UpdateLoanProduct, middleware.RequireManager)
+
+	fees := api.Group("/fees", middleware.Auth)
+	fees.GET("/types", handlers.ListFeeTypes, middleware.RequireRole("manager", "auditor"))
+	fees.POST("/types/:code", handlers.UpdateFeeType, middleware.RequireManager)
+	fees.GET("/charges", handlers.ListFeeCharges, middleware.RequireRole("manager", "auditor"))
+	fees.POST("/charges/:id/waive", handlers.WaiveFeeCharge, middleware.RequireManager)
+	fees.GET("/member", handlers.GetMemberFeeCharges, middleware.RequireMember)
+	fees.POST("/run", handlers.RunFees, middleware.RequireManager)
+
+	provisioning := api.Group("/provisioning", middleware.Auth)
+	provisioning.GET("", handlers.GetLoanProvisioning, middleware.RequireRole("manager", "auditor"))
+	provisioning.POST("/run", handlers.RunLoanProvisioning, middleware.RequireManager)
+	provisioning.POST("/rates/:bucket", handlers.UpdateProvisionRate, middleware.RequireManager)
+
+	reminders := api.Group("/reminders", middleware.Auth)
+	reminders.GET("/templates", handlers.ListReminderTemplates, middleware.RequireRole("manager", "auditor"))
+	reminders.POST("/templates/:event", handlers.UpdateReminderTemplate, middleware.RequireManager)
+	reminders.GET("/messages", handlers.ListSMSMessages, middleware.RequireRole("manager", "auditor"))
+	reminders.POST("/messages/:id/retry", handlers.RetrySMSMessage, middleware.RequireManager)
+	reminders.POST("/run", handlers.RunReminders, middleware.RequireManager)
+
+	statements := api.Group("/statements", middleware.Auth)
+	statements.GET("", handlers.GetMyStatement, middleware.RequireMember)
+	statements.GET("/members/:id", handlers.GetMemberStatement, middleware.RequireManager)
+
+	periods := api.Group("/periods", middleware.Auth)
+	periods.GET("", handlers.ListFiscalPeriods, middleware.RequireRole("manager", "auditor"))
+	periods.POST("/close", handlers.CloseFiscalPeriod, middleware.RequireManager)
+	periods.GET("/reopen_requests", handlers.GetPendingReopenRequests, middleware.RequireAuditor)
+	periods.POST("/reopen_requests/:id/decide", handlers.DecidePeriodReopen, middleware.RequireAuditor)
+	periods.GET("/:id", handlers.GetFiscalPeriod, middleware.RequireRole("manager", "auditor"))
+	periods.POST("/:id/reopen", handlers.RequestPeriodReopen, middleware.RequireManager)
+
+	reconciliation := api.Group("/reconciliation", middleware.Auth)
+	reconciliation.POST("/statements", handlers.ImportBankStatement, middleware.RequireManager)
+	reconciliation.GET("/statements", handlers.ListBankStatements, middleware.RequireRole("manager", "auditor"))
+	reconciliation.GET("/worklist", handlers.GetReconciliationWorklist, middleware.RequireRole("manager", "auditor"))
+	reconciliation.POST("/lines/:id/match", handlers.MatchStatementLine, middleware.RequireManager)
+	reconciliation.POST("/lines/:id/unmatch", handlers.UnmatchStatementLine, middleware.RequireManager)
+	reconciliation.POST("/lines/:id/ignore", handlers.IgnoreStatementLine, middleware.RequireManager)
+
+	payroll := api.Group("/payroll", middleware.Auth)
+	payroll.POST("/batches", handlers.ImportPayrollBatch, middleware.RequireManager)
+	payroll.GET("/batches", handlers.ListPayrollBatches, middleware.RequireRole("manager", "auditor"))
+	payroll.GET("/batches/:id", handlers.GetPayrollBatch, middleware.RequireRole("manager", "auditor"))
+	payroll.POST("/batches/:id/post", handlers.PostPayrollBatch, middleware.RequireManager)
 
 	users := api.Group("/users", middleware.Auth, middleware.RequireManager)
 	users.GET("", handlers.ListUsers)
@@ -85,4 +178,8 @@ func RegisterRoutes(e *echo.Echo) {
 	audit.GET("/transactions/export", handlers.ExportTransactions)
 	audit.GET("/users/:id", handlers.GetUserAuditReport)
 	audit.GET("/blockchain/status", handlers.GetBlockchainStatus)
+	audit.GET("/reports/trial_balance", handlers.GetTrialBalance)
+	audit.GET("/reports/balance_sheet", handlers.GetBalanceSheet)
+	audit.GET("/reports/income_statement", handlers.GetIncomeStatement)
+	audit.GET("/reports/portfolio_aging", handlers.GetPortfolioAging)
 }
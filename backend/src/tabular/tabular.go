@@ -0,0 +1,240 @@
+package tabular
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+// DetectFormat guesses whether a spreadsheet is CSV or XLSX from its name,
+// falling back to sniffing for the zip header every XLSX file starts with.
+func DetectFormat(filename string, data []byte) string {
+	lower := strings.ToLower(filename)
+	switch {
+	case strings.HasSuffix(lower, ".xlsx"):
+		return FormatXLSX
+	case strings.HasSuffix(lower, ".csv"):
+		return FormatCSV
+	}
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		return FormatXLSX
+	}
+	return FormatCSV
+}
+
+// ReadCSV returns every record of a CSV file. Records may have differing
+// numbers of fields.
+func ReadCSV(data []byte) ([][]string, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var records [][]string
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", len(records)+1, err)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// ReadXLSX returns every row of the first sheet of a workbook.
+func ReadXLSX(data []byte) ([][]string, error) {
+	f, err := excelize.OpenReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open workbook: %w", err)
+	}
+	defer f.Close()
+
+	sheets := f.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, fmt.Errorf("workbook has no sheets")
+	}
+	records, err := f.GetRows(sheets[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sheet %s: %w", sheets[0], err)
+	}
+	return records, nil
+}
+
+// Columns maps each column a file may carry to the headings it goes by.
+type Columns map[string][]string
+
+// Header records which field of a record holds each known column.
+type Header map[string]int
+
+// Match maps a header row to known columns. Headings are matched
+// case-insensitively and the first heading matching a column wins.
+func (c Columns) Match(header []string) Header {
+	index := Header{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		for key, aliases := range c {
+			if _, found := index[key]; found {
+				continue
+			}
+			for _, alias := range aliases {
+				if name == alias {
+					index[key] = i
+				}
+			}
+		}
+	}
+	return index
+}
+
+func (h Header) Has(key string) bool {
+	_, ok := h[key]
+	return ok
+}
+
+// Field returns the trimmed value of a column, or "" when the file or the
+// record does not have it.
+func (h Header) Field(record []string, key string) string {
+	i, ok := h[key]
+	if !ok || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+// Blank reports whether every field of a record is empty.
+func Blank(record []string) bool {
+	return strings.TrimSpace(strings.Join(record, "")) == ""
+}
+
+// Decimal marks an amount may be written with.
+const (
+	DecimalPoint = "."
+	DecimalComma = ","
+)
+
+// CheckDecimal reports whether mark is a decimal mark ParseAmount accepts.
+// The empty mark asks for it to be worked out from the amounts.
+func CheckDecimal(mark string) error {
+	switch mark {
+	case "", DecimalPoint, DecimalComma:
+		return nil
+	}
+	return fmt.Errorf("decimal mark must be %q or %q", DecimalPoint, DecimalComma)
+}
+
+// ParseAmount converts a decimal amount to whole currency units, the unit
+// deposits are recorded in. Negatives may be written -1500, (1,500.00) or
+// 250.00 DR. decimal is the mark separating the fraction, with the other
+// mark grouping thousands. When decimal is empty the amount must show its
+// own: 1,234.56, 1.234,56 and 12,50 do, but 1.234 and 1,234 could be a
+// thousand or a little over one and are rejected rather than guessed.
+func ParseAmount(value, decimal string) (int, error) {
+	cleaned, negative := cleanAmount(value)
+	if decimal == "" {
+		decimal = decimalMark(cleaned)
+		if decimal == "" {
+			return 0, fmt.Errorf("ambiguous amount %q: set the decimal mark", value)
+		}
+	}
+	grouping := DecimalComma
+	if decimal == DecimalComma {
+		grouping = DecimalPoint
+	}
+	if mark := strings.Index(cleaned, decimal); mark >= 0 &&
+		(strings.LastIndex(cleaned, grouping) > mark || strings.Count(cleaned, decimal) > 1) {
+		return 0, fmt.Errorf("unrecognised amount: %q", value)
+	}
+
+	cleaned = strings.Replace(strings.ReplaceAll(cleaned, grouping, ""), decimal, ".", 1)
+	f, err := strconv.ParseFloat(cleaned, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unrecognised amount: %q", value)
+	}
+	if negative {
+		f = -f
+	}
+	return int(math.Round(f)), nil
+}
+
+// DetectDecimal works out the decimal mark a file writes its amounts with
+// from the amounts that can only be read one way. It returns "" when none
+// of them tells, and fails when they disagree.
+func DetectDecimal(values []string) (string, error) {
+	found := ""
+	for _, value := range values {
+		cleaned, _ := cleanAmount(value)
+		if !strings.ContainsAny(cleaned, ".,") {
+			continue
+		}
+		mark := decimalMark(cleaned)
+		if mark == "" {
+			continue
+		}
+		if found != "" && mark != found {
+			return "", fmt.Errorf("amounts are written with both %q and %q as the decimal mark", found, mark)
+		}
+		found = mark
+	}
+	return found, nil
+}
+
+// cleanAmount removes spaces and the ways a bank marks an amount negative
+// or positive, reporting whether it was negative.
+func cleanAmount(value string) (string, bool) {
+	amount := strings.NewReplacer(" ", "", "\u00a0", "").Replace(strings.TrimSpace(value))
+	negative := false
+	if strings.HasPrefix(amount, "(") && strings.HasSuffix(amount, ")") {
+		negative = true
+		amount = strings.Trim(amount, "()")
+	}
+	if strings.HasSuffix(strings.ToUpper(amount), "DR") {
+		negative = true
+		amount = amount[:len(amount)-2]
+	} else if strings.HasSuffix(strings.ToUpper(amount), "CR") {
+		amount = amount[:len(amount)-2]
+	}
+	return amount, negative
+}
+
+// decimalMark returns the decimal mark an amount shows, or "" when it could
+// be either. When both marks appear the later one is the decimal mark. A
+// mark that appears more than once groups thousands, so the decimal mark is
+// the other one. A single mark followed by other than three digits, as in
+// 12,50, is the decimal mark; followed by exactly three it is ambiguous.
+func decimalMark(amount string) string {
+	comma, point := strings.LastIndex(amount, ","), strings.LastIndex(amount, ".")
+	switch {
+	case comma < 0 && point < 0:
+		return DecimalPoint
+	case comma >= 0 && point >= 0:
+		if point > comma {
+			return DecimalPoint
+		}
+		return DecimalComma
+	}
+
+	mark, other, at := DecimalComma, DecimalPoint, comma
+	if point >= 0 {
+		mark, other, at = DecimalPoint, DecimalComma, point
+	}
+	if strings.Count(amount, mark) > 1 {
+		return other
+	}
+	if len(amount)-at-1 != 3 {
+		return mark
+	}
+	return ""
+}
@@ -0,0 +1,122 @@
+package tabular
+
+import (
+	"testing"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		value   string
+		decimal string
+		want    int
+	}{
+		{"1234", "", 1234},
+		{"1,234.56", "", 1235},
+		{"1.234,56", "", 1235},
+		{"12,50", "", 13},
+		{"12,4", "", 12},
+		{"1,234,567", "", 1234567},
+		{"1.234.567,00", "", 1234567},
+		{"1 234,56", "", 1235},
+		{"-45.20", "", -45},
+		{"(1,500.00)", "", -1500},
+		{"250.00 CR", "", 250},
+		{"250,00DR", "", -250},
+		{"1,234", DecimalPoint, 1234},
+		{"1,234", DecimalComma, 1},
+		{"1.234", DecimalComma, 1234},
+		{"1.234", DecimalPoint, 1},
+		{"(1.500)", DecimalComma, -1500},
+	}
+	for _, tt := range tests {
+		got, err := ParseAmount(tt.value, tt.decimal)
+		if err != nil {
+			t.Errorf("ParseAmount(%q, %q) returned error: %v", tt.value, tt.decimal, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseAmount(%q, %q) = %d, want %d", tt.value, tt.decimal, got, tt.want)
+		}
+	}
+
+	rejected := []struct {
+		value   string
+		decimal string
+	}{
+		{"", ""},
+		{"abc", ""},
+		{"12,3x", ""},
+		{"1,234", ""},
+		{"1.234", ""},
+		{"-2.500 DR", ""},
+		{"1.234,56", DecimalPoint},
+		{"1,234.56", DecimalComma},
+		{"1.2.3", DecimalPoint},
+	}
+	for _, tt := range rejected {
+		if _, err := ParseAmount(tt.value, tt.decimal); err == nil {
+			t.Errorf("ParseAmount(%q, %q) succeeded, want error", tt.value, tt.decimal)
+		}
+	}
+}
+
+func TestDetectDecimal(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  []string
+		want    string
+		wantErr bool
+	}{
+		{"grouped thousands with cents", []string{"1,234", "", "1,000.50"}, DecimalPoint, false},
+		{"decimal comma", []string{"1.234", "12,50"}, DecimalComma, false},
+		{"repeated grouping", []string{"1.234", "1,234,567"}, DecimalPoint, false},
+		{"nothing to go on", []string{"1,234", "500"}, "", false},
+		{"mixed", []string{"1,000.50", "25,00"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DetectDecimal(tt.values)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DetectDecimal error = %v, want error %t", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("DetectDecimal = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		filename string
+		data     string
+		want     string
+	}{
+		{"remittance.csv", "PK\x03\x04", FormatCSV},
+		{"Remittance.XLSX", "member_id", FormatXLSX},
+		{"upload", "PK\x03\x04rest", FormatXLSX},
+		{"upload", "member_id,savings", FormatCSV},
+	}
+	for _, tt := range tests {
+		if got := DetectFormat(tt.filename, []byte(tt.data)); got != tt.want {
+			t.Errorf("DetectFormat(%q) = %s, want %s", tt.filename, got, tt.want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	columns := Columns{
+		"amount":    {"amount", "credit"},
+		"reference": {"reference", "ref"},
+	}
+	header := columns.Match([]string{"\ufeffDate", " Credit ", "Amount", "REF"})
+	if header["amount"] != 1 || header["reference"] != 3 || header.Has("date") {
+		t.Fatalf("Match = %v, want amount 1 and reference 3", header)
+	}
+	if got := header.Field([]string{"2025-11-14", " 100 "}, "amount"); got != "100" {
+		t.Errorf("Field(amount) = %q, want %q", got, "100")
+	}
+	if got := header.Field([]string{"2025-11-14", "100"}, "reference"); got != "" {
+		t.Errorf("Field(reference) on a short record = %q, want empty", got)
+	}
+}